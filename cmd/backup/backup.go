@@ -0,0 +1,89 @@
+// Command backup backs up and restores the Osusu database.
+//
+// Usage:
+//
+//	backup create [-dir dir] [-keep n]
+//	backup schedule [-dir dir] [-keep n] [-every duration]
+//	backup verify path
+//	backup restore [-mode merge|replace] path
+//
+// Backups are gzip compressed tar archives with one JSON file per table
+// and a manifest with the SHA-256 checksum of each table, which is verified
+// before anything is restored.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"cogentcore.org/core/base/errors"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+var modes = map[string]osusu.RestoreModes{
+	"merge":   osusu.RestoreMerge,
+	"replace": osusu.RestoreReplace,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	dir := fs.String("dir", "backups", "the directory to save backups in")
+	keep := fs.Int("keep", 10, "the number of most recent backups to keep, or 0 to keep all of them")
+	every := fs.Duration("every", 24*time.Hour, "how often to back up the database when scheduled")
+	mode := fs.String("mode", "merge", "how to restore the backup: merge to add missing rows, or replace to replace all rows")
+	errors.Must(fs.Parse(os.Args[2:]))
+
+	switch os.Args[1] {
+	case "create":
+		errors.Must(osusu.OpenDB())
+		path := errors.Must1(osusu.BackupToDir(*dir, *keep))
+		fmt.Println("Backed up to", path)
+	case "schedule":
+		errors.Must(osusu.OpenDB())
+		slog.Info("scheduling backups", "dir", *dir, "every", *every, "keep", *keep)
+		for {
+			path, err := osusu.BackupToDir(*dir, *keep)
+			if errors.Log(err) == nil {
+				slog.Info("backed up", "path", path)
+			}
+			time.Sleep(*every)
+		}
+	case "verify":
+		ba := errors.Must1(osusu.OpenBackupFile(pathArg(fs)))
+		fmt.Println("Backup from", ba.Manifest.Time.Format(time.DateTime), "is valid")
+		for _, t := range ba.Manifest.Tables {
+			fmt.Printf("%s: %d rows\n", t.Name, t.Rows)
+		}
+	case "restore":
+		m, ok := modes[*mode]
+		if !ok {
+			errors.Log(fmt.Errorf("invalid restore mode %q", *mode))
+			os.Exit(2)
+		}
+		ba := errors.Must1(osusu.OpenBackupFile(pathArg(fs)))
+		errors.Must(osusu.OpenDB())
+		added := errors.Must1(ba.Restore(m))
+		fmt.Printf("Restored %d rows from the backup from %s\n", added, ba.Manifest.Time.Format(time.DateTime))
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: backup create|schedule|verify|restore [flags] [path]")
+	os.Exit(2)
+}
+
+// pathArg returns the path argument of the given parsed flag set.
+func pathArg(fs *flag.FlagSet) string {
+	if fs.NArg() != 1 {
+		usage()
+	}
+	return fs.Arg(0)
+}
@@ -0,0 +1,451 @@
+// Command cli is a command-line interface for managing meals and entries
+// and getting recommendations without the app.
+//
+// Usage:
+//
+//	cli [-user email] [-json] meals [list]
+//	cli [-user email] [-json] meals add -name name [meal flags]
+//	cli [-user email] [-json] meals edit -id id [meal flags]
+//	cli [-user email] [-json] entries [list] [-meal id] [-n n]
+//	cli [-user email] [-json] entries add -meal id [entry flags]
+//	cli [-user email] [-json] rank [-n n] [filter flags]
+//	cli [-user email] [-json] discover [-n n] [-recipes path] [-vectors path] [-encode] [filter flags]
+//
+// The user defaults to the OSUSU_USER environment variable. Rankings and
+// recommendations use the current options preset of the user, or the one
+// given with -preset, with the filters given with -categories, -sources,
+// and -cuisines. Bit flags are separated with | or commas.
+//
+// For example, to see what to have for dinner:
+//
+//	cli rank -categories Dinner -n 5
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cogentcore.org/core/base/errors"
+	"cogentcore.org/core/base/iox/jsonx"
+	"cogentcore.org/core/enums"
+	"github.com/kkoreilly/osusu/osusu"
+	"github.com/kkoreilly/osusu/otextencoding"
+	"github.com/nlpodyssey/cybertron/pkg/client"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+)
+
+var (
+	jsonOutput bool
+	user       *osusu.User
+	// dims are the dimensions of the group of the user
+	dims []*osusu.Dimension
+)
+
+func main() {
+	email := flag.String("user", os.Getenv("OSUSU_USER"), "the email of the user")
+	flag.BoolVar(&jsonOutput, "json", false, "output JSON instead of a table")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: cli [-user email] [-json] meals|entries|rank|discover [add|edit|list] [flags]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *email == "" {
+		fatal(fmt.Errorf("no user; use -user or set OSUSU_USER"))
+	}
+
+	fatal(osusu.OpenDB())
+	user = &osusu.User{}
+	err := osusu.DB.Where("email = ?", *email).First(user).Error
+	if err != nil {
+		fatal(fmt.Errorf("error finding user %q: %w", *email, err))
+	}
+	// changes are recorded in the audit log as made by the user
+	osusu.DB = osusu.DB.WithContext(osusu.WithActor(context.Background(), user.ID))
+	dims, err = osusu.LoadDimensions(user.GroupID)
+	fatal(err)
+
+	command, args := args[0], args[1:]
+	sub := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+	switch command + " " + sub {
+	case "meals list":
+		listMeals(args)
+	case "meals add":
+		editMeal(args, false)
+	case "meals edit":
+		editMeal(args, true)
+	case "entries list":
+		listEntries(args)
+	case "entries add":
+		addEntry(args)
+	case "rank list":
+		rank(args)
+	case "discover list":
+		discover(args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// fatal exits with the given error if it is not nil.
+func fatal(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cli:", err)
+		os.Exit(1)
+	}
+}
+
+// parseBitFlag sets the given bit flag from the given string,
+// in which flags can be separated by | or commas.
+func parseBitFlag(bf enums.BitFlagSetter, s string) error {
+	return bf.SetString(strings.ReplaceAll(strings.ReplaceAll(s, " ", ""), ",", "|"))
+}
+
+// bitFlagVar defines a flag with the given name that sets the given bit flag.
+func bitFlagVar(fs *flag.FlagSet, bf enums.BitFlagSetter, name, usage string) {
+	fs.Func(name, usage, func(s string) error {
+		return parseBitFlag(bf, s)
+	})
+}
+
+// output outputs the given value as JSON if -json is set, and
+// otherwise calls the given function to write it as a table.
+func output(v any, table func(tw *tabwriter.Writer)) {
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		fatal(enc.Encode(v))
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(tw)
+	fatal(tw.Flush())
+}
+
+func listMeals(args []string) {
+	fs := flag.NewFlagSet("meals", flag.ExitOnError)
+	fatal(fs.Parse(args))
+	var meals []*osusu.Meal
+	fatal(osusu.DB.Find(&meals, "group_id = ?", user.GroupID).Error)
+	output(meals, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tName\tCategory\tCuisine\tSource")
+		for _, m := range meals {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", m.ID, m.Name, m.Category, m.Cuisine, m.Source)
+		}
+	})
+}
+
+// editMeal adds a new meal or edits an existing one with the given arguments.
+func editMeal(args []string, edit bool) {
+	fs := flag.NewFlagSet("meals", flag.ExitOnError)
+	id := fs.Uint("id", 0, "the ID of the meal to edit")
+	meal := &osusu.Meal{GroupID: user.GroupID}
+	fs.StringVar(&meal.Name, "name", "", "the name of the meal")
+	fs.StringVar(&meal.Description, "description", "", "the description of the meal")
+	fs.StringVar(&meal.Image, "image", "", "the URL of an image of the meal")
+	fs.StringVar(&meal.SourceURL, "url", "", "the URL of the recipe of the meal")
+	fs.Float64Var(&meal.CostPerServing, "cost", 0, "the cost of one serving of the meal")
+	bitFlagVar(fs, &meal.Category, "category", "the categories of the meal")
+	bitFlagVar(fs, &meal.Cuisine, "cuisine", "the cuisines of the meal")
+	bitFlagVar(fs, &meal.Source, "source", "the sources of the meal")
+	fatal(fs.Parse(args))
+
+	if !edit {
+		if meal.Name == "" {
+			fatal(fmt.Errorf("meals add: -name is required"))
+		}
+		fatal(osusu.DB.Create(meal).Error)
+		output(meal, func(tw *tabwriter.Writer) {
+			fmt.Fprintf(tw, "Created meal %d\n", meal.ID)
+		})
+		return
+	}
+
+	existing := &osusu.Meal{}
+	err := osusu.DB.First(existing, "id = ? AND group_id = ?", *id, user.GroupID).Error
+	if err != nil {
+		fatal(fmt.Errorf("error finding meal %d: %w", *id, err))
+	}
+	// only the given flags are changed
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			existing.Name = meal.Name
+		case "description":
+			existing.Description = meal.Description
+		case "image":
+			existing.Image = meal.Image
+		case "url":
+			existing.SourceURL = meal.SourceURL
+		case "cost":
+			existing.CostPerServing = meal.CostPerServing
+		case "category":
+			existing.Category = meal.Category
+		case "cuisine":
+			existing.Cuisine = meal.Cuisine
+		case "source":
+			existing.Source = meal.Source
+		}
+	})
+	fatal(osusu.DB.Save(existing).Error)
+	output(existing, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "Saved meal %d\n", existing.ID)
+	})
+}
+
+func listEntries(args []string) {
+	fs := flag.NewFlagSet("entries", flag.ExitOnError)
+	mealID := fs.Uint("meal", 0, "only list the entries for the meal with this ID")
+	n := fs.Int("n", 20, "the number of most recent entries to list, or 0 for all of them")
+	fatal(fs.Parse(args))
+	q := osusu.DB.Preload("Meal").Where("user_id = ?", user.ID).Order("time DESC")
+	if *mealID != 0 {
+		q = q.Where("meal_id = ?", *mealID)
+	}
+	if *n > 0 {
+		q = q.Limit(*n)
+	}
+	var entries []osusu.Entry
+	fatal(q.Find(&entries).Error)
+	output(entries, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tTime\tMeal\tTaste\tCost\tEffort\tHealthiness\tServings")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%d\t%d\t%g\n", e.ID, e.Time.Format(time.DateTime), e.Meal.Name, e.Taste, e.Cost, e.Effort, e.Healthiness, e.Servings)
+		}
+	})
+}
+
+func addEntry(args []string) {
+	fs := flag.NewFlagSet("entries", flag.ExitOnError)
+	entry := &osusu.Entry{
+		UserID:      user.ID,
+		Time:        time.Now(),
+		Taste:       50,
+		Cost:        50,
+		Effort:      50,
+		Healthiness: 50,
+		Servings:    1,
+	}
+	fs.UintVar(&entry.MealID, "meal", 0, "the ID of the meal")
+	fs.Func("time", "the time of the entry, like 2006-01-02 15:04 (default now)", func(s string) error {
+		t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t, err = time.ParseInLocation(time.DateOnly, s, time.Local)
+		}
+		entry.Time = t
+		return err
+	})
+	fs.IntVar(&entry.Taste, "taste", entry.Taste, "the taste rating from 0 to 100")
+	fs.IntVar(&entry.Cost, "cost", entry.Cost, "the cost rating from 0 to 100")
+	fs.IntVar(&entry.Effort, "effort", entry.Effort, "the effort rating from 0 to 100")
+	fs.IntVar(&entry.Healthiness, "healthiness", entry.Healthiness, "the healthiness rating from 0 to 100")
+	fs.Float64Var(&entry.Servings, "servings", entry.Servings, "the number of servings eaten")
+	for _, d := range dims {
+		if !d.IsBuiltin() {
+			if entry.Ratings == nil {
+				entry.Ratings = map[string]int{}
+			}
+			entry.Ratings[d.Name] = d.DefaultRating()
+		}
+	}
+	fs.Func("rating", "a rating on a custom dimension, like spiciness=70; can be repeated", func(s string) error {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("rating %q is not in the form name=value", s)
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if _, ok := entry.Ratings[name]; !ok {
+			return fmt.Errorf("there is no custom dimension named %q", name)
+		}
+		entry.Ratings[name] = v
+		return nil
+	})
+	bitFlagVar(fs, &entry.Category, "category", "the category of the entry (default the categories of the meal)")
+	bitFlagVar(fs, &entry.Source, "source", "the source of the entry (default the sources of the meal)")
+	fatal(fs.Parse(args))
+
+	meal := &osusu.Meal{}
+	err := osusu.DB.First(meal, "id = ? AND group_id = ?", entry.MealID, user.GroupID).Error
+	if err != nil {
+		fatal(fmt.Errorf("error finding meal %d: %w", entry.MealID, err))
+	}
+	if entry.Category == 0 {
+		entry.Category = meal.Category
+	}
+	if entry.Source == 0 {
+		entry.Source = meal.Source
+	}
+
+	// the choice is recorded before the entry is created so that it is compared to the other meals as they were
+	opts := loadOptions("")
+	scored, err := osusu.ScoreMeals(user, opts, dims)
+	fatal(err)
+	fatal(osusu.DB.Create(entry).Error)
+	// meals that do not match the options are not recommendations
+	if choice := osusu.NewEntryChoice(user.ID, meal.ID, scored); choice != nil {
+		fatal(osusu.DB.Create(choice).Error)
+	}
+	output(entry, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "Created entry %d for %s\n", entry.ID, meal.Name)
+	})
+}
+
+// loadOptions returns the options of the preset of the user with the given
+// name, or of the current preset of the user if the name is empty.
+func loadOptions(preset string) *osusu.Options {
+	if preset == "" {
+		p, err := osusu.LoadPreset(user)
+		fatal(err)
+		p.Options.AddDimensionImportances(dims)
+		return &p.Options
+	}
+	p := &osusu.Preset{}
+	err := osusu.DB.First(p, "user_id = ? AND name = ?", user.ID, preset).Error
+	if err != nil {
+		fatal(fmt.Errorf("error finding preset %q: %w", preset, err))
+	}
+	p.Options.AddDimensionImportances(dims)
+	return &p.Options
+}
+
+// optionFlags defines the flags for the options on the given flag set and
+// returns a function that returns the options after the flags are parsed.
+func optionFlags(fs *flag.FlagSet) func() *osusu.Options {
+	preset := fs.String("preset", "", "the name of the options preset to use (default the current preset)")
+	var categories osusu.Categories
+	var sources osusu.Sources
+	var cuisines osusu.Cuisines
+	bitFlagVar(fs, &categories, "categories", "only include these categories (default the preset categories)")
+	bitFlagVar(fs, &sources, "sources", "only include these sources (default the preset sources)")
+	bitFlagVar(fs, &cuisines, "cuisines", "only include these cuisines (default the preset cuisines)")
+	return func() *osusu.Options {
+		opts := loadOptions(*preset)
+		if categories != 0 {
+			opts.Categories = categories
+		}
+		if sources != 0 {
+			opts.Sources = sources
+		}
+		if cuisines != 0 {
+			opts.Cuisines = cuisines
+		}
+		return opts
+	}
+}
+
+// rankedItem is a ranked meal or recipe for output.
+type rankedItem struct {
+	ID    uint `json:",omitempty"`
+	Name  string
+	URL   string `json:",omitempty"`
+	Score *osusu.Score
+}
+
+// outputRanking outputs the given ranked items with their scores.
+func outputRanking(items []rankedItem, showRecency bool) {
+	shown := []*osusu.Dimension{}
+	for _, d := range dims {
+		if d.Name != osusu.Recency || showRecency {
+			shown = append(shown, d)
+		}
+	}
+	output(items, func(tw *tabwriter.Writer) {
+		fmt.Fprint(tw, "#\tID\tName\tTotal")
+		for _, d := range shown {
+			fmt.Fprint(tw, "\t", d.Label)
+		}
+		fmt.Fprintln(tw)
+		for i, item := range items {
+			id := ""
+			if item.ID != 0 {
+				id = strconv.Itoa(int(item.ID))
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d", i+1, id, item.Name, osusu.Round(item.Score.Total))
+			for _, d := range shown {
+				if v, ok := item.Score.Value(d); ok {
+					fmt.Fprint(tw, "\t", osusu.Round(v))
+				} else {
+					fmt.Fprint(tw, "\t-")
+				}
+			}
+			fmt.Fprintln(tw)
+		}
+	})
+}
+
+func rank(args []string) {
+	fs := flag.NewFlagSet("rank", flag.ExitOnError)
+	n := fs.Int("n", 10, "the number of meals to show, or 0 for all of them")
+	options := optionFlags(fs)
+	fatal(fs.Parse(args))
+	scored, err := osusu.ScoreMeals(user, options(), dims)
+	fatal(err)
+	if *n > 0 {
+		scored = scored[:min(*n, len(scored))]
+	}
+	items := make([]rankedItem, len(scored))
+	for i, sm := range scored {
+		items[i] = rankedItem{ID: sm.Meal.ID, Name: sm.Meal.Name, URL: sm.Meal.SourceURL, Score: sm.Score}
+	}
+	outputRanking(items, true)
+}
+
+func discover(args []string) {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	n := fs.Int("n", 10, "the number of recipes to show")
+	recipesPath := fs.String("recipes", filepath.Join("cmd", "osusu", "recipes.json"), "the recipes file")
+	vectorsPath := fs.String("vectors", filepath.Join("cmd", "osusu", "textEncodingVectors.json"), "the recipe text encoding vectors file")
+	encode := fs.Bool("encode", false, "text encode meals with the text encoding server instead of using the vectors of their recipes")
+	options := optionFlags(fs)
+	fatal(fs.Parse(args))
+	opts := options()
+
+	var recipes []*osusu.Recipe
+	fatal(jsonx.Open(&recipes, *recipesPath))
+	for _, recipe := range recipes {
+		errors.Log(recipe.Init())
+		errors.Log(recipe.CategoryFlag.SetString(strings.Join(recipe.Category, "|")))
+		errors.Log(recipe.CuisineFlag.SetString(strings.Join(recipe.Cuisine, "|")))
+	}
+	var vectors map[string][]float32
+	fatal(jsonx.Open(&vectors, *vectorsPath))
+
+	var meals []*osusu.Meal
+	fatal(osusu.DB.Find(&meals, "group_id = ?", user.GroupID).Error)
+	mealVectors := osusu.SourceVectors(meals, vectors)
+	if *encode {
+		otextencoding.Model = client.NewClientForTextEncoding("localhost:8081", client.Options{})
+		for _, meal := range meals {
+			res, err := otextencoding.Model.Encode(context.TODO(), meal.Text(), int(bert.MeanPooling))
+			fatal(err)
+			mealVectors[meal.ID] = res.Vector.Data().F32()
+		}
+	}
+
+	ranked, err := osusu.Discover(recipes, vectors, meals, mealVectors, user, opts, dims, *n)
+	fatal(err)
+	items := make([]rankedItem, len(ranked))
+	for i, r := range ranked {
+		items[i] = rankedItem{Name: r.Name, URL: r.URL, Score: &r.Score}
+	}
+	outputRanking(items, false)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/kkoreilly/osusu/osusu"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestParseBitFlag(t *testing.T) {
+	tests := []struct {
+		s    string
+		want osusu.Categories
+		err  bool
+	}{
+		{"Dinner", 1 << osusu.Dinner, false},
+		{"Lunch|Dinner", 1<<osusu.Lunch | 1<<osusu.Dinner, false},
+		{"Lunch,Dinner", 1<<osusu.Lunch | 1<<osusu.Dinner, false},
+		{"Lunch, Dinner", 1<<osusu.Lunch | 1<<osusu.Dinner, false},
+		{"Lunch|Diner", 0, true},
+	}
+	for _, test := range tests {
+		var c osusu.Categories
+		err := parseBitFlag(&c, test.s)
+		if (err != nil) != test.err {
+			t.Errorf("%q: expected an error %v but got %v", test.s, test.err, err)
+		}
+		if !test.err && c != test.want {
+			t.Errorf("%q: expected %v but got %v", test.s, test.want, c)
+		}
+	}
+}
+
+func TestOptionFlags(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "osusu.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := osusu.DB
+	osusu.DB = db
+	t.Cleanup(func() {
+		osusu.DB = old
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	err = db.AutoMigrate(osusu.Models...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user = &osusu.User{Name: "A"}
+	err = db.Create(user).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	preset := &osusu.Preset{UserID: user.ID, Name: "Quick", Options: *osusu.DefaultOptions()}
+	preset.Options.EffortImportance = 100
+	preset.Options.Cuisines = 1 << osusu.Italian
+	err = db.Create(preset).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the filters given with flags replace those of the preset, and the rest are kept
+	fs := flag.NewFlagSet("rank", flag.ContinueOnError)
+	options := optionFlags(fs)
+	err = fs.Parse([]string{"-preset", "Quick", "-categories", "Lunch,Dinner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := options()
+	if opts.EffortImportance != 100 {
+		t.Errorf("expected the effort importance of the preset, 100, but got %d", opts.EffortImportance)
+	}
+	if opts.Categories != 1<<osusu.Lunch|1<<osusu.Dinner {
+		t.Errorf("expected the categories Lunch and Dinner but got %v", opts.Categories)
+	}
+	if opts.Cuisines != 1<<osusu.Italian {
+		t.Errorf("expected the cuisines of the preset, Italian, but got %v", opts.Cuisines)
+	}
+}
@@ -0,0 +1,97 @@
+// Command data exports and imports meal, entry, group, and user data in JSON and CSV.
+//
+// Usage:
+//
+//	data export [-format json|csv] [-group id] path
+//	data import [-format json|csv] [-conflicts skip|overwrite|new] [-group id] [-user id] path
+//
+// The format defaults to JSON for paths ending in .json and CSV otherwise,
+// in which case the path is a directory with one file per table.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cogentcore.org/core/base/errors"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+var formats = map[string]osusu.DataFormats{
+	"json": osusu.DataJSON,
+	"csv":  osusu.DataCSV,
+}
+
+var conflicts = map[string]osusu.ImportConflicts{
+	"skip":      osusu.ImportSkip,
+	"overwrite": osusu.ImportOverwrite,
+	"new":       osusu.ImportNew,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	format := fs.String("format", "", "the format of the data: json or csv")
+	group := fs.Uint("group", 0, "the ID of the group to export, or the group to import all users and meals into; all groups if 0")
+	conflict := fs.String("conflicts", "skip", "how to handle imported records with existing IDs: skip, overwrite, or new")
+	user := fs.Uint("user", 0, "the ID of the user to give imported entries without users")
+
+	switch os.Args[1] {
+	case "export":
+		errors.Must(fs.Parse(os.Args[2:]))
+		path := pathArg(fs)
+		errors.Must(osusu.OpenDB())
+		de := errors.Must1(osusu.ExportData(*group))
+		errors.Must(de.Save(path, dataFormat(*format, path)))
+		fmt.Printf("Exported %d groups, %d users, %d meals, and %d entries to %s\n", len(de.Groups), len(de.Users), len(de.Meals), len(de.Entries), path)
+	case "import":
+		errors.Must(fs.Parse(os.Args[2:]))
+		path := pathArg(fs)
+		c, ok := conflicts[*conflict]
+		if !ok {
+			errors.Log(fmt.Errorf("invalid conflict handling %q", *conflict))
+			os.Exit(2)
+		}
+		de := errors.Must1(osusu.OpenDataExport(path, dataFormat(*format, path)))
+		errors.Must(osusu.OpenDB())
+		res := errors.Must1(de.Import(&osusu.ImportOptions{Conflicts: c, GroupID: *group, UserID: *user}))
+		fmt.Println(res)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: data export|import [flags] path")
+	os.Exit(2)
+}
+
+// pathArg returns the path argument of the given parsed flag set.
+func pathArg(fs *flag.FlagSet) string {
+	if fs.NArg() != 1 {
+		usage()
+	}
+	return fs.Arg(0)
+}
+
+// dataFormat returns the format with the given name, or the
+// format based on the extension of the given path if it is empty.
+func dataFormat(name, path string) osusu.DataFormats {
+	if name == "" {
+		name = "csv"
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			name = "json"
+		}
+	}
+	f, ok := formats[name]
+	if !ok {
+		errors.Log(fmt.Errorf("invalid format %q", name))
+		os.Exit(2)
+	}
+	return f
+}
@@ -0,0 +1,113 @@
+// Command evaluate evaluates the recommender on historical or synthetic data
+// and compares two configurations of it. Like the CLI and the server, it is
+// run from the root of the repository so that the default recipe files are found.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"cogentcore.org/core/base/errors"
+	"cogentcore.org/core/base/iox/jsonx"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+func main() {
+	recipesPath := flag.String("recipes", filepath.Join("cmd", "osusu", "recipes.json"), "the recipes file")
+	vectorsPath := flag.String("vectors", filepath.Join("cmd", "osusu", "textEncodingVectors.json"), "the recipe text encoding vectors file")
+	dataPath := flag.String("data", "", "a JSON file with Meals and Entries to evaluate on instead of the database")
+	synthetic := flag.Int("synthetic", 0, "the number of synthetic users to evaluate on instead of the database")
+	syntheticEntries := flag.Int("synthetic-entries", 50, "the number of entries of each synthetic user")
+	seed := flag.Uint64("seed", 1, "the seed for the synthetic data")
+	a := flag.String("a", "", "a JSON file with the first configuration (Name, Options, and Recommend); the default configuration if empty")
+	b := flag.String("b", "", "a JSON file with the second configuration; the default configuration if empty")
+	params := osusu.DefaultEvalParams()
+	flag.IntVar(&params.K, "k", params.K, "the number of recommendations evaluated at each point")
+	flag.IntVar(&params.Horizon, "horizon", params.Horizon, "the number of next new meals that are relevant at each point")
+	flag.IntVar(&params.MinHistory, "min-history", params.MinHistory, "the number of entries a user needs before they are evaluated")
+	jsonOutput := flag.Bool("json", false, "output the results as JSON instead of a table")
+	flag.Parse()
+
+	var recipes []*osusu.Recipe
+	errors.Must(jsonx.Open(&recipes, *recipesPath))
+	for _, recipe := range recipes {
+		errors.Log(recipe.Init())
+		errors.Log(recipe.CategoryFlag.SetString(strings.Join(recipe.Category, "|")))
+		errors.Log(recipe.CuisineFlag.SetString(strings.Join(recipe.Cuisine, "|")))
+		recipe.EstimateCost(nil)
+	}
+	var vectors map[string][]float32
+	errors.Must(jsonx.Open(&vectors, *vectorsPath))
+
+	data := &osusu.EvalData{}
+	switch {
+	case *dataPath != "":
+		errors.Must(jsonx.Open(data, *dataPath))
+	case *synthetic > 0:
+		data = osusu.SyntheticEvalData(recipes, vectors, *synthetic, *syntheticEntries, *seed)
+	default:
+		errors.Must(osusu.OpenDB())
+		errors.Must(osusu.DB.Find(&data.Meals).Error)
+		errors.Must(osusu.DB.Find(&data.Entries).Error)
+	}
+	slog.Info("evaluating", "meals", len(data.Meals), "entries", len(data.Entries), "recipes", len(recipes))
+
+	results := []*osusu.EvalResult{}
+	for i, path := range []string{*a, *b} {
+		cfg := errors.Must1(openConfig(path))
+		if i == 1 && cfg.Name == results[0].Config {
+			cfg.Name += " (b)"
+		}
+		results = append(results, osusu.Evaluate(data, recipes, vectors, cfg, params))
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		errors.Must(enc.Encode(results))
+		return
+	}
+	report(results[0], results[1], params.K)
+}
+
+// openConfig opens the evaluation configuration at the given path,
+// returning the default configuration if the path is empty.
+func openConfig(path string) (*osusu.EvalConfig, error) {
+	cfg := osusu.DefaultEvalConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	cfg.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	err := jsonx.Open(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Options == nil {
+		cfg.Options = osusu.DefaultOptions()
+	}
+	if cfg.Recommend == nil {
+		cfg.Recommend = osusu.DefaultRecommendConfig()
+	}
+	return cfg, nil
+}
+
+// report prints a table comparing the given results.
+func report(a, b *osusu.EvalResult, k int) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Metric\t%s\t%s\tChange\t\n", a.Config, b.Config)
+	row := func(name string, av, bv float64) {
+		fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%+.4f\t\n", name, av, bv, bv-av)
+	}
+	fmt.Fprintf(tw, "Points\t%d\t%d\t\t\n", a.Points, b.Points)
+	row(fmt.Sprintf("Precision@%d", k), a.Precision, b.Precision)
+	row(fmt.Sprintf("NDCG@%d", k), a.NDCG, b.NDCG)
+	row("Coverage", a.Coverage, b.Coverage)
+	row("Diversity", a.Diversity, b.Diversity)
+	tw.Flush()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+func updateProfile(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	// the fields in the request are decoded on top of the existing profile
+	p := user.Profile()
+	err := readJSON(w, r, p)
+	if err != nil {
+		return err
+	}
+	err = user.UpdateProfile(p)
+	if err != nil {
+		return httpError(http.StatusBadRequest, "%w", err)
+	}
+	return writeJSON(w, http.StatusOK, user)
+}
+
+func deleteAccount(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	anonymize := true
+	if s := r.URL.Query().Get("anonymize"); s != "" {
+		var err error
+		anonymize, err = strconv.ParseBool(s)
+		if err != nil {
+			return httpError(http.StatusBadRequest, "invalid anonymize %q", s)
+		}
+	}
+	err := user.DeleteAccount(anonymize)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func exportPersonalData(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	pd, err := user.PersonalData()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="osusu-personal-data.json"`)
+	return writeJSON(w, http.StatusOK, pd)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kkoreilly/osusu/osusu"
+	"gorm.io/gorm"
+)
+
+// server is the API server.
+type server struct {
+	recipes []*osusu.Recipe
+	// vectors are the text encoding vectors of the recipes, keyed by URL
+	vectors map[string][]float32
+	// encode text encodes the given meal, or is nil to use the vectors
+	// of the recipes that meals were added from
+	encode func(meal *osusu.Meal) ([]float32, error)
+
+	// verifier verifies the ID tokens that users log in with, or is nil
+	// if no providers are configured
+	verifier *osusu.Verifier
+	nonces   nonces
+}
+
+// handler is an API handler for an authenticated user. If it returns
+// an error, it is written as the response (see [httpError]).
+type handler func(w http.ResponseWriter, r *http.Request, user *osusu.User) error
+
+// apiError is an error with an HTTP status code.
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e *apiError) Error() string { return e.err.Error() }
+
+func (e *apiError) Unwrap() error { return e.err }
+
+// httpError returns an error with the given HTTP status code and formatted message.
+func httpError(status int, format string, a ...any) error {
+	return &apiError{status, fmt.Errorf(format, a...)}
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, h handler) {
+		mux.Handle(pattern, s.auth(h))
+	}
+	// handleGroup is like handle for handlers that use the group of the user
+	handleGroup := func(pattern string, h handler) {
+		handle(pattern, inGroup(h))
+	}
+	mux.HandleFunc("POST /api/v1/nonces", s.createNonce)
+	mux.HandleFunc("POST /api/v1/sessions", s.createSession)
+	handle("GET /api/v1/me", getMe)
+	handle("PATCH /api/v1/me", updateProfile)
+	handle("DELETE /api/v1/me", deleteAccount)
+	handle("GET /api/v1/me/data", exportPersonalData)
+	handle("GET /api/v1/me/identities", listIdentities)
+	handle("POST /api/v1/me/identities", s.linkIdentity)
+	handle("DELETE /api/v1/me/identities/{id}", unlinkIdentity)
+	handle("DELETE /api/v1/session", deleteSession)
+
+	handleGroup("GET /api/v1/meals", listMeals)
+	handleGroup("POST /api/v1/meals", createMeal)
+	handleGroup("GET /api/v1/meals/duplicates", s.getDuplicates)
+	handleGroup("GET /api/v1/meals/{id}", getMeal)
+	handleGroup("PATCH /api/v1/meals/{id}", updateMeal)
+	handleGroup("DELETE /api/v1/meals/{id}", deleteMeal)
+	handleGroup("GET /api/v1/meals/{id}/history", getMealHistory)
+	handleGroup("POST /api/v1/meals/{id}/revert", revertMeal)
+	handleGroup("POST /api/v1/meals/{id}/merge", mergeMeals)
+
+	handleGroup("GET /api/v1/entries", listEntries)
+	handleGroup("POST /api/v1/entries", s.createEntry)
+	handleGroup("GET /api/v1/entries/{id}", getEntry)
+	handleGroup("PATCH /api/v1/entries/{id}", updateEntry)
+	handleGroup("DELETE /api/v1/entries/{id}", deleteEntry)
+
+	handleGroup("GET /api/v1/trash", getTrash)
+	handleGroup("DELETE /api/v1/trash", emptyTrash)
+	handleGroup("POST /api/v1/trash/{kind}/{id}/restore", restoreTrashItem)
+	handleGroup("DELETE /api/v1/trash/{kind}/{id}", purgeTrashItem)
+
+	handleGroup("GET /api/v1/scores", s.scores)
+	handleGroup("GET /api/v1/recommendations", s.recommendations)
+
+	handle("GET /api/v1/group", getGroup)
+	handle("POST /api/v1/group", createGroup)
+	handle("POST /api/v1/group/join", joinGroup)
+	handle("POST /api/v1/group/leave", leaveGroup)
+	handle("DELETE /api/v1/group/members/{id}", removeMember)
+	handleGroup("GET /api/v1/group/history", getGroupHistory)
+	return mux
+}
+
+// auth returns an HTTP handler that authenticates the user with the bearer
+// token of their session and then calls the given handler.
+func (s *server) auth(h handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, r, httpError(http.StatusUnauthorized, "missing bearer token"))
+			return
+		}
+		session, err := osusu.LoadSession(token)
+		if errors.Is(err, osusu.ErrInvalidSession) {
+			err = &apiError{http.StatusUnauthorized, osusu.ErrInvalidSession}
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		// changes are recorded in the audit log as made by the user
+		r = r.WithContext(osusu.WithActor(r.Context(), session.UserID))
+		writeError(w, r, h(w, r, &session.User))
+	})
+}
+
+// db returns the database with the context of the given request,
+// which should be used for changes so that they have the actor.
+func db(r *http.Request) *gorm.DB {
+	return osusu.DB.WithContext(r.Context())
+}
+
+// writeJSON writes the given value as the JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the given error as the JSON response if it is not nil.
+// Errors that are not [apiError]s are internal server errors, except for
+// records that are not found.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	status := http.StatusInternalServerError
+	var ae *apiError
+	switch {
+	case errors.As(err, &ae):
+		status = ae.status
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	default:
+		slog.Error("error handling request", "method", r.Method, "path", r.URL.Path, "err", err)
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// readJSON decodes the JSON request body into the given value.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v)
+	if err != nil {
+		return httpError(http.StatusBadRequest, "invalid request body: %w", err)
+	}
+	return nil
+}
+
+// pathID returns the ID in the path of the request.
+func pathID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, httpError(http.StatusBadRequest, "invalid ID %q", r.PathValue("id"))
+	}
+	return uint(id), nil
+}
+
+// queryUint returns the unsigned integer query parameter with the given name, or 0 if it is not given.
+func queryUint(r *http.Request, name string) (uint, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, httpError(http.StatusBadRequest, "invalid %s %q", name, s)
+	}
+	return uint(u), nil
+}
+
+// requireGroup returns an error if the given user is not in a group.
+func requireGroup(user *osusu.User) error {
+	if user.GroupID == 0 {
+		return httpError(http.StatusConflict, "you are not in a group")
+	}
+	return nil
+}
+
+// inGroup returns a handler that returns an error if the user is not
+// in a group and otherwise calls the given handler.
+func inGroup(h handler) handler {
+	return func(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+		err := requireGroup(user)
+		if err != nil {
+			return err
+		}
+		return h(w, r, user)
+	}
+}
+
+func getMe(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	return writeJSON(w, http.StatusOK, user)
+}
+
+func deleteSession(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	err := osusu.DB.Delete(&osusu.Session{}, "token = ?", token).Error
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/kkoreilly/osusu/osusu"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testServer sets [osusu.DB] to a new migrated SQLite database in a temporary
+// directory and returns a test server for the API for the rest of the given test.
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "osusu.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := osusu.DB
+	osusu.DB = db
+	t.Cleanup(func() {
+		osusu.DB = old
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	err = db.AutoMigrate(osusu.Models...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer((&server{}).routes())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// testUser creates a user in the group with the given ID, or no group
+// if it is 0, and returns the user and the token of a new session of them.
+func testUser(t *testing.T, name string, groupID uint) (*osusu.User, string) {
+	t.Helper()
+	user := &osusu.User{Name: name, Email: strings.ToLower(name) + "@example.com", GroupID: groupID}
+	err := osusu.DB.Create(user).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := osusu.NewSession(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, session.Token
+}
+
+// testRequest makes a request to the test server with the given token and
+// JSON body, which is not sent if it is "", and returns the status code and
+// body of the response.
+func testRequest(t *testing.T, ts *httptest.Server, token, method, path, body string) (int, string) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(b)
+}
+
+// testDecode decodes the given JSON response body into the given value.
+func testDecode(t *testing.T, body string, v any) {
+	t.Helper()
+	err := json.Unmarshal([]byte(body), v)
+	if err != nil {
+		t.Fatalf("error decoding %q: %v", body, err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	ts := testServer(t)
+	_, token := testUser(t, "A", 0)
+	tests := []struct {
+		token  string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"invalid", http.StatusUnauthorized},
+		{token, http.StatusOK},
+	}
+	for _, test := range tests {
+		status, body := testRequest(t, ts, test.token, "GET", "/api/v1/me", "")
+		if status != test.status {
+			t.Errorf("with token %q: expected status %d but got %d: %s", test.token, test.status, status, body)
+		}
+	}
+}
+
+func TestRequireGroup(t *testing.T) {
+	ts := testServer(t)
+	_, token := testUser(t, "A", 0)
+	// every group-scoped handler returns a conflict for users without a group
+	// before reading anything, so that they never see the data of group 0
+	requests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/api/v1/meals", ""},
+		{"POST", "/api/v1/meals", `{"Name": "Tacos"}`},
+		{"GET", "/api/v1/meals/duplicates", ""},
+		{"GET", "/api/v1/meals/1", ""},
+		{"PATCH", "/api/v1/meals/1", `{"Name": "Tacos"}`},
+		{"DELETE", "/api/v1/meals/1", ""},
+		{"GET", "/api/v1/meals/1/history", ""},
+		{"POST", "/api/v1/meals/1/revert", `{"Version": 1}`},
+		{"POST", "/api/v1/meals/1/merge", `{"Meals": [2]}`},
+		{"GET", "/api/v1/entries", ""},
+		{"POST", "/api/v1/entries", `{"MealID": 1}`},
+		{"GET", "/api/v1/entries/1", ""},
+		{"PATCH", "/api/v1/entries/1", `{"Taste": 50}`},
+		{"DELETE", "/api/v1/entries/1", ""},
+		{"GET", "/api/v1/trash", ""},
+		{"DELETE", "/api/v1/trash", ""},
+		{"POST", "/api/v1/trash/meals/1/restore", ""},
+		{"DELETE", "/api/v1/trash/entries/1", ""},
+		{"GET", "/api/v1/scores", ""},
+		{"GET", "/api/v1/recommendations", ""},
+		{"GET", "/api/v1/group/history", ""},
+	}
+	for _, req := range requests {
+		status, body := testRequest(t, ts, token, req.method, req.path, req.body)
+		if status != http.StatusConflict || !strings.Contains(body, "you are not in a group") {
+			t.Errorf("%s %s: expected status %d but got %d: %s", req.method, req.path, http.StatusConflict, status, body)
+		}
+	}
+
+	// users without a group can still see themselves and create a group
+	status, body := testRequest(t, ts, token, "GET", "/api/v1/me", "")
+	if status != http.StatusOK {
+		t.Errorf("expected status %d for the current user but got %d: %s", http.StatusOK, status, body)
+	}
+	status, body = testRequest(t, ts, token, "POST", "/api/v1/group", `{"Name": "Family"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("expected the group to be created but got %d: %s", status, body)
+	}
+	// after which the group-scoped handlers work
+	status, body = testRequest(t, ts, token, "GET", "/api/v1/meals", "")
+	if status != http.StatusOK || strings.TrimSpace(body) != "[]" {
+		t.Errorf("expected no meals in the new group but got %d: %s", status, body)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ts := testServer(t)
+	_, token := testUser(t, "A", 0)
+	status, body := testRequest(t, ts, token, "DELETE", "/api/v1/me?anonymize=maybe", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid anonymize but got %d: %s", http.StatusBadRequest, status, body)
+	}
+	status, body = testRequest(t, ts, token, "DELETE", "/api/v1/me?anonymize=false", "")
+	if status != http.StatusNoContent {
+		t.Fatalf("expected status %d but got %d: %s", http.StatusNoContent, status, body)
+	}
+	// the session is deleted with the account
+	status, body = testRequest(t, ts, token, "GET", "/api/v1/me", "")
+	if status != http.StatusUnauthorized {
+		t.Errorf("expected status %d after deleting the account but got %d: %s", http.StatusUnauthorized, status, body)
+	}
+}
+
+func TestMealCreatorSpoof(t *testing.T) {
+	ts := testServer(t)
+	group := &osusu.Group{Name: "Family"}
+	err := osusu.DB.Create(group).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, tokenA := testUser(t, "A", group.ID)
+	b, tokenB := testUser(t, "B", group.ID)
+	err = osusu.DB.Model(group).Update("owner_id", a.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the creator can not be set when creating a meal
+	status, body := testRequest(t, ts, tokenA, "POST", "/api/v1/meals", fmt.Sprintf(`{"Name": "Tacos", "CreatorID": %d, "DeletionID": "x"}`, b.ID))
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d but got %d: %s", http.StatusCreated, status, body)
+	}
+	meal := &osusu.Meal{}
+	testDecode(t, body, meal)
+	if meal.CreatorID != a.ID || meal.DeletionID != "" {
+		t.Errorf("expected the meal to be created by %d without a deletion ID but got %d and %q", a.ID, meal.CreatorID, meal.DeletionID)
+	}
+	status, body = testRequest(t, ts, tokenA, "POST", "/api/v1/entries", fmt.Sprintf(`{"MealID": %d}`, meal.ID))
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d but got %d: %s", http.StatusCreated, status, body)
+	}
+
+	// nor when updating it, so other members can not make themselves its creator
+	path := fmt.Sprintf("/api/v1/meals/%d", meal.ID)
+	status, body = testRequest(t, ts, tokenB, "PATCH", path, fmt.Sprintf(`{"Name": "Nachos", "CreatorID": %d, "DeletionID": "x"}`, b.ID))
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d but got %d: %s", http.StatusOK, status, body)
+	}
+	testDecode(t, body, meal)
+	if meal.Name != "Nachos" || meal.CreatorID != a.ID || meal.DeletionID != "" {
+		t.Errorf("expected only the name to change but got %q, %d, and %q", meal.Name, meal.CreatorID, meal.DeletionID)
+	}
+	// and purge it with the entries of other members
+	status, body = testRequest(t, ts, tokenB, "DELETE", path, "")
+	if status != http.StatusNoContent {
+		t.Fatalf("expected status %d but got %d: %s", http.StatusNoContent, status, body)
+	}
+	status, body = testRequest(t, ts, tokenB, "DELETE", fmt.Sprintf("/api/v1/trash/meals/%d", meal.ID), "")
+	if status != http.StatusForbidden {
+		t.Errorf("expected status %d but got %d: %s", http.StatusForbidden, status, body)
+	}
+	var n int64
+	err = osusu.DB.Unscoped().Model(&osusu.Entry{}).Where("meal_id = ?", meal.ID).Count(&n).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected the entry of the creator to be kept but got %d entries", n)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+func getMealHistory(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	// deleted meals still have a history
+	err = groupMeals(user).Unscoped().First(&osusu.Meal{}, id).Error
+	if err != nil {
+		return err
+	}
+	records, err := osusu.MealHistory(id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, records)
+}
+
+// revertRequest is the request body for reverting a meal.
+type revertRequest struct {
+	// Version is the ID of the audit record of the version to revert to
+	Version uint
+}
+
+func revertMeal(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	err = groupMeals(user).Unscoped().First(&osusu.Meal{}, id).Error
+	if err != nil {
+		return err
+	}
+	req := &revertRequest{}
+	err = readJSON(w, r, req)
+	if err != nil {
+		return err
+	}
+	meal, err := osusu.RevertMeal(r.Context(), id, req.Version)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, meal)
+}
+
+func getGroupHistory(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	n, err := queryN(r, 100)
+	if err != nil {
+		return err
+	}
+	records, err := osusu.GroupHistory(user.GroupID, n)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, records)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+func (s *server) getDuplicates(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	meals := []*osusu.Meal{}
+	err := groupMeals(user).Find(&meals).Error
+	if err != nil {
+		return err
+	}
+	vectors, err := s.mealVectors(meals)
+	if err != nil {
+		return err
+	}
+	dups := osusu.FindDuplicates(meals, vectors, osusu.DefaultDuplicateConfig())
+	if dups == nil {
+		dups = []*osusu.Duplicates{}
+	}
+	return writeJSON(w, http.StatusOK, dups)
+}
+
+// mergeRequest is the request body for merging meals.
+type mergeRequest struct {
+	// Meals are the IDs of the duplicate meals to merge into the meal
+	Meals []uint
+}
+
+func mergeMeals(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	meal, err := findMeal(user, id)
+	if err != nil {
+		return err
+	}
+	req := &mergeRequest{}
+	err = readJSON(w, r, req)
+	if err != nil {
+		return err
+	}
+	if len(req.Meals) == 0 {
+		return httpError(http.StatusBadRequest, "no meals to merge")
+	}
+	dups := make([]*osusu.Meal, len(req.Meals))
+	for i, id := range req.Meals {
+		dups[i], err = findMeal(user, id)
+		if err != nil {
+			return err
+		}
+	}
+	err = osusu.MergeMeals(r.Context(), meal, dups)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, meal)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kkoreilly/osusu/osusu"
+	"gorm.io/gorm"
+)
+
+// findGroup returns the group of the given user with its members.
+func findGroup(user *osusu.User) (*osusu.Group, error) {
+	err := requireGroup(user)
+	if err != nil {
+		return nil, err
+	}
+	group := &osusu.Group{}
+	err = osusu.DB.Preload("Members").First(group, user.GroupID).Error
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// groupResponse is a group with its members, which are not included in the JSON of groups.
+type groupResponse struct {
+	*osusu.Group
+	Members []osusu.User
+}
+
+func getGroup(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	group, err := findGroup(user)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, groupResponse{group, group.Members})
+}
+
+func createGroup(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	group := &osusu.Group{}
+	err := readJSON(w, r, group)
+	if err != nil {
+		return err
+	}
+	if group.Name == "" {
+		return httpError(http.StatusBadRequest, "groups must have a name")
+	}
+	group.Model = gorm.Model{}
+	group.Code = ""
+	group.OwnerID = user.ID
+	group.DefaultPresetID = 0
+	err = db(r).Create(group).Error
+	if err != nil {
+		return err
+	}
+	user.GroupID = group.ID
+	err = osusu.DB.Save(user).Error
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, groupResponse{group, []osusu.User{*user}})
+}
+
+func joinGroup(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	req := struct{ Code string }{}
+	err := readJSON(w, r, &req)
+	if err != nil {
+		return err
+	}
+	_, err = user.JoinGroup(req.Code)
+	if errors.Is(err, osusu.ErrInvalidGroupCode) {
+		return httpError(http.StatusNotFound, "%w", err)
+	}
+	if err != nil {
+		return err
+	}
+	return getGroup(w, r, user)
+}
+
+func leaveGroup(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	group, err := findGroup(user)
+	if err != nil {
+		return err
+	}
+	if group.OwnerID == user.ID && len(group.Members) > 1 {
+		return httpError(http.StatusConflict, "the owner can not leave the group while it has other members")
+	}
+	user.GroupID = 0
+	err = osusu.DB.Save(user).Error
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func removeMember(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	group, err := findGroup(user)
+	if err != nil {
+		return err
+	}
+	if group.OwnerID != user.ID {
+		return httpError(http.StatusForbidden, "only the owner can remove members")
+	}
+	if id == user.ID {
+		return httpError(http.StatusBadRequest, "the owner can not remove themself")
+	}
+	for _, m := range group.Members {
+		if m.ID == id {
+			err := osusu.DB.Model(&m).Update("group_id", 0).Error
+			if err != nil {
+				return err
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+	return httpError(http.StatusNotFound, "there is no member with ID %d", id)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// nonceDuration is how long clients have to use a nonce to log in.
+const nonceDuration = 10 * time.Minute
+
+// nonces are the nonces issued to clients for requesting ID tokens,
+// which can each be used once to log in or link an identity.
+type nonces struct {
+	mu sync.Mutex
+	// expiries are the expiry times of the nonces
+	expiries map[string]time.Time
+}
+
+// new issues a new nonce.
+func (n *nonces) new() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(b)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	for k, exp := range n.expiries {
+		if now.After(exp) {
+			delete(n.expiries, k)
+		}
+	}
+	if n.expiries == nil {
+		n.expiries = map[string]time.Time{}
+	}
+	n.expiries[nonce] = now.Add(nonceDuration)
+	return nonce, nil
+}
+
+// use returns whether the given nonce was issued and has not expired or
+// been used yet, and makes it so that it can not be used again.
+func (n *nonces) use(nonce string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	exp, ok := n.expiries[nonce]
+	delete(n.expiries, nonce)
+	return ok && time.Now().Before(exp)
+}
+
+// idTokenRequest is a request with an ID token from a provider.
+type idTokenRequest struct {
+	// Provider is the name of the provider (eg: google)
+	Provider string
+	IDToken  string
+	// Nonce is the nonce from /api/v1/nonces that the ID token was requested with
+	Nonce string
+}
+
+// verify reads an [idTokenRequest] from the given request and returns the
+// claims of its ID token after verifying it and using its nonce.
+func (s *server) verify(w http.ResponseWriter, r *http.Request) (*osusu.Claims, error) {
+	if s.verifier == nil {
+		return nil, httpError(http.StatusNotImplemented, "logging in with providers is not configured")
+	}
+	req := &idTokenRequest{}
+	err := readJSON(w, r, req)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.verifier.Verify(r.Context(), req.Provider, req.IDToken, req.Nonce)
+	if err != nil {
+		return nil, httpError(http.StatusUnauthorized, "invalid ID token: %w", err)
+	}
+	if !s.nonces.use(req.Nonce) {
+		return nil, httpError(http.StatusUnauthorized, "invalid, expired, or used nonce")
+	}
+	return c, nil
+}
+
+func (s *server) createNonce(w http.ResponseWriter, r *http.Request) {
+	nonce, err := s.nonces.new()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"Nonce": nonce})
+}
+
+func (s *server) createSession(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, func() error {
+		c, err := s.verify(w, r)
+		if err != nil {
+			return err
+		}
+		user, err := osusu.Login(c)
+		if errors.Is(err, osusu.ErrEmailInUse) {
+			return httpError(http.StatusConflict, "%w", err)
+		}
+		if err != nil {
+			return err
+		}
+		session, err := osusu.NewSession(user.ID)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusCreated, map[string]any{"Token": session.Token, "User": user})
+	}())
+}
+
+func listIdentities(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	identities, err := user.Identities()
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, identities)
+}
+
+func (s *server) linkIdentity(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	c, err := s.verify(w, r)
+	if err != nil {
+		return err
+	}
+	identity, err := user.LinkIdentity(c)
+	if errors.Is(err, osusu.ErrIdentityInUse) {
+		return httpError(http.StatusConflict, "%w", err)
+	}
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, identity)
+}
+
+func unlinkIdentity(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	err = user.UnlinkIdentity(id)
+	if errors.Is(err, osusu.ErrLastIdentity) {
+		return httpError(http.StatusConflict, "%w", err)
+	}
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,145 @@
+// Command osusu-server serves a JSON API for Osusu data, which can be used by
+// clients that do not embed the app, like dashboards and chat bots.
+//
+// Usage:
+//
+//	osusu-server [-addr addr] [-providers path] [-recipes path] [-vectors path] [-encode]
+//	osusu-server token -user email
+//
+// Requests to the API are authenticated with the token of a session in the
+// Authorization header:
+//
+//	Authorization: Bearer <token>
+//
+// Users log in by getting a nonce, requesting an ID token with it from an OpenID
+// Connect provider, and exchanging the ID token for a session, which verifies the
+// ID token. The providers are configured in a JSON file with a list of
+// [osusu.Provider]s, and default to Google with the client ID in the
+// GOOGLE_OAUTH2_CLIENT_ID environment variable if it is set. The token command
+// creates a new session for the given user and prints its token, which can be
+// used for clients like chat bots.
+//
+// The API is versioned under /api/v1:
+//
+//	POST   /api/v1/nonces               get a nonce to request an ID token with
+//	POST   /api/v1/sessions             log in with {"Provider", "IDToken", "Nonce"} and get a session token
+//	GET    /api/v1/me                   the current user
+//	PATCH  /api/v1/me                   update the given fields of the profile of the current user
+//	DELETE /api/v1/me                   delete the account of the current user, keeping their entries anonymously unless ?anonymize=false
+//	GET    /api/v1/me/data              export all of the personal data of the current user
+//	GET    /api/v1/me/identities        the provider accounts that the current user logs in with
+//	POST   /api/v1/me/identities        link another provider account with {"Provider", "IDToken", "Nonce"}
+//	DELETE /api/v1/me/identities/{id}   unlink a provider account
+//	DELETE /api/v1/session              log out of the current session
+//	GET    /api/v1/meals                the meals of the group
+//	POST   /api/v1/meals                create a meal
+//	GET    /api/v1/meals/duplicates     the groups of likely duplicate meals in the group
+//	GET    /api/v1/meals/{id}           get a meal
+//	PATCH  /api/v1/meals/{id}           update the given fields of a meal
+//	DELETE /api/v1/meals/{id}           move a meal and its entries to the trash
+//	GET    /api/v1/meals/{id}/history   the audit log of a meal and its entries
+//	POST   /api/v1/meals/{id}/revert    revert a meal to the version in the given audit record
+//	POST   /api/v1/meals/{id}/merge     merge the meals with the given {"Meals": [ids]} into a meal
+//	GET    /api/v1/entries              the entries of the group, optionally for ?meal=id and ?user=id
+//	POST   /api/v1/entries              create an entry for the current user
+//	GET    /api/v1/entries/{id}         get an entry
+//	PATCH  /api/v1/entries/{id}         update the given fields of an entry of the current user
+//	DELETE /api/v1/entries/{id}         move an entry of the current user to the trash
+//	GET    /api/v1/trash                the deleted meals of the group and entries of the current user
+//	DELETE /api/v1/trash                permanently delete everything in the trash
+//	POST   /api/v1/trash/{kind}/{id}/restore  restore a meal or entry, where kind is meals or entries
+//	DELETE /api/v1/trash/{kind}/{id}    permanently delete a meal or entry
+//	GET    /api/v1/scores               the scored meals of the group, highest first
+//	GET    /api/v1/recommendations      the recipes recommended by Discover
+//	GET    /api/v1/group                the group with its members
+//	POST   /api/v1/group                create a group and join it
+//	POST   /api/v1/group/join           join the group with the given code
+//	POST   /api/v1/group/leave          leave the group
+//	DELETE /api/v1/group/members/{id}   remove a member from the group, which only the owner can do
+//	GET    /api/v1/group/history        the n most recent changes in the group
+//
+// Scores and recommendations use the options of the current preset of the user,
+// or the one named by ?preset=name, with any of the options given as query
+// parameters named after the fields of the options (eg: ?categories=Dinner&tasteImportance=80),
+// and return the top ?n=n results.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cogentcore.org/core/base/errors"
+	"cogentcore.org/core/base/iox/jsonx"
+	"github.com/kkoreilly/osusu/osusu"
+	"github.com/kkoreilly/osusu/otextencoding"
+	"github.com/nlpodyssey/cybertron/pkg/client"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		token(os.Args[2:])
+		return
+	}
+	addr := flag.String("addr", ":8080", "the address to listen on")
+	providersPath := flag.String("providers", "", "the JSON file with the OpenID Connect providers that users can log in with")
+	recipesPath := flag.String("recipes", filepath.Join("cmd", "osusu", "recipes.json"), "the recipes file")
+	vectorsPath := flag.String("vectors", filepath.Join("cmd", "osusu", "textEncodingVectors.json"), "the recipe text encoding vectors file")
+	encode := flag.Bool("encode", false, "text encode meals with the text encoding server instead of using the vectors of their recipes")
+	flag.Parse()
+
+	errors.Must(osusu.OpenDB())
+	s := &server{}
+	errors.Must(jsonx.Open(&s.recipes, *recipesPath))
+	for _, recipe := range s.recipes {
+		errors.Log(recipe.Init())
+		errors.Log(recipe.CategoryFlag.SetString(strings.Join(recipe.Category, "|")))
+		errors.Log(recipe.CuisineFlag.SetString(strings.Join(recipe.Cuisine, "|")))
+	}
+	errors.Must(jsonx.Open(&s.vectors, *vectorsPath))
+
+	var providers []*osusu.Provider
+	if *providersPath != "" {
+		errors.Must(jsonx.Open(&providers, *providersPath))
+	} else if id := os.Getenv("GOOGLE_OAUTH2_CLIENT_ID"); id != "" {
+		providers = []*osusu.Provider{{Name: "google", Issuer: osusu.GoogleIssuer, ClientIDs: []string{id}}}
+	}
+	if len(providers) > 0 {
+		s.verifier = errors.Must1(osusu.NewVerifier(context.Background(), providers))
+	}
+	if *encode {
+		otextencoding.Model = client.NewClientForTextEncoding("localhost:8081", client.Options{})
+		s.encode = func(meal *osusu.Meal) ([]float32, error) {
+			res, err := otextencoding.Model.Encode(context.TODO(), meal.Text(), int(bert.MeanPooling))
+			if err != nil {
+				return nil, err
+			}
+			return res.Vector.Data().F32(), nil
+		}
+	}
+
+	slog.Info("serving the API", "addr", *addr)
+	errors.Must(http.ListenAndServe(*addr, s.routes()))
+}
+
+// token creates a new session for a user and prints its token.
+func token(args []string) {
+	fs := flag.NewFlagSet("token", flag.ExitOnError)
+	email := fs.String("user", "", "the email of the user")
+	errors.Must(fs.Parse(args))
+	if *email == "" {
+		fmt.Fprintln(os.Stderr, "usage: osusu-server token -user email")
+		os.Exit(2)
+	}
+	errors.Must(osusu.OpenDB())
+	user := &osusu.User{}
+	errors.Must(osusu.DB.First(user, "email = ?", *email).Error)
+	session := errors.Must1(osusu.NewSession(user.ID))
+	fmt.Println(session.Token)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kkoreilly/osusu/osusu"
+	"gorm.io/gorm"
+)
+
+// groupMeals returns a query for the meals of the group of the given user.
+func groupMeals(user *osusu.User) *gorm.DB {
+	return osusu.DB.Where("group_id = ?", user.GroupID)
+}
+
+// groupEntries returns a query for the entries for the meals of the group of the given user.
+func groupEntries(user *osusu.User) *gorm.DB {
+	return osusu.DB.Where("meal_id IN (?)", osusu.DB.Model(&osusu.Meal{}).Select("id").Where("group_id = ?", user.GroupID))
+}
+
+// findMeal returns the meal of the group of the given user with the given ID.
+func findMeal(user *osusu.User, id uint) (*osusu.Meal, error) {
+	meal := &osusu.Meal{}
+	err := groupMeals(user).First(meal, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return meal, nil
+}
+
+func listMeals(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	meals := []*osusu.Meal{}
+	err := groupMeals(user).Find(&meals).Error
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, meals)
+}
+
+func createMeal(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	meal := &osusu.Meal{}
+	err := readJSON(w, r, meal)
+	if err != nil {
+		return err
+	}
+	if meal.Name == "" {
+		return httpError(http.StatusBadRequest, "meals must have a name")
+	}
+	meal.Model = gorm.Model{}
+	meal.GroupID, meal.CreatorID, meal.DeletionID = user.GroupID, user.ID, ""
+	err = db(r).Create(meal).Error
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, meal)
+}
+
+func getMeal(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	meal, err := findMeal(user, id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, meal)
+}
+
+func updateMeal(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	meal, err := findMeal(user, id)
+	if err != nil {
+		return err
+	}
+	model, groupID, creatorID, deletionID := meal.Model, meal.GroupID, meal.CreatorID, meal.DeletionID
+	// the fields in the request are decoded on top of the existing meal
+	err = readJSON(w, r, meal)
+	if err != nil {
+		return err
+	}
+	meal.Model, meal.GroupID, meal.CreatorID, meal.DeletionID = model, groupID, creatorID, deletionID
+	err = db(r).Save(meal).Error
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, meal)
+}
+
+func deleteMeal(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	meal, err := findMeal(user, id)
+	if err != nil {
+		return err
+	}
+	err = meal.Delete(r.Context())
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func listEntries(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	q := groupEntries(user).Order("time DESC")
+	mealID, err := queryUint(r, "meal")
+	if err != nil {
+		return err
+	}
+	if mealID != 0 {
+		q = q.Where("meal_id = ?", mealID)
+	}
+	userID, err := queryUint(r, "user")
+	if err != nil {
+		return err
+	}
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+	}
+	entries := []*osusu.Entry{}
+	err = q.Find(&entries).Error
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, entries)
+}
+
+// findEntry returns the entry with the given ID for a meal of the group of the
+// given user. If own is true, it must also be an entry of the user.
+func findEntry(user *osusu.User, id uint, own bool) (*osusu.Entry, error) {
+	entry := &osusu.Entry{}
+	err := groupEntries(user).First(entry, id).Error
+	if err != nil {
+		return nil, err
+	}
+	if own && entry.UserID != user.ID {
+		return nil, httpError(http.StatusForbidden, "you can only change your own entries")
+	}
+	return entry, nil
+}
+
+func (s *server) createEntry(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	// the default values are the same as in the app
+	entry := &osusu.Entry{
+		Time:        time.Now(),
+		Taste:       50,
+		Cost:        50,
+		Effort:      50,
+		Healthiness: 50,
+		Servings:    1,
+	}
+	err := readJSON(w, r, entry)
+	if err != nil {
+		return err
+	}
+	meal, err := findMeal(user, entry.MealID)
+	if err != nil {
+		return err
+	}
+	entry.Model = gorm.Model{}
+	entry.UserID, entry.DeletionID = user.ID, ""
+	if entry.Category == 0 {
+		entry.Category = meal.Category
+	}
+	if entry.Source == 0 {
+		entry.Source = meal.Source
+	}
+
+	dims, err := osusu.LoadDimensions(user.GroupID)
+	if err != nil {
+		return err
+	}
+	for _, d := range dims {
+		if d.IsBuiltin() {
+			continue
+		}
+		if _, ok := entry.Ratings[d.Name]; !ok {
+			if entry.Ratings == nil {
+				entry.Ratings = map[string]int{}
+			}
+			entry.Ratings[d.Name] = d.DefaultRating()
+		}
+	}
+
+	// the choice is recorded before the entry is created so that it is compared to the other meals as they were
+	preset, err := osusu.LoadPreset(user)
+	if err != nil {
+		return err
+	}
+	scored, err := osusu.ScoreMeals(user, &preset.Options, dims)
+	if err != nil {
+		return err
+	}
+	err = db(r).Create(entry).Error
+	if err != nil {
+		return err
+	}
+	// meals that do not match the options are not recommendations
+	if choice := osusu.NewEntryChoice(user.ID, meal.ID, scored); choice != nil {
+		err := osusu.DB.Create(choice).Error
+		if err != nil {
+			return err
+		}
+	}
+	return writeJSON(w, http.StatusCreated, entry)
+}
+
+func getEntry(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	entry, err := findEntry(user, id, false)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, entry)
+}
+
+func updateEntry(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	entry, err := findEntry(user, id, true)
+	if err != nil {
+		return err
+	}
+	model, deletionID := entry.Model, entry.DeletionID
+	// the fields in the request are decoded on top of the existing entry
+	err = readJSON(w, r, entry)
+	if err != nil {
+		return err
+	}
+	entry.Model, entry.UserID, entry.DeletionID = model, user.ID, deletionID
+	_, err = findMeal(user, entry.MealID)
+	if err != nil {
+		return err
+	}
+	err = db(r).Save(entry).Error
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, entry)
+}
+
+func deleteEntry(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	entry, err := findEntry(user, id, true)
+	if err != nil {
+		return err
+	}
+	err = entry.Delete(r.Context())
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/kkoreilly/osusu/osusu"
+	"gorm.io/gorm"
+)
+
+// queryOptions returns the options for the given request, which are those of the
+// current preset of the given user or of the one named by the preset query parameter,
+// with any options given as query parameters (see [osusu.Options.SetQuery]) and
+// with the default importances of any of the given dimensions that they do not have.
+func queryOptions(r *http.Request, user *osusu.User, dims []*osusu.Dimension) (*osusu.Options, error) {
+	q := r.URL.Query()
+	preset := &osusu.Preset{}
+	if name := q.Get("preset"); name != "" {
+		err := osusu.DB.First(preset, "user_id = ? AND name = ?", user.ID, name).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, httpError(http.StatusNotFound, "there is no preset named %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		preset, err = osusu.LoadPreset(user)
+		if err != nil {
+			return nil, err
+		}
+	}
+	opts := &preset.Options
+	opts.AddDimensionImportances(dims)
+	err := opts.SetQuery(q)
+	if err != nil {
+		return nil, httpError(http.StatusBadRequest, "%w", err)
+	}
+	return opts, nil
+}
+
+// queryN returns the number of results to return for the given request,
+// which is given by the n query parameter, or the given default number.
+func queryN(r *http.Request, def int) (int, error) {
+	s := r.URL.Query().Get("n")
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, httpError(http.StatusBadRequest, "invalid n %q", s)
+	}
+	return n, nil
+}
+
+func (s *server) scores(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	n, err := queryN(r, 20)
+	if err != nil {
+		return err
+	}
+	dims, err := osusu.LoadDimensions(user.GroupID)
+	if err != nil {
+		return err
+	}
+	opts, err := queryOptions(r, user, dims)
+	if err != nil {
+		return err
+	}
+	scored, err := osusu.ScoreMeals(user, opts, dims)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		scored = scored[:min(n, len(scored))]
+	}
+	return writeJSON(w, http.StatusOK, scored)
+}
+
+// mealVectors returns the text encoding vectors of the given meals keyed by meal ID.
+func (s *server) mealVectors(meals []*osusu.Meal) (map[uint][]float32, error) {
+	vectors := osusu.SourceVectors(meals, s.vectors)
+	if s.encode != nil {
+		for _, meal := range meals {
+			v, err := s.encode(meal)
+			if err != nil {
+				return nil, err
+			}
+			vectors[meal.ID] = v
+		}
+	}
+	return vectors, nil
+}
+
+func (s *server) recommendations(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	n, err := queryN(r, 20)
+	if err != nil {
+		return err
+	}
+	meals := []*osusu.Meal{}
+	err = groupMeals(user).Find(&meals).Error
+	if err != nil {
+		return err
+	}
+	mealVectors, err := s.mealVectors(meals)
+	if err != nil {
+		return err
+	}
+
+	dims, err := osusu.LoadDimensions(user.GroupID)
+	if err != nil {
+		return err
+	}
+	opts, err := queryOptions(r, user, dims)
+	if err != nil {
+		return err
+	}
+	// recommending sets the scores of the recipes, so each request scores its own copies
+	recipes := make([]*osusu.Recipe, len(s.recipes))
+	for i, recipe := range s.recipes {
+		c := *recipe
+		recipes[i] = &c
+	}
+	ranked, err := osusu.Discover(recipes, s.vectors, meals, mealVectors, user, opts, dims, n)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, ranked)
+}
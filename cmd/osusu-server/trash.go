@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// trashItem returns the function to restore or purge the meal or entry
+// in the trash of the given user with the ID in the path of the given request.
+func trashItem(r *http.Request, user *osusu.User, restore bool) (func(ctx context.Context) error, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	trash, err := osusu.LoadTrash(user.ID, user.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if r.PathValue("kind") == "meals" {
+		for _, m := range trash.Meals {
+			if m.ID == id {
+				if restore {
+					return m.Restore, nil
+				}
+				return m.Purge, nil
+			}
+		}
+	} else {
+		for _, e := range trash.Entries {
+			if e.ID == id {
+				if restore {
+					return e.Restore, nil
+				}
+				return e.Purge, nil
+			}
+		}
+	}
+	return nil, httpError(http.StatusNotFound, "there is no item with ID %d in the trash", id)
+}
+
+func getTrash(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	trash, err := osusu.LoadTrash(user.ID, user.GroupID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, trash)
+}
+
+func emptyTrash(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	trash, err := osusu.LoadTrash(user.ID, user.GroupID)
+	if err != nil {
+		return err
+	}
+	err = trash.Empty(r.Context())
+	if err != nil {
+		return err
+	}
+	// meals that the user is not allowed to purge are left in the trash
+	return writeJSON(w, http.StatusOK, trash)
+}
+
+func restoreTrashItem(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	restore, err := trashItem(r, user, true)
+	if err != nil {
+		return err
+	}
+	err = restore(r.Context())
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func purgeTrashItem(w http.ResponseWriter, r *http.Request, user *osusu.User) error {
+	purge, err := trashItem(r, user, false)
+	if err != nil {
+		return err
+	}
+	err = purge(r.Context())
+	if errors.Is(err, osusu.ErrPurgeNotAllowed) {
+		return &apiError{http.StatusForbidden, err}
+	}
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"cogentcore.org/core/colors"
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"cogentcore.org/core/icons"
+	"cogentcore.org/core/styles"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// auditText returns a description of the change in the given audit record, like
+// "Alice updated the meal".
+func auditText(ar *osusu.AuditRecord) string {
+	actor := ar.Actor.Name
+	if actor == "" {
+		actor = "Someone"
+	}
+	verb := map[osusu.AuditActions]string{
+		osusu.AuditCreate:  "created",
+		osusu.AuditUpdate:  "updated",
+		osusu.AuditDelete:  "deleted",
+		osusu.AuditRestore: "restored",
+	}[ar.Action]
+	record := map[string]string{
+		"meals":   "the meal",
+		"entries": "an entry",
+		"groups":  "the group",
+	}[ar.RecordTable]
+	return actor + " " + verb + " " + record
+}
+
+// viewAuditLog opens a dialog with the given title showing the given records in
+// the audit log. If revert is not nil, the records of meals have a button that
+// calls it to revert the meal to that version.
+func viewAuditLog(ctx core.Widget, title string, records []*osusu.AuditRecord, revert func(ar *osusu.AuditRecord)) {
+	d := core.NewBody(title)
+	if len(records) == 0 {
+		core.NewText(d).SetText("There are no changes yet")
+	}
+	for _, ar := range records {
+		c := core.NewFrame(d)
+		cardStyles(c)
+		core.NewText(c).SetType(core.TextHeadlineSmall).SetText(auditText(ar))
+		core.NewText(c).SetText(ar.Time.Format("Monday, January 2, 2006 at 3:04 PM")).Styler(func(s *styles.Style) {
+			s.Color = colors.Scheme.OnSurfaceVariant
+		})
+		for _, change := range ar.Changes {
+			core.NewText(c).SetText(change.String())
+		}
+		if revert != nil && ar.RecordTable == "meals" {
+			core.NewButton(c).SetType(core.ButtonTonal).SetIcon(icons.History).SetText("Revert to this version").OnClick(func(e events.Event) {
+				d.Close()
+				revert(ar)
+			})
+		}
+	}
+	d.RunFullDialog(ctx)
+}
+
+// viewMealHistory opens a dialog showing the audit log of the given meal and
+// its entries, from which the meal can be reverted to a previous version.
+// It calls the given function after the meal is reverted.
+func viewMealHistory(ctx core.Widget, meal *osusu.Meal, reverted func()) {
+	records, err := osusu.MealHistory(meal.ID)
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	viewAuditLog(ctx, "History of "+meal.Name, records, func(ar *osusu.AuditRecord) {
+		_, err := osusu.RevertMeal(actorCtx, meal.ID, ar.ID)
+		if err != nil {
+			core.ErrorDialog(ctx, err)
+			return
+		}
+		reverted()
+		core.MessageSnackbar(ctx, "Reverted "+meal.Name)
+	})
+}
+
+// viewGroupActivity opens a dialog showing the recent changes in the current group.
+func viewGroupActivity(ctx core.Widget) {
+	records, err := osusu.GroupHistory(curGroup.ID, 100)
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	viewAuditLog(ctx, "Activity", records, nil)
+}
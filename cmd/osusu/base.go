@@ -1,7 +1,6 @@
 package main
 
 import (
-	"errors"
 	"path/filepath"
 
 	"cogentcore.org/core/base/auth"
@@ -10,7 +9,6 @@ import (
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/kkoreilly/osusu/osusu"
 	"golang.org/x/oauth2"
-	"gorm.io/gorm"
 )
 
 func base(b *core.Body) {
@@ -25,27 +23,21 @@ func base(b *core.Body) {
 	core.NewText(b).SetType(core.TextTitleLarge).SetText("An app for getting recommendations on what meals to eat in a group based on the ratings of each member of the group, and the cost, effort, healthiness, and recency of the meal.")
 
 	fun := func(token *oauth2.Token, userInfo *oidc.UserInfo) {
-		user := &osusu.User{}
-		err := userInfo.Claims(&user)
+		// the user info is from the provider itself, so we can trust its subject
+		claims := &osusu.Claims{}
+		err := userInfo.Claims(claims)
 		if err != nil {
 			core.ErrorDialog(b, err)
 			return
 		}
-		var oldUser osusu.User
-		err = osusu.DB.First(&oldUser, "email = ?", user.Email).Error
-		// if we already have a user with the same email, we don't need to make a new account
-		if err == nil {
-			curUser = &oldUser
-			home()
-			return
-		}
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			core.ErrorDialog(b, err)
-			return
-		}
-		err = osusu.DB.Create(user).Error
+		claims.Issuer = osusu.GoogleIssuer
+		claims.Subject = userInfo.Subject
+		claims.Email = userInfo.Email
+		claims.EmailVerified = userInfo.EmailVerified
+		user, err := osusu.Login(claims)
 		if err != nil {
 			core.ErrorDialog(b, err)
+			return
 		}
 		curUser = user
 		home()
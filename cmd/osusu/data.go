@@ -0,0 +1,58 @@
+package main
+
+import (
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"cogentcore.org/core/icons"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// dataSettings are the settings for exporting and importing data.
+type dataSettings struct {
+	// Format is the format of the data; CSV data is a directory with one file per table
+	Format osusu.DataFormats
+	// Path is the file or directory to export to or import from; for importing CSV data, it can be any file in the directory
+	Path core.Filename
+}
+
+// manageData opens a dialog for exporting the data of the current group and
+// importing data into it, calling the given function after data is imported.
+// Imported records are always created as new records, so that importing can
+// never change existing records.
+func manageData(ctx core.Widget, imported func()) {
+	d := core.NewBody("Export and import")
+	settings := &dataSettings{Path: "osusu.json"}
+	core.NewForm(d).SetStruct(settings)
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddCancel(bar)
+		core.NewButton(bar).SetType(core.ButtonOutlined).SetIcon(icons.Upload).SetText("Import").OnClick(func(e events.Event) {
+			de, err := osusu.OpenDataExport(string(settings.Path), settings.Format)
+			if err != nil {
+				core.ErrorDialog(d, err, "Error opening data")
+				return
+			}
+			res, err := de.Import(&osusu.ImportOptions{Conflicts: osusu.ImportNew, GroupID: curGroup.ID, UserID: curUser.ID})
+			if err != nil {
+				core.ErrorDialog(d, err, "Error importing data")
+				return
+			}
+			d.Close()
+			core.MessageSnackbar(ctx, res.String())
+			imported()
+		})
+		d.AddOK(bar).SetIcon(icons.Download).SetText("Export").OnClick(func(e events.Event) {
+			de, err := osusu.ExportData(curGroup.ID)
+			if err != nil {
+				core.ErrorDialog(ctx, err, "Error exporting data")
+				return
+			}
+			err = de.Save(string(settings.Path), settings.Format)
+			if err != nil {
+				core.ErrorDialog(ctx, err, "Error saving data")
+				return
+			}
+			core.MessageSnackbar(ctx, "Exported data to "+string(settings.Path))
+		})
+	})
+	d.RunDialog(ctx)
+}
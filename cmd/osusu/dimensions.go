@@ -0,0 +1,41 @@
+package main
+
+import (
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// editDimensions opens a dialog for editing the custom dimensions of the
+// current group, calling the given function after the dimensions are saved.
+func editDimensions(ctx core.Widget, saved func()) {
+	var dims []*osusu.Dimension
+	err := osusu.DB.Find(&dims, "group_id = ?", curGroup.ID).Error
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	d := core.NewBody("Custom dimensions")
+	core.NewText(d).SetText("Custom dimensions are rated in entries and scored in addition to taste, recency, cost, effort, and healthiness. Ratings are from the minimum to the maximum, and the dimension is better when the rating is higher unless otherwise specified.")
+	core.NewTable(d).SetSlice(&dims)
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddCancel(bar)
+		d.AddOK(bar).SetText("Save").OnClick(func(e events.Event) {
+			err := osusu.SaveDimensions(actorCtx, curGroup.ID, dims)
+			if err != nil {
+				core.ErrorDialog(d, err)
+				return
+			}
+			all, err := osusu.LoadDimensions(curGroup.ID)
+			if err != nil {
+				core.ErrorDialog(d, err)
+				return
+			}
+			curDimensions = all
+			// the importances of renamed dimensions were moved in the saved preset
+			loadPreset(d)
+			saved()
+		})
+	})
+	d.RunFullDialog(ctx)
+}
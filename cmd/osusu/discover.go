@@ -1,10 +1,8 @@
 package main
 
 import (
-	"cmp"
 	"context"
 	"embed"
-	"slices"
 	"strings"
 
 	"cogentcore.org/core/base/errors"
@@ -17,7 +15,6 @@ import (
 	"github.com/kkoreilly/osusu/otextencoding"
 	"github.com/nlpodyssey/cybertron/pkg/client"
 	"github.com/nlpodyssey/cybertron/pkg/models/bert"
-	"github.com/nlpodyssey/spago/mat"
 )
 
 //go:embed recipes.json
@@ -30,6 +27,9 @@ var textEncodingVectorsFS embed.FS
 
 var textEncodingVectors map[string][]float32
 
+// discoverScores are the scores of the recipes currently shown in discover.
+var discoverScores []*osusu.Score
+
 func configDiscover(rf *core.Frame, mf *core.Frame) {
 	// TODO: use Makers and Plans
 	if rf.HasChildren() {
@@ -48,6 +48,8 @@ func configDiscover(rf *core.Frame, mf *core.Frame) {
 		}
 		for _, recipe := range recipes {
 			errors.Log(recipe.Init())
+			errors.Log(recipe.CategoryFlag.SetString(strings.Join(recipe.Category, "|")))
+			errors.Log(recipe.CuisineFlag.SetString(strings.Join(recipe.Cuisine, "|")))
 		}
 
 		err = jsonx.OpenFS(&textEncodingVectors, textEncodingVectorsFS, "textEncodingVectors.json")
@@ -59,102 +61,38 @@ func configDiscover(rf *core.Frame, mf *core.Frame) {
 		otextencoding.Model = client.NewClientForTextEncoding("localhost:8081", client.Options{})
 	}
 
+	// meals without a group are not shown to users without a group
 	var meals []*osusu.Meal
-	err := osusu.DB.Find(&meals).Error
-	if err != nil {
-		core.ErrorDialog(rf, err)
-	}
-	mealEntries := map[uint][]osusu.Entry{}
-	mealVectors := make([]mat.Matrix, len(meals))
-	for i, meal := range meals {
-
-		entries := []osusu.Entry{}
-		err := osusu.DB.Find(&entries, "meal_id = ? AND user_id = ?", meal.ID, curUser.ID).Error
+	if curGroup.ID != 0 {
+		err := osusu.DB.Find(&meals, "group_id = ?", curGroup.ID).Error
 		if err != nil {
 			core.ErrorDialog(rf, err)
 		}
-		mealEntries[meal.ID] = entries
-
+	}
+	mealVectors := map[uint][]float32{}
+	for _, meal := range meals {
 		res, err := otextencoding.Model.Encode(context.TODO(), meal.Text(), int(bert.MeanPooling))
 		if err != nil {
 			core.ErrorDialog(rf, err, "Error text encoding meal")
 			continue
 		}
-		mealVectors[i] = res.Vector
-	}
-
-	for _, recipe := range recipes {
-		// first we get the base score index
-		// TODO(kai/osusu): cache this step
-		recipe.ComputeBaseScoreIndex()
-
-		// then we get the raw text encoding score
-		// TODO(kai/osusu): cache this step
-		recipeVector := textEncodingVectors[recipe.URL]
-		recipeMat := mat.NewDense[float32](mat.WithBacking(recipeVector))
-		recipe.TextEncodingScores = map[uint]float32{}
-		for i, meal := range meals {
-			mealVector := mealVectors[i]
-			score := mealVector.DotUnitary(recipeMat)
-			recipe.TextEncodingScores[meal.ID] = score.Item().F32()
-		}
-
-		// then we get the weighted score
-		// this step can not be cached
-		weightedScores := make([]*osusu.Score, len(meals))
-		for i, meal := range meals {
-			textEncodingScore := recipe.TextEncodingScores[meal.ID]
-			entries := mealEntries[meal.ID]
-
-			score := meal.Score(entries)
-			score.ComputeTotal(curOptions)
-
-			osusu.MulScore(score, textEncodingScore)
-			weightedScores[i] = score
-		}
-		recipe.EncodingScoreIndex = *osusu.AverageScore(weightedScores)
+		mealVectors[meal.ID] = res.Vector.Data().F32()
 	}
 
-	// now we can compute the percentile scores
-	osusu.ComputeNormScores(recipes)
-
-	// and then the total scores
-	for _, recipe := range recipes {
-		recipe.BaseScore.ComputeTotal(curOptions)
-		recipe.EncodingScore.ComputeTotal(curOptions)
-		// encoding score is three times more important than base score
-		recipe.Score = *osusu.AverageScore([]*osusu.Score{&recipe.BaseScore, &recipe.EncodingScore, &recipe.EncodingScore, &recipe.EncodingScore})
+	ranked, err := osusu.Discover(recipes, textEncodingVectors, meals, mealVectors, curUser, curOptions, curDimensions, 100)
+	if err != nil {
+		core.ErrorDialog(rf, err)
+		return
 	}
 
-	slices.SortFunc(recipes, func(a, b *osusu.Recipe) int {
-		return cmp.Compare(b.Score.Total, a.Score.Total)
-	})
-
-	for _, recipe := range recipes {
+	discoverScores = nil
+	for _, recipe := range ranked {
 		recipe := recipe
 
-		if rf.NumChildren() > 100 {
-			break
-		}
-
-		errors.Log(recipe.CategoryFlag.SetString(strings.Join(recipe.Category, "|")))
-		errors.Log(recipe.CuisineFlag.SetString(strings.Join(recipe.Cuisine, "|")))
-
-		if !bitFlagsOverlap(&recipe.CategoryFlag, &curOptions.Categories) ||
-			!bitFlagsOverlap(&recipe.CuisineFlag, &curOptions.Cuisines) {
-			continue
-		}
-
 		rc := core.NewFrame(rf)
 		cardStyles(rc)
 
-		img := core.NewImage(rc)
-		go func() {
-			if i := getImageFromURL(recipe.Image); i != nil {
-				img.SetImage(i)
-				img.Update()
-			}
-		}()
+		cardImage(rc, recipe.Image)
 
 		core.NewText(rc).SetType(core.TextHeadlineSmall).SetText(recipe.Name)
 
@@ -169,7 +107,8 @@ func configDiscover(rf *core.Frame, mf *core.Frame) {
 			s.Color = colors.Scheme.OnSurfaceVariant
 		})
 
-		scoreGrid(rc, &recipe.Score, true)
+		discoverScores = append(discoverScores, &recipe.Score)
+		scoreGrid(rc, &recipe.Score, true, false)
 
 		rc.OnClick(func(e events.Event) {
 			addRecipe(rf, recipe, rc, mf)
@@ -184,17 +123,51 @@ func addRecipe(rf *core.Frame, recipe *osusu.Recipe, rc *core.Frame, mf *core.Fr
 	core.NewForm(d).SetStruct(recipe).SetReadOnly(true)
 	d.AddBottomBar(func(bar *core.Frame) {
 		d.AddCancel(bar)
+		core.NewButton(bar).SetType(core.ButtonOutlined).SetText("Not interested").OnClick(func(e events.Event) {
+			dismissRecipe(d, recipe, func() {
+				d.Close()
+				configDiscover(rf, mf)
+			})
+		})
 		d.AddOK(bar).SetText("Add").OnClick(func(e events.Event) {
 			meal := &osusu.Meal{
-				Name:        recipe.Name,
-				Description: recipe.Description,
-				Image:       recipe.Image,
-				Category:    recipe.CategoryFlag,
-				Cuisine:     recipe.CuisineFlag,
+				Name:           recipe.Name,
+				Description:    recipe.Description,
+				Image:          recipe.Image,
+				SourceURL:      recipe.URL,
+				Category:       recipe.CategoryFlag,
+				Cuisine:        recipe.CuisineFlag,
+				Nutrition:      recipe.ServingNutrition(),
+				CostPerServing: recipe.CostPerServing,
 			}
 			meal.Source.SetFlag(true, osusu.Cooking)
+			recordChoice(osusu.DiscoverChoice, &recipe.Score, discoverScores)
 			newMeal(rf, mf, meal)
 		})
 	})
 	d.RunFullDialog(rc)
 }
+
+// dismissRecipe opens a dialog for dismissing the given recipe so that it no longer
+// appears in Discover, calling the given function after it is dismissed.
+func dismissRecipe(ctx core.Widget, recipe *osusu.Recipe, dismissed func()) {
+	d := core.NewBody("Not interested in " + recipe.Name)
+	dismissal := &osusu.Dismissal{
+		UserID:  curUser.ID,
+		GroupID: curGroup.ID,
+		URL:     recipe.URL,
+	}
+	core.NewForm(d).SetStruct(dismissal)
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddCancel(bar)
+		d.AddOK(bar).SetText("Dismiss").OnClick(func(e events.Event) {
+			err := osusu.DB.Create(dismissal).Error
+			if err != nil {
+				core.ErrorDialog(d, err)
+				return
+			}
+			dismissed()
+		})
+	})
+	d.RunDialog(ctx)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"cogentcore.org/core/base/errors"
+	"cogentcore.org/core/colors"
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"cogentcore.org/core/icons"
+	"cogentcore.org/core/styles"
+	"github.com/kkoreilly/osusu/osusu"
+	"github.com/kkoreilly/osusu/otextencoding"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+)
+
+// mergeDuplicates opens a dialog for merging the duplicate meals in the current
+// group, calling the given function after any are merged.
+func mergeDuplicates(ctx core.Widget, merged func()) {
+	var meals []*osusu.Meal
+	err := osusu.DB.Find(&meals, "group_id = ?", curGroup.ID).Error
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	// meals are text encoded if the model has been loaded by discover,
+	// and otherwise only the meals added from recipes have vectors
+	vectors := osusu.SourceVectors(meals, textEncodingVectors)
+	if otextencoding.Model != nil {
+		for _, meal := range meals {
+			res, err := otextencoding.Model.Encode(context.TODO(), meal.Text(), int(bert.MeanPooling))
+			if errors.Log(err) != nil {
+				continue
+			}
+			vectors[meal.ID] = res.Vector.Data().F32()
+		}
+	}
+	dups := osusu.FindDuplicates(meals, vectors, osusu.DefaultDuplicateConfig())
+
+	d := core.NewBody("Duplicate meals")
+	list := core.NewFrame(d)
+	list.Styler(func(s *styles.Style) {
+		s.Wrap = true
+	})
+	if len(dups) == 0 {
+		core.NewText(list).SetText("There are no duplicate meals")
+	}
+	for _, dup := range dups {
+		c := core.NewFrame(list)
+		cardStyles(c)
+		names := make([]string, len(dup.Meals))
+		for i, meal := range dup.Meals {
+			names[i] = meal.Name
+		}
+		core.NewText(c).SetType(core.TextHeadlineSmall).SetText(strings.Join(names, ", "))
+		core.NewText(c).SetText(strconv.Itoa(osusu.Round(100*dup.Similarity)) + "% similar").Styler(func(s *styles.Style) {
+			s.Color = colors.Scheme.OnSurfaceVariant
+		})
+
+		keep := dup.Meals[0]
+		items := make([]core.ChooserItem, len(dup.Meals))
+		for i, meal := range dup.Meals {
+			items[i] = core.ChooserItem{Value: meal, Text: meal.Name, Func: func() {
+				keep = meal
+			}}
+		}
+		core.NewChooser(c).SetItems(items...).SetCurrentIndex(0).SetTooltip("The meal to keep, which the others are merged into")
+
+		// all of the meals are merged by default, but any can be left out
+		merge := make([]bool, len(dup.Meals))
+		for i, meal := range dup.Meals {
+			merge[i] = true
+			sw := core.NewSwitch(c).SetType(core.SwitchCheckbox).SetText(meal.Name).SetChecked(true)
+			sw.SetTooltip("Whether to merge this meal")
+			sw.OnChange(func(e events.Event) {
+				merge[i] = sw.IsChecked()
+			})
+		}
+
+		core.NewButton(c).SetType(core.ButtonTonal).SetIcon(icons.Merge).SetText("Merge").OnClick(func(e events.Event) {
+			var meals []*osusu.Meal
+			for i, meal := range dup.Meals {
+				if merge[i] {
+					meals = append(meals, meal)
+				}
+			}
+			err := osusu.MergeMeals(actorCtx, keep, meals)
+			if err != nil {
+				core.ErrorDialog(d, err)
+				return
+			}
+			c.Delete()
+			list.Update()
+			merged()
+			core.MessageSnackbar(d, "Merged into "+keep.Name)
+		})
+	}
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddOK(bar).SetText("Done")
+	})
+	d.RunFullDialog(ctx)
+}
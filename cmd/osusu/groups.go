@@ -6,12 +6,23 @@ import (
 	"github.com/kkoreilly/osusu/osusu"
 )
 
-func groups(b *core.Body) {
+// groups opens a dialog for joining or creating a group, calling the
+// given function after the current user is in a group.
+func groups(b *core.Body, joined func()) {
 	d := core.NewBody("Groups")
 	core.NewText(d).SetType(core.TextHeadlineMedium).SetText("Join group")
 	groupCode := ""
 	core.Bind(&groupCode, core.NewTextField(d))
-	core.NewButton(d).SetText("Join group")
+	core.NewButton(d).SetText("Join group").OnClick(func(e events.Event) {
+		group, err := curUser.JoinGroup(groupCode)
+		if err != nil {
+			core.ErrorDialog(d, err)
+			return
+		}
+		curGroup = group
+		d.Close()
+		joined()
+	})
 	core.NewText(d).SetType(core.TextHeadlineMedium).SetText("Create group")
 	newGroup := &osusu.Group{OwnerID: curUser.ID, Owner: *curUser, Members: []osusu.User{*curUser}}
 	core.NewForm(d).SetStruct(newGroup)
@@ -28,6 +39,7 @@ func groups(b *core.Body) {
 			core.ErrorDialog(d, err)
 		}
 		d.Close()
+		joined()
 	})
 	d.RunFullDialog(b)
 }
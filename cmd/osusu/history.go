@@ -4,6 +4,7 @@ import (
 	"cogentcore.org/core/colors"
 	"cogentcore.org/core/core"
 	"cogentcore.org/core/events"
+	"cogentcore.org/core/icons"
 	"cogentcore.org/core/styles"
 	"github.com/kkoreilly/osusu/osusu"
 )
@@ -31,13 +32,7 @@ func configHistory(ef *core.Frame) {
 		ec := core.NewFrame(ef)
 		cardStyles(ec)
 
-		img := core.NewImage(ec)
-		go func() {
-			if i := getImageFromURL(entry.Meal.Image); i != nil {
-				img.SetImage(i)
-				img.Update()
-			}
-		}()
+		cardImage(ec, entry.Meal.Image)
 
 		core.NewText(ec).SetType(core.TextHeadlineSmall).SetText(entry.Time.Format("Monday, January 2, 2006"))
 
@@ -56,9 +51,9 @@ func configHistory(ef *core.Frame) {
 			s.Color = colors.Scheme.OnSurfaceVariant
 		})
 
-		score := entry.Score()
-		score.ComputeTotal(curOptions)
-		scoreGrid(ec, score, false)
+		score := entry.Score(curDimensions)
+		score.ComputeTotal(curOptions, curDimensions)
+		scoreGrid(ec, score, false, false)
 
 		ec.OnClick(func(e events.Event) {
 			editEntry(ef, &entry, ec)
@@ -72,13 +67,20 @@ func editEntry(ef *core.Frame, entry *osusu.Entry, ec *core.Frame) {
 	d := core.NewBody("Edit entry")
 	core.NewForm(d).SetStruct(entry)
 	d.AddBottomBar(func(bar *core.Frame) {
+		core.NewButton(bar).SetType(core.ButtonOutlined).SetIcon(icons.Delete).SetText("Delete").OnClick(func(e events.Event) {
+			d.Close()
+			deleteEntry(ef, entry, func() {
+				configHistory(ef)
+			})
+		})
 		d.AddCancel(bar)
 		d.AddOK(bar).SetText("Save").OnClick(func(e events.Event) {
-			err := osusu.DB.Save(entry).Error
+			err := saveWithUndo(ef, entry, entry.ID, "Saved the entry", func() {
+				configHistory(ef)
+			})
 			if err != nil {
 				core.ErrorDialog(d, err)
 			}
-			configHistory(ef)
 		})
 	})
 	d.RunFullDialog(ec)
@@ -1,12 +1,10 @@
 package main
 
 import (
-	"image"
-	"net/http"
+	"context"
 	"strconv"
 
 	"cogentcore.org/core/base/errors"
-	"cogentcore.org/core/base/iox/imagex"
 	"cogentcore.org/core/colors"
 	"cogentcore.org/core/core"
 	"cogentcore.org/core/cursors"
@@ -25,11 +23,37 @@ var (
 	curUser    *osusu.User
 	curGroup   *osusu.Group
 	curOptions = osusu.DefaultOptions()
+	// curDimensions are the dimensions of the current group
+	curDimensions = osusu.BuiltinDimensions
+
+	// actorCtx is the context with the current user as the actor of changes
+	actorCtx context.Context
 )
 
 func home() {
 	b := core.NewBody("Home")
 
+	// all changes are recorded in the audit log as made by the current user
+	actorCtx = osusu.WithActor(context.Background(), curUser.ID)
+	osusu.DB = osusu.DB.WithContext(actorCtx)
+
+	// the group is loaded before the tabs, which show the data of the group;
+	// users without a group have an empty group until they join or create one
+	curGroup = &osusu.Group{}
+	err := osusu.DB.First(curGroup, curUser.GroupID).Error
+	noGroup := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !noGroup {
+		core.ErrorDialog(b, err)
+	}
+
+	dims, err := osusu.LoadDimensions(curUser.GroupID)
+	if err != nil {
+		core.ErrorDialog(b, err)
+	} else {
+		curDimensions = dims
+	}
+	loadPreset(b)
+
 	tabs := core.NewTabs(b).SetType(core.NavigationAuto)
 
 	search, stab := tabs.NewTab("Search")
@@ -48,6 +72,10 @@ func home() {
 	configHistory(history)
 	htab.SetIcon(icons.History)
 
+	nutrition, ntab := tabs.NewTab("Nutrition")
+	configNutrition(nutrition)
+	ntab.SetIcon(icons.Egg)
+
 	b.AddTopBar(func(bar *core.Frame) {
 		tb := core.NewToolbar(bar)
 		tb.Maker(func(p *tree.Plan) {
@@ -57,17 +85,74 @@ func home() {
 					newMeal(tb, search, &osusu.Meal{})
 				})
 			})
+			tree.Add(p, func(w *core.Button) {
+				w.SetIcon(icons.Sell).SetText("Prices")
+				w.OnClick(func(e events.Event) {
+					editPrices(tb, func() {
+						configDiscover(discover, search)
+					})
+				})
+			})
+			tree.Add(p, func(w *core.Button) {
+				w.SetIcon(icons.Tune).SetText("Dimensions")
+				w.OnClick(func(e events.Event) {
+					editDimensions(tb, func() {
+						configSearch(search)
+						configHistory(history)
+						configDiscover(discover, search)
+					})
+				})
+			})
+			tree.Add(p, func(w *core.Button) {
+				w.SetIcon(icons.Database).SetText("Data")
+				w.OnClick(func(e events.Event) {
+					manageData(tb, func() {
+						configSearch(search)
+						configHistory(history)
+						configNutrition(nutrition)
+					})
+				})
+			})
+			tree.Add(p, func(w *core.Button) {
+				w.SetIcon(icons.Merge).SetText("Duplicates")
+				w.OnClick(func(e events.Event) {
+					mergeDuplicates(tb, func() {
+						configSearch(search)
+						configHistory(history)
+					})
+				})
+			})
+			tree.Add(p, func(w *core.Button) {
+				w.SetIcon(icons.Delete).SetText("Trash")
+				w.OnClick(func(e events.Event) {
+					viewTrash(tb, func() {
+						configSearch(search)
+						configHistory(history)
+						configNutrition(nutrition)
+					})
+				})
+			})
+			tree.Add(p, func(w *core.Button) {
+				w.SetIcon(icons.Group).SetText("Activity")
+				w.OnClick(func(e events.Event) {
+					viewGroupActivity(tb)
+				})
+			})
+			tree.Add(p, func(w *core.Button) {
+				w.SetIcon(icons.Person).SetText("Profile")
+				w.OnClick(func(e events.Event) {
+					editProfile(tb)
+				})
+			})
 			tree.Add(p, func(w *core.Button) {
 				w.SetIcon(icons.Sort).SetText("Sort")
 				w.OnClick(func(e events.Event) {
-					d := core.NewBody("Sort and filter")
-					core.NewForm(d).SetStruct(curOptions)
-					d.OnClose(func(e events.Event) {
+					editOptions(tb, func() {
 						configSearch(search)
 						configHistory(history)
+						configNutrition(nutrition)
 						configDiscover(discover, search)
 					})
-					d.RunFullDialog(tb)
 				})
 			})
 		})
@@ -75,14 +160,12 @@ func home() {
 
 	b.RunWindow()
 
-	curGroup = &osusu.Group{}
-	err := osusu.DB.First(curGroup, curUser.GroupID).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			groups(b)
-		} else {
-			core.ErrorDialog(b, err)
-		}
+	if noGroup {
+		groups(b, func() {
+			configSearch(search)
+			configHistory(history)
+			configNutrition(nutrition)
+		})
 	}
 }
 
@@ -92,6 +175,7 @@ func newMeal(ctx core.Widget, mf *core.Frame, meal *osusu.Meal) {
 	d.AddBottomBar(func(bar *core.Frame) {
 		d.AddCancel(bar)
 		d.AddOK(bar).SetText("Create").OnClick(func(e events.Event) {
+			meal.GroupID = curGroup.ID
 			err := osusu.DB.Create(meal).Error
 			if err != nil {
 				core.ErrorDialog(d, err)
@@ -131,14 +215,21 @@ func cardStyles(card *core.Frame) {
 	// })
 }
 
-func scoreGrid(card *core.Frame, score *osusu.Score, showRecency bool) *core.Frame {
+func scoreGrid(card *core.Frame, score *osusu.Score, showRecency, showConfidence bool) *core.Frame {
+	dims := []*osusu.Dimension{}
+	for _, d := range curDimensions {
+		if d.Name == osusu.Recency && !showRecency {
+			continue
+		}
+		dims = append(dims, d)
+	}
+
 	grid := core.NewFrame(card)
 	grid.Styler(func(s *styles.Style) {
 		s.Display = styles.Grid
-		if showRecency {
-			s.Columns = 6
-		} else {
-			s.Columns = 5
+		s.Columns = 1 + len(dims)
+		if showConfidence {
+			s.Columns++
 		}
 		s.Justify.Content = styles.Center
 		s.Justify.Items = styles.Center
@@ -152,43 +243,33 @@ func scoreGrid(card *core.Frame, score *osusu.Score, showRecency bool) *core.Fra
 	}
 
 	label("Total")
-	label("Taste")
-	if showRecency {
-		label("New")
+	for _, d := range dims {
+		label(d.Label)
 	}
-	label("Cost")
-	label("Effort")
-	label("Health")
-
-	value := func(value int) {
-		core.NewText(grid).SetText(strconv.Itoa(value))
+	if showConfidence {
+		label("Sure")
 	}
 
-	value(score.Total)
-	value(score.Taste)
-	if showRecency {
-		value(score.Recency)
+	// scores are only rounded for display
+	value := func(value float64) {
+		core.NewText(grid).SetText(strconv.Itoa(osusu.Round(value)))
 	}
-	value(score.Cost)
-	value(score.Effort)
-	value(score.Healthiness)
-	return grid
-}
 
-func getImageFromURL(url string) image.Image {
-	if url == "" {
-		return nil
-	}
-	resp, err := http.Get(url)
-	if errors.Log(err) != nil {
-		return nil
+	value(score.Total)
+	for _, d := range dims {
+		v, ok := score.Value(d)
+		if !ok {
+			core.NewText(grid).SetText("-")
+			continue
+		}
+		value(v)
 	}
-	defer resp.Body.Close()
-	img, _, err := imagex.Read(resp.Body)
-	if errors.Log(err) != nil {
-		return nil
+	if showConfidence {
+		core.NewText(grid).SetText(strconv.Itoa(osusu.Round(score.Confidence)) + "%").SetTooltip("How confident the score is based on the number of entries; scores with less confidence are closer to your average ratings").Styler(func(s *styles.Style) {
+			s.Color = colors.Scheme.OnSurfaceVariant
+		})
 	}
-	return img
+	return grid
 }
 
 // bitFlagsOverlap returns whether there is any overlap between the two bit flags.
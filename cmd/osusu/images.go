@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"cogentcore.org/core/base/errors"
+	"cogentcore.org/core/colors"
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/styles"
+	"cogentcore.org/core/styles/units"
+	"github.com/kkoreilly/osusu/oimage"
+)
+
+// imageCache returns the cache of meal and recipe images in the app data directory.
+var imageCache = sync.OnceValue(func() *oimage.Cache {
+	return oimage.NewCache(filepath.Join(core.TheApp.AppDataDir(), "images"))
+})
+
+// cardImage adds an image to the given card that shows a placeholder
+// until the thumbnail of the image at the given URL is loaded.
+func cardImage(card *core.Frame, url string) *core.Image {
+	cache := imageCache()
+	img := core.NewImage(card)
+	img.SetImage(oimage.Placeholder(cache.ThumbnailSize, colors.ToUniform(colors.Scheme.SurfaceContainerHighest)))
+	img.Styler(func(s *styles.Style) {
+		s.Min.Set(units.Em(20))
+		s.ObjectFit = styles.FitCover
+	})
+	if url == "" {
+		return img
+	}
+	go func() {
+		thumb, err := cache.Thumbnail(context.Background(), url)
+		if errors.Log(err) != nil {
+			return
+		}
+		img.AsyncLock()
+		img.SetImage(thumb)
+		img.Update()
+		img.AsyncUnlock()
+	}()
+	return img
+}
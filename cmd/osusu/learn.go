@@ -0,0 +1,49 @@
+package main
+
+import (
+	"cogentcore.org/core/base/errors"
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// recordChoice records that the current user chose the item with the given score
+// out of the given scores of all of the recommended items, which can include the
+// chosen score. It is recorded in the background and errors are only logged.
+func recordChoice(kind osusu.ChoiceKinds, chosen *osusu.Score, all []*osusu.Score) {
+	if chosen == nil {
+		return
+	}
+	choice := osusu.NewChoice(curUser.ID, kind, chosen, all)
+	go func() {
+		errors.Log(osusu.DB.Create(choice).Error)
+	}()
+}
+
+// suggestImportances opens a dialog with the importances learned from the
+// choices of the current user, which the user can accept to apply them to
+// the given options, calling the given function after they are applied.
+func suggestImportances(ctx core.Widget, opts *osusu.Options, accepted func()) {
+	choices := []osusu.Choice{}
+	err := osusu.DB.Find(&choices, "user_id = ?", curUser.ID).Error
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	li, err := osusu.LearnImportances(choices, curDimensions, 1)
+	if err != nil {
+		core.ErrorDialog(ctx, err, "Error learning importances")
+		return
+	}
+	d := core.NewBody("Suggested importances")
+	core.NewText(d).SetText("These importances were learned from which meals you create entries for and which recipes you add compared to what else was recommended. The coefficients show how much each metric influences your choices.")
+	core.NewForm(d).SetStruct(li).SetReadOnly(true)
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddCancel(bar).SetText("Reject")
+		d.AddOK(bar).SetText("Accept").OnClick(func(e events.Event) {
+			li.Apply(opts, curDimensions)
+			accepted()
+		})
+	})
+	d.RunDialog(ctx)
+}
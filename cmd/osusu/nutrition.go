@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"cogentcore.org/core/colors"
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"cogentcore.org/core/icons"
+	"cogentcore.org/core/styles"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+func configNutrition(nf *core.Frame) {
+	// TODO: use Makers and Plans
+	if nf.HasChildren() {
+		nf.DeleteChildren()
+	}
+
+	nf.Styler(func(s *styles.Style) {
+		s.Wrap = true
+	})
+
+	entries := []osusu.Entry{}
+	err := osusu.DB.Preload("Meal").Find(&entries, "user_id = ?", curUser.ID).Error
+	if err != nil {
+		core.ErrorDialog(nf, err)
+	}
+
+	core.NewButton(nf).SetIcon(icons.Edit).SetText("Edit targets").OnClick(func(e events.Event) {
+		editTargets(nf)
+	})
+
+	now := time.Now()
+	for _, summary := range osusu.DailyNutrition(entries, now, 7) {
+		nutritionCard(nf, &summary, summary.Start.Format("Monday, January 2"))
+	}
+	for _, summary := range osusu.WeeklyNutrition(entries, now, 4) {
+		nutritionCard(nf, &summary, "Week of "+summary.Start.Format("January 2"))
+	}
+
+	nf.Update()
+}
+
+func nutritionCard(nf *core.Frame, summary *osusu.NutritionSummary, title string) {
+	nc := core.NewFrame(nf)
+	cardStyles(nc)
+	core.NewText(nc).SetType(core.TextHeadlineSmall).SetText(title)
+
+	grid := core.NewFrame(nc)
+	grid.Styler(func(s *styles.Style) {
+		s.Display = styles.Grid
+		s.Columns = 4
+		s.Justify.Content = styles.Center
+		s.Justify.Items = styles.Center
+	})
+
+	label := func(text string) {
+		tx := core.NewText(grid).SetType(core.TextLabelLarge).SetText(text)
+		tx.Styler(func(s *styles.Style) {
+			s.SetTextWrap(false)
+		})
+	}
+	label("Calories")
+	label("Protein")
+	label("Sodium")
+	label("Sugar")
+
+	targets := summary.Targets(curUser.Targets)
+	// limit is whether the target is a maximum instead of a minimum
+	value := func(value, target int, unit string, limit bool) {
+		text := strconv.Itoa(value)
+		if target > 0 {
+			text += "/" + strconv.Itoa(target)
+		}
+		tx := core.NewText(grid).SetText(text + unit)
+		tx.Styler(func(s *styles.Style) {
+			if target > 0 && limit == (value > target) {
+				s.Color = colors.Scheme.Error.Base
+			}
+		})
+	}
+	n := summary.Nutrition
+	value(n.Calories, targets.Calories, "", true)
+	value(n.Protein, targets.Protein, "g", false)
+	value(n.Sodium, targets.Sodium, "mg", true)
+	value(n.Sugar, targets.Sugar, "g", true)
+}
+
+func editTargets(nf *core.Frame) {
+	d := core.NewBody("Edit daily nutrition targets")
+	core.NewText(d).SetText("Set a target to 0 to not have a target for it")
+	targets := curUser.Targets
+	core.NewForm(d).SetStruct(&targets)
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddCancel(bar)
+		d.AddOK(bar).SetText("Save").OnClick(func(e events.Event) {
+			curUser.Targets = targets
+			err := osusu.DB.Save(curUser).Error
+			if err != nil {
+				core.ErrorDialog(d, err)
+			}
+			configNutrition(nf)
+		})
+	})
+	d.RunFullDialog(nf)
+}
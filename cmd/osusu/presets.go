@@ -0,0 +1,147 @@
+package main
+
+import (
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"cogentcore.org/core/icons"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// curPreset is the current options preset of the current user,
+// the options of which are [curOptions].
+var curPreset *osusu.Preset
+
+// loadPreset loads the current preset of the current user.
+func loadPreset(b *core.Body) {
+	preset, err := osusu.LoadPreset(curUser)
+	if err != nil {
+		core.ErrorDialog(b, err)
+		return
+	}
+	setPreset(preset)
+}
+
+// setPreset sets the current preset to the given preset.
+func setPreset(preset *osusu.Preset) {
+	curPreset = preset
+	curOptions = &curPreset.Options
+	curOptions.AddDimensionImportances(curDimensions)
+}
+
+// savePreset saves the current preset and sets it
+// as the current preset of the current user.
+func savePreset(ctx core.Widget) {
+	if curPreset == nil {
+		return
+	}
+	err := osusu.DB.Save(curPreset).Error
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	if curUser.PresetID != curPreset.ID {
+		curUser.PresetID = curPreset.ID
+		err := osusu.DB.Save(curUser).Error
+		if err != nil {
+			core.ErrorDialog(ctx, err)
+		}
+	}
+}
+
+// editOptions opens a dialog for editing the current options and switching
+// between presets, calling the given function after the dialog is closed.
+func editOptions(ctx core.Widget, saved func()) {
+	var presets []*osusu.Preset
+	err := osusu.DB.Find(&presets, "user_id = ?", curUser.ID).Error
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+
+	d := core.NewBody("Sort and filter")
+	ch := core.NewChooser(d)
+	form := core.NewForm(d).SetStruct(curOptions)
+
+	setItems := func() {
+		items := make([]core.ChooserItem, len(presets))
+		for i, preset := range presets {
+			items[i] = core.ChooserItem{Value: preset, Text: preset.Name, Func: func() {
+				if preset == curPreset {
+					return
+				}
+				savePreset(d)
+				setPreset(preset)
+				savePreset(d)
+				form.SetStruct(curOptions).Update()
+			}}
+		}
+		ch.SetItems(items...)
+		for _, preset := range presets {
+			if curPreset != nil && preset.ID == curPreset.ID {
+				ch.SetCurrentValue(preset)
+			}
+		}
+	}
+	setItems()
+
+	bar := core.NewFrame(d)
+	core.NewButton(bar).SetIcon(icons.Add).SetText("New preset").OnClick(func(e events.Event) {
+		preset := &osusu.Preset{UserID: curUser.ID, Options: *curOptions}
+		nd := core.NewBody("New preset")
+		core.NewText(nd).SetText("The new preset starts with the current options")
+		core.Bind(&preset.Name, core.NewTextField(nd).SetPlaceholder("Name"))
+		nd.AddBottomBar(func(bar *core.Frame) {
+			nd.AddCancel(bar)
+			nd.AddOK(bar).SetText("Create").OnClick(func(e events.Event) {
+				savePreset(nd)
+				err := osusu.DB.Create(preset).Error
+				if err != nil {
+					core.ErrorDialog(nd, err)
+					return
+				}
+				presets = append(presets, preset)
+				setPreset(preset)
+				savePreset(nd)
+				setItems()
+				ch.Update()
+				form.SetStruct(curOptions).Update()
+			})
+		})
+		nd.RunDialog(d)
+	})
+	core.NewButton(bar).SetIcon(icons.Group).SetText("Set as group default").SetTooltip("Use this preset as the starting preset for new members of your group").OnClick(func(e events.Event) {
+		if curGroup == nil || curPreset == nil {
+			return
+		}
+		savePreset(d)
+		curGroup.DefaultPresetID = curPreset.ID
+		err := osusu.DB.Save(curGroup).Error
+		if err != nil {
+			core.ErrorDialog(d, err)
+			return
+		}
+		core.MessageSnackbar(d, curPreset.Name+" is now the default preset for "+curGroup.Name)
+	})
+	if curGroup != nil && curGroup.OwnerID == curUser.ID {
+		sw := core.NewSwitch(bar).SetText("Share anonymized ratings").SetChecked(curGroup.ShareData)
+		sw.SetTooltip("Let Discover recommend recipes to other groups based on what your group likes, and to your group based on what other groups like")
+		sw.OnChange(func(e events.Event) {
+			curGroup.ShareData = sw.IsChecked()
+			err := osusu.DB.Save(curGroup).Error
+			if err != nil {
+				core.ErrorDialog(d, err)
+			}
+		})
+	}
+	core.NewButton(bar).SetIcon(icons.Lightbulb).SetText("Suggest importances").OnClick(func(e events.Event) {
+		suggestImportances(d, curOptions, func() {
+			form.Update()
+		})
+	})
+
+	d.OnClose(func(e events.Event) {
+		savePreset(d)
+		saved()
+	})
+	d.RunFullDialog(ctx)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// editPrices opens a dialog for editing the ingredient price table of the
+// current group, calling the given function after the prices are saved.
+func editPrices(ctx core.Widget, saved func()) {
+	var prices osusu.PriceTable
+	err := osusu.DB.Find(&prices, "group_id = ?", curGroup.ID).Error
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	oldIDs := map[uint]bool{}
+	for _, price := range prices {
+		oldIDs[price.ID] = true
+	}
+	d := core.NewBody("Ingredient prices")
+	core.NewText(d).SetText("Ingredients in recipes are matched to the items with the most words in common. The price is for the given amount of the item in the given unit, or for a count of the item if there is no unit.")
+	core.NewTable(d).SetSlice(&prices)
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddCancel(bar)
+		d.AddOK(bar).SetText("Save").OnClick(func(e events.Event) {
+			for i := range prices {
+				prices[i].GroupID = curGroup.ID
+				delete(oldIDs, prices[i].ID)
+			}
+			// any remaining old prices were removed in the table
+			for id := range oldIDs {
+				err := osusu.DB.Delete(&osusu.IngredientPrice{}, id).Error
+				if err != nil {
+					core.ErrorDialog(d, err)
+					return
+				}
+			}
+			if len(prices) > 0 {
+				err := osusu.DB.Save(&prices).Error
+				if err != nil {
+					core.ErrorDialog(d, err)
+					return
+				}
+			}
+			saved()
+		})
+	})
+	d.RunFullDialog(ctx)
+}
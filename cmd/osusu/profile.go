@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"cogentcore.org/core/icons"
+)
+
+// editProfile opens a dialog for editing the profile of the current user,
+// exporting their personal data, and deleting their account.
+func editProfile(ctx core.Widget) {
+	d := core.NewBody("Profile")
+	profile := curUser.Profile()
+	core.NewForm(d).SetStruct(profile)
+	d.AddBottomBar(func(bar *core.Frame) {
+		core.NewButton(bar).SetType(core.ButtonOutlined).SetIcon(icons.Delete).SetText("Delete account").OnClick(func(e events.Event) {
+			deleteAccount(d)
+		})
+		core.NewButton(bar).SetType(core.ButtonOutlined).SetIcon(icons.Download).SetText("Export my data").OnClick(func(e events.Event) {
+			exportPersonalData(d)
+		})
+		d.AddCancel(bar)
+		d.AddOK(bar).SetText("Save").OnClick(func(e events.Event) {
+			err := curUser.UpdateProfile(profile)
+			if err != nil {
+				core.ErrorDialog(ctx, err)
+			}
+		})
+	})
+	d.RunDialog(ctx)
+}
+
+// exportPersonalData opens a dialog for saving all of the personal data of the current user.
+func exportPersonalData(ctx core.Widget) {
+	d := core.NewBody("Export my data")
+	settings := &struct {
+		// Path is the JSON file to save the data to
+		Path core.Filename
+	}{Path: "osusu-personal-data.json"}
+	core.NewForm(d).SetStruct(settings)
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddCancel(bar)
+		d.AddOK(bar).SetIcon(icons.Download).SetText("Export").OnClick(func(e events.Event) {
+			pd, err := curUser.PersonalData()
+			if err != nil {
+				core.ErrorDialog(ctx, err, "Error exporting data")
+				return
+			}
+			err = pd.Save(string(settings.Path))
+			if err != nil {
+				core.ErrorDialog(ctx, err, "Error saving data")
+				return
+			}
+			core.MessageSnackbar(ctx, "Exported your data to "+string(settings.Path))
+		})
+	})
+	d.RunDialog(ctx)
+}
+
+// deleteAccount opens a dialog for confirming that the current user wants to
+// permanently delete their account, and then deletes it and quits the app.
+func deleteAccount(ctx core.Widget) {
+	d := core.NewBody("Delete account")
+	core.NewText(d).SetText("Are you sure you want to permanently delete your account? This can not be undone. If you own your group, it will be given to the member who joined first, or deleted if you are the only member.")
+	anonymize := core.NewSwitch(d).SetText("Keep my ratings anonymously in my group").SetChecked(true)
+	d.AddBottomBar(func(bar *core.Frame) {
+		d.AddCancel(bar)
+		d.AddOK(bar).SetIcon(icons.Delete).SetText("Delete").OnClick(func(e events.Event) {
+			err := curUser.DeleteAccount(anonymize.IsChecked())
+			if err != nil {
+				core.ErrorDialog(ctx, err)
+				return
+			}
+			// the saved token would otherwise sign the user back in to a new account
+			os.Remove(filepath.Join(core.TheApp.AppDataDir(), "google-token.json"))
+			core.TheApp.Quit()
+		})
+	})
+	d.RunDialog(ctx)
+}
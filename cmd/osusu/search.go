@@ -12,6 +12,9 @@ import (
 	"github.com/kkoreilly/osusu/osusu"
 )
 
+// searchScores are the scores of the meals currently shown in search, keyed by meal ID.
+var searchScores map[uint]*osusu.Score
+
 func configSearch(mf *core.Frame) {
 	// TODO: use Makers and Plans
 	if mf.HasChildren() {
@@ -22,30 +25,30 @@ func configSearch(mf *core.Frame) {
 		s.Wrap = true
 	})
 
+	// meals without a group are not shown to users without a group
 	var meals []*osusu.Meal
-	err := osusu.DB.Find(&meals).Error
+	if curGroup.ID != 0 {
+		err := osusu.DB.Find(&meals, "group_id = ?", curGroup.ID).Error
+		if err != nil {
+			core.ErrorDialog(mf, err)
+		}
+	}
+	prior, err := loadPrior()
 	if err != nil {
 		core.ErrorDialog(mf, err)
 	}
+	searchScores = map[uint]*osusu.Score{}
 	for _, meal := range meals {
 		meal := meal
 
-		if !bitFlagsOverlap(&meal.Category, &curOptions.Categories) ||
-			!bitFlagsOverlap(&meal.Source, &curOptions.Sources) ||
-			!bitFlagsOverlap(&meal.Cuisine, &curOptions.Cuisines) {
+		if !curOptions.IncludesMeal(meal) {
 			continue
 		}
 
 		mc := core.NewFrame(mf)
 		cardStyles(mc)
 
-		img := core.NewImage(mc)
-		go func() {
-			if i := getImageFromURL(meal.Image); i != nil {
-				img.SetImage(i)
-				img.Update()
-			}
-		}()
+		cardImage(mc, meal.Image)
 
 		core.NewText(mc).SetType(core.TextHeadlineSmall).SetText(meal.Name)
 
@@ -66,9 +69,10 @@ func configSearch(mf *core.Frame) {
 			core.ErrorDialog(mc, err)
 		}
 
-		score := meal.Score(entries)
-		score.ComputeTotal(curOptions)
-		scoreGrid(mc, score, true)
+		score := meal.Score(entries, prior, curOptions, curDimensions, time.Now())
+		score.ComputeTotal(curOptions, curDimensions)
+		searchScores[meal.ID] = score
+		scoreGrid(mc, score, true, true)
 
 		mc.OnClick(func(e events.Event) {
 			core.NewMenu(func(m *core.Scene) {
@@ -81,6 +85,16 @@ func configSearch(mf *core.Frame) {
 				core.NewButton(m).SetIcon(icons.Edit).SetText("Edit meal").OnClick(func(e events.Event) {
 					editMeal(mf, meal, mc)
 				})
+				core.NewButton(m).SetIcon(icons.History).SetText("Edit history").OnClick(func(e events.Event) {
+					viewMealHistory(mc, meal, func() {
+						configSearch(mf)
+					})
+				})
+				core.NewButton(m).SetIcon(icons.Delete).SetText("Delete meal").OnClick(func(e events.Event) {
+					deleteMeal(mf, meal, func() {
+						configSearch(mf)
+					})
+				})
 			}, mc, mc.ContextMenuPos(e)).Run()
 		})
 	}
@@ -97,6 +111,15 @@ func newEntry(meal *osusu.Meal, mc *core.Frame) {
 		Effort:      50,
 		Healthiness: 50,
 		Taste:       50,
+		Servings:    1,
+	}
+	for _, d := range curDimensions {
+		if !d.IsBuiltin() {
+			if entry.Ratings == nil {
+				entry.Ratings = map[string]int{}
+			}
+			entry.Ratings[d.Name] = d.DefaultRating()
+		}
 	}
 	core.NewForm(d).SetStruct(entry)
 	d.AddBottomBar(func(bar *core.Frame) {
@@ -105,7 +128,13 @@ func newEntry(meal *osusu.Meal, mc *core.Frame) {
 			err := osusu.DB.Create(entry).Error
 			if err != nil {
 				core.ErrorDialog(d, err)
+				return
+			}
+			all := []*osusu.Score{}
+			for _, score := range searchScores {
+				all = append(all, score)
 			}
+			recordChoice(osusu.EntryChoice, searchScores[meal.ID], all)
 		})
 	})
 	d.RunFullDialog(mc)
@@ -140,17 +169,21 @@ func viewEntries(meal *osusu.Meal, entries []osusu.Entry, mc *core.Frame) {
 			s.Color = colors.Scheme.OnSurfaceVariant
 		})
 
-		score := entry.Score()
-		score.ComputeTotal(curOptions)
-		scoreGrid(ec, score, false)
+		score := entry.Score(curDimensions)
+		score.ComputeTotal(curOptions, curDimensions)
+		scoreGrid(ec, score, false, false)
 
 		ec.OnClick(func(e events.Event) {
 			d := core.NewBody("Edit entry")
 			core.NewForm(d).SetStruct(entry)
 			d.AddBottomBar(func(bar *core.Frame) {
+				core.NewButton(bar).SetType(core.ButtonOutlined).SetIcon(icons.Delete).SetText("Delete").OnClick(func(e events.Event) {
+					d.Close()
+					deleteEntry(mc, entry, func() {})
+				})
 				d.AddCancel(bar)
 				d.AddOK(bar).SetText("Save").OnClick(func(e events.Event) {
-					err := osusu.DB.Save(entry).Error
+					err := saveWithUndo(mc, entry, entry.ID, "Saved the entry", func() {})
 					if err != nil {
 						core.ErrorDialog(d, err)
 					}
@@ -166,14 +199,42 @@ func editMeal(mf *core.Frame, meal *osusu.Meal, mc *core.Frame) {
 	d := core.NewBody("Edit meal")
 	core.NewForm(d).SetStruct(meal)
 	d.AddBottomBar(func(bar *core.Frame) {
+		core.NewButton(bar).SetType(core.ButtonOutlined).SetIcon(icons.Delete).SetText("Delete").OnClick(func(e events.Event) {
+			d.Close()
+			deleteMeal(mf, meal, func() {
+				configSearch(mf)
+			})
+		})
 		d.AddCancel(bar)
 		d.AddOK(bar).SetText("Save").OnClick(func(e events.Event) {
-			err := osusu.DB.Save(meal).Error
+			err := saveWithUndo(mf, meal, meal.ID, "Saved "+meal.Name, func() {
+				configSearch(mf)
+			})
 			if err != nil {
 				core.ErrorDialog(d, err)
 			}
-			configSearch(mf)
 		})
 	})
 	d.RunFullDialog(mc)
 }
+
+// loadPrior returns the prior score of the current user
+// based on all of the entries for the meals of the current group,
+// or nil if the current user is not in a group yet.
+func loadPrior() (*osusu.Score, error) {
+	if curGroup == nil || curGroup.ID == 0 {
+		return nil, nil
+	}
+	groupEntries := []osusu.Entry{}
+	err := osusu.DB.Find(&groupEntries, "meal_id IN (?)", osusu.DB.Model(&osusu.Meal{}).Select("id").Where("group_id = ?", curGroup.ID)).Error
+	if err != nil {
+		return nil, err
+	}
+	userEntries := []osusu.Entry{}
+	for _, entry := range groupEntries {
+		if entry.UserID == curUser.ID {
+			userEntries = append(userEntries, entry)
+		}
+	}
+	return osusu.ComputePrior(userEntries, groupEntries, curOptions, curDimensions, time.Now()), nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+
+	"cogentcore.org/core/colors"
+	"cogentcore.org/core/core"
+	"cogentcore.org/core/events"
+	"cogentcore.org/core/icons"
+	"cogentcore.org/core/styles"
+	"github.com/kkoreilly/osusu/osusu"
+)
+
+// undoSnackbar shows a snackbar with the given message and an undo button,
+// which calls the given undo function and then the given changed function.
+func undoSnackbar(ctx core.Widget, message string, undo func() error, changed func()) {
+	core.NewBody().AddSnackbarText(message).AddSnackbarButton("Undo", func(e events.Event) {
+		err := undo()
+		if err != nil {
+			core.ErrorDialog(ctx, err, "Error undoing")
+			return
+		}
+		changed()
+	}).RunSnackbar(ctx)
+}
+
+// saveWithUndo saves the given edited record with the given ID and shows
+// a snackbar that can undo the edit by saving the previous version of it.
+// It calls the given function after the record is saved and after it is undone.
+func saveWithUndo[T any](ctx core.Widget, record *T, id uint, message string, changed func()) error {
+	old := new(T)
+	err := osusu.DB.First(old, id).Error
+	if err != nil {
+		return err
+	}
+	err = osusu.DB.Save(record).Error
+	if err != nil {
+		return err
+	}
+	changed()
+	undoSnackbar(ctx, message, func() error {
+		return osusu.DB.Save(old).Error
+	}, changed)
+	return nil
+}
+
+// deleteMeal moves the given meal to the trash and shows a snackbar that can undo it.
+func deleteMeal(ctx core.Widget, meal *osusu.Meal, changed func()) {
+	err := meal.Delete(actorCtx)
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	changed()
+	undoSnackbar(ctx, "Moved "+meal.Name+" to the trash", func() error {
+		return meal.Restore(actorCtx)
+	}, changed)
+}
+
+// deleteEntry moves the given entry to the trash and shows a snackbar that can undo it.
+func deleteEntry(ctx core.Widget, entry *osusu.Entry, changed func()) {
+	err := entry.Delete(actorCtx)
+	if err != nil {
+		core.ErrorDialog(ctx, err)
+		return
+	}
+	changed()
+	undoSnackbar(ctx, "Moved the entry to the trash", func() error {
+		return entry.Restore(actorCtx)
+	}, changed)
+}
+
+// viewTrash opens a dialog for restoring and permanently deleting the meals
+// and entries in the trash, calling the given function after any are restored.
+func viewTrash(ctx core.Widget, restored func()) {
+	d := core.NewBody("Trash")
+	list := core.NewFrame(d)
+	list.Styler(func(s *styles.Style) {
+		s.Wrap = true
+	})
+	var trash *osusu.Trash
+	var configTrash func()
+	configTrash = func() {
+		list.DeleteChildren()
+		var err error
+		trash, err = osusu.LoadTrash(curUser.ID, curGroup.ID)
+		if err != nil {
+			core.ErrorDialog(d, err)
+			return
+		}
+		if len(trash.Meals) == 0 && len(trash.Entries) == 0 {
+			core.NewText(list).SetText("The trash is empty")
+		}
+		item := func(title, subtitle string, restore, purge func(ctx context.Context) error) {
+			c := core.NewFrame(list)
+			cardStyles(c)
+			core.NewText(c).SetType(core.TextHeadlineSmall).SetText(title)
+			core.NewText(c).SetText(subtitle).Styler(func(s *styles.Style) {
+				s.Color = colors.Scheme.OnSurfaceVariant
+			})
+			buttons := core.NewFrame(c)
+			core.NewButton(buttons).SetType(core.ButtonTonal).SetIcon(icons.RestoreFromTrash).SetText("Restore").OnClick(func(e events.Event) {
+				err := restore(actorCtx)
+				if err != nil {
+					core.ErrorDialog(d, err)
+					return
+				}
+				configTrash()
+				restored()
+			})
+			core.NewButton(buttons).SetType(core.ButtonOutlined).SetIcon(icons.DeleteForever).SetText("Delete forever").OnClick(func(e events.Event) {
+				err := purge(actorCtx)
+				if err != nil {
+					core.ErrorDialog(d, err)
+					return
+				}
+				configTrash()
+			})
+		}
+		for _, meal := range trash.Meals {
+			item(meal.Name, "Meal deleted "+meal.DeletedAt.Time.Format("January 2, 2006"), meal.Restore, meal.Purge)
+		}
+		for _, entry := range trash.Entries {
+			item(entry.Meal.Name, "Entry from "+entry.Time.Format("January 2, 2006")+" deleted "+entry.DeletedAt.Time.Format("January 2, 2006"), entry.Restore, entry.Purge)
+		}
+		list.Update()
+	}
+	configTrash()
+	d.AddBottomBar(func(bar *core.Frame) {
+		core.NewButton(bar).SetType(core.ButtonOutlined).SetIcon(icons.DeleteForever).SetText("Empty trash").OnClick(func(e events.Event) {
+			err := trash.Empty(actorCtx)
+			if err != nil {
+				core.ErrorDialog(d, err)
+			} else if len(trash.Meals) > 0 {
+				core.MessageSnackbar(d, "Meals with entries of other members can only be deleted forever by their creator or the group owner")
+			}
+			configTrash()
+		})
+		d.AddOK(bar).SetText("Done")
+	})
+	d.RunFullDialog(ctx)
+}
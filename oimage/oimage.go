@@ -0,0 +1,326 @@
+// Package oimage provides Osusu image loading, with an on-disk cache,
+// thumbnails, and placeholders for images that are not loaded yet.
+package oimage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cogentcore.org/core/base/iox/imagex"
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache loads images from URLs and caches them and their thumbnails
+// on disk. Its fields should not be changed after it is first used.
+type Cache struct {
+	// Dir is the directory that images are cached in
+	Dir string
+	// MaxBytes is the maximum total size of the cached files, after which the
+	// least recently used ones are deleted
+	MaxBytes int64
+	// MaxDownloadBytes is the maximum size of an image that is downloaded
+	MaxDownloadBytes int64
+	// MaxPixels is the maximum number of pixels in an image that is decoded,
+	// which stops small files from using a lot of memory when decoded
+	MaxPixels int
+	// ThumbnailSize is the maximum width and height of thumbnails
+	ThumbnailSize int
+	// MaxConcurrent is the maximum number of images that are downloaded
+	// and decoded at the same time
+	MaxConcurrent int
+	// Client is the HTTP client used to download images
+	Client *http.Client
+
+	// sem limits the number of images loaded at the same time
+	sem     chan struct{}
+	semOnce sync.Once
+	// group makes loads of the same image at the same time share one load
+	group singleflight.Group
+	// evictMu is held while deleting the least recently used files
+	evictMu sync.Mutex
+}
+
+// NewCache returns a new [Cache] in the given directory with the default limits.
+func NewCache(dir string) *Cache {
+	return &Cache{
+		Dir:              dir,
+		MaxBytes:         200 << 20,
+		MaxDownloadBytes: 10 << 20,
+		MaxPixels:        50_000_000,
+		ThumbnailSize:    480,
+		MaxConcurrent:    4,
+		Client:           &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+// ErrTooLarge is returned for images larger than the limits of the [Cache].
+var ErrTooLarge = errors.New("image is too large")
+
+// key returns the name of the cache file for the image at the given URL with
+// the given suffix.
+func key(url, suffix string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:]) + suffix
+}
+
+// Image returns the image at the given URL, downloading it if it is not cached.
+func (c *Cache) Image(ctx context.Context, url string) (image.Image, error) {
+	b, err := c.original(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return c.decode(b)
+}
+
+// Thumbnail returns a thumbnail of the image at the given URL that is at most
+// [Cache.ThumbnailSize] wide and tall, which is faster to load and display for
+// cards. The thumbnail is generated and cached the first time it is loaded.
+func (c *Cache) Thumbnail(ctx context.Context, url string) (image.Image, error) {
+	path := filepath.Join(c.Dir, key(url, "-"+strconv.Itoa(c.ThumbnailSize)+".jpg"))
+	v, err, _ := c.group.Do(path, func() (any, error) {
+		if img, err := c.open(path); err == nil {
+			return img, nil
+		}
+		b, err := c.original(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		c.acquire()
+		defer c.release()
+		img, err := c.decode(b)
+		if err != nil {
+			return nil, err
+		}
+		thumb := Resize(img, c.ThumbnailSize)
+		var buf bytes.Buffer
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		if err != nil {
+			return nil, err
+		}
+		return thumb, c.write(path, buf.Bytes())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(image.Image), nil
+}
+
+// original returns the bytes of the original image at the given URL,
+// downloading it if it is not cached.
+func (c *Cache) original(ctx context.Context, url string) ([]byte, error) {
+	if url == "" {
+		return nil, errors.New("no image URL")
+	}
+	path := filepath.Join(c.Dir, key(url, ".orig"))
+	v, err, _ := c.group.Do(path, func() (any, error) {
+		if b, err := os.ReadFile(path); err == nil {
+			c.touch(path)
+			return b, nil
+		}
+		c.acquire()
+		defer c.release()
+		b, err := c.download(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		// only valid images are cached
+		_, err = c.decode(b)
+		if err != nil {
+			return nil, err
+		}
+		return b, c.write(path, b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// download downloads the image at the given URL.
+func (c *Cache) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading image %q: %s", url, resp.Status)
+	}
+	if resp.ContentLength > c.MaxDownloadBytes {
+		return nil, fmt.Errorf("%w: %q is %d bytes", ErrTooLarge, url, resp.ContentLength)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, c.MaxDownloadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > c.MaxDownloadBytes {
+		return nil, fmt.Errorf("%w: %q is more than %d bytes", ErrTooLarge, url, c.MaxDownloadBytes)
+	}
+	return b, nil
+}
+
+// decode decodes the given image, checking its size first.
+func (c *Cache) decode(b []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > c.MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	img, _, err := imagex.Read(bytes.NewReader(b))
+	return img, err
+}
+
+// open opens the cached image file at the given path.
+func (c *Cache) open(path string) (image.Image, error) {
+	img, _, err := imagex.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	c.touch(path)
+	return img, nil
+}
+
+// touch marks the cached file at the given path as recently used.
+func (c *Cache) touch(path string) {
+	now := time.Now()
+	os.Chtimes(path, now, now)
+}
+
+// write atomically writes the given cached file and then evicts
+// files if the cache is too large.
+func (c *Cache) write(path string, b []byte) error {
+	err := os.MkdirAll(c.Dir, 0750)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.Dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return c.Evict()
+}
+
+// Evict deletes the least recently used cached files until the total size
+// of the cache is at most [Cache.MaxBytes]. It is called automatically
+// after files are added to the cache.
+func (c *Cache) Evict() error {
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return err
+	}
+	var infos []fs.FileInfo
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), "tmp-") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+		total += info.Size()
+	}
+	if total <= c.MaxBytes {
+		return nil
+	}
+	slices.SortFunc(infos, func(a, b fs.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+	for _, info := range infos {
+		if total <= c.MaxBytes {
+			break
+		}
+		err := os.Remove(filepath.Join(c.Dir, info.Name()))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= info.Size()
+	}
+	return nil
+}
+
+// Clear deletes all of the cached files.
+func (c *Cache) Clear() error {
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+	return os.RemoveAll(c.Dir)
+}
+
+// acquire waits until fewer than [Cache.MaxConcurrent] images are being loaded.
+func (c *Cache) acquire() {
+	c.semOnce.Do(func() {
+		c.sem = make(chan struct{}, max(c.MaxConcurrent, 1))
+	})
+	c.sem <- struct{}{}
+}
+
+// release marks an image as no longer being loaded after [Cache.acquire].
+func (c *Cache) release() {
+	<-c.sem
+}
+
+// Resize returns the given image scaled down to be at most the given size
+// wide and tall, keeping its aspect ratio. Images that are already small
+// enough are returned as is. Transparent parts of the image are white.
+func Resize(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w > h {
+		w, h = size, max(h*size/w, 1)
+	} else {
+		w, h = max(w*size/h, 1), size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+// Placeholder returns an image of the given size and color to show
+// in the place of an image before it is loaded or if it can not be.
+func Placeholder(size int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
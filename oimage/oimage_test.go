@@ -0,0 +1,271 @@
+package oimage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPNG returns a PNG image with the given size.
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := range w {
+		for y := range h {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x * y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testServer returns a test server that serves the given images by path,
+// and a count of the requests to it.
+func testServer(t *testing.T, images map[string][]byte) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		b, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+// testCache returns a new cache in a temporary directory that uses the given test server.
+func testCache(t *testing.T, ts *httptest.Server) *Cache {
+	t.Helper()
+	c := NewCache(t.TempDir())
+	c.Client = ts.Client()
+	return c
+}
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		w, h, size int
+		// wantW and wantH are the expected size of the resized image
+		wantW, wantH int
+	}{
+		{1000, 500, 480, 480, 240},
+		{500, 1000, 480, 240, 480},
+		{600, 600, 480, 480, 480},
+		{100, 50, 480, 100, 50},
+		{2000, 1, 480, 480, 1},
+	}
+	for _, test := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, test.w, test.h))
+		res := Resize(img, test.size)
+		if b := res.Bounds(); b.Dx() != test.wantW || b.Dy() != test.wantH {
+			t.Errorf("resizing %dx%d to %d: expected %dx%d but got %dx%d", test.w, test.h, test.size, test.wantW, test.wantH, b.Dx(), b.Dy())
+		}
+		if test.w <= test.size && test.h <= test.size && res != image.Image(img) {
+			t.Errorf("expected the %dx%d image to be returned as is", test.w, test.h)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	ts, requests := testServer(t, map[string][]byte{"/a.png": testPNG(t, 200, 100)})
+	c := testCache(t, ts)
+	c.ThumbnailSize = 50
+	for range 2 {
+		thumb, err := c.Thumbnail(context.Background(), ts.URL+"/a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := thumb.Bounds(); b.Dx() != 50 || b.Dy() != 25 {
+			t.Errorf("expected a 50x25 thumbnail but got %dx%d", b.Dx(), b.Dy())
+		}
+	}
+	// the original and the thumbnail are cached
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected 1 request but got %d", n)
+	}
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 cached files but got %d", len(entries))
+	}
+}
+
+func TestTooLarge(t *testing.T) {
+	small, large := testPNG(t, 10, 10), testPNG(t, 100, 100)
+	ts, _ := testServer(t, map[string][]byte{"/small.png": small, "/large.png": large})
+	// chunked responses do not have a content length, so only the read is limited
+	chunked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(large[:len(large)/2])
+		w.(http.Flusher).Flush()
+		w.Write(large[len(large)/2:])
+	}))
+	t.Cleanup(chunked.Close)
+
+	tests := []struct {
+		name             string
+		url              string
+		maxDownloadBytes int64
+		maxPixels        int
+		tooLarge         bool
+	}{
+		{"within limits", ts.URL + "/small.png", int64(len(small)), 100, false},
+		{"content length", ts.URL + "/large.png", int64(len(large)) - 1, 1_000_000, true},
+		{"chunked", chunked.URL, int64(len(large)) - 1, 1_000_000, true},
+		{"pixels", ts.URL + "/large.png", int64(len(large)), 100*100 - 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := testCache(t, ts)
+			c.MaxDownloadBytes, c.MaxPixels = test.maxDownloadBytes, test.maxPixels
+			_, err := c.Image(context.Background(), test.url)
+			if errors.Is(err, ErrTooLarge) != test.tooLarge {
+				t.Fatalf("expected too large %v but got %v", test.tooLarge, err)
+			}
+			// images that are too large are not cached
+			entries, err := os.ReadDir(c.Dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := map[bool]int{true: 0, false: 1}[test.tooLarge]; len(entries) != want {
+				t.Errorf("expected %d cached files but got %d", want, len(entries))
+			}
+		})
+	}
+}
+
+func TestEvict(t *testing.T) {
+	c := NewCache(t.TempDir())
+	c.MaxBytes = 250
+	now := time.Now()
+	// c is the least recently used, then a, then b; tmp files are not counted
+	files := []struct {
+		name string
+		age  time.Duration
+	}{
+		{"a", 2 * time.Hour},
+		{"b", time.Hour},
+		{"c", 3 * time.Hour},
+		{"tmp-1", 4 * time.Hour},
+	}
+	for _, f := range files {
+		path := filepath.Join(c.Dir, f.name)
+		err := os.WriteFile(path, make([]byte, 100), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(path, now.Add(-f.age), now.Add(-f.age))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := c.Evict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(c.Dir, f.name))
+		if exists, want := err == nil, f.name != "c"; exists != want {
+			t.Errorf("expected %s to exist %v but got %v", f.name, want, exists)
+		}
+	}
+
+	// touching a file makes it recently used, so the other one is evicted
+	c.MaxBytes = 150
+	c.touch(filepath.Join(c.Dir, "a"))
+	err = c.Evict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(c.Dir, "a")); err != nil {
+		t.Errorf("expected the recently used file to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(c.Dir, "b")); err == nil {
+		t.Error("expected the least recently used file to be evicted")
+	}
+}
+
+func TestEvictLoads(t *testing.T) {
+	images := map[string][]byte{}
+	for _, name := range []string{"/a.png", "/b.png", "/c.png"} {
+		images[name] = testPNG(t, 20, 20)
+	}
+	ts, requests := testServer(t, images)
+	c := testCache(t, ts)
+	// only two of the images fit in the cache
+	c.MaxBytes = int64(len(images["/a.png"]))*2 + 1
+	for _, name := range []string{"/a.png", "/b.png", "/c.png", "/c.png"} {
+		_, err := c.Image(context.Background(), ts.URL+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests but got %d", n)
+	}
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 cached files but got %d", len(entries))
+	}
+}
+
+func TestSingleflight(t *testing.T) {
+	b := testPNG(t, 20, 20)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Write(b)
+	}))
+	t.Cleanup(ts.Close)
+	c := testCache(t, ts)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = c.Image(context.Background(), ts.URL+"/a.png")
+		}()
+	}
+	// the other loads wait for the first download instead of downloading the image again
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected 1 request but got %d", n)
+	}
+}
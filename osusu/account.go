@@ -0,0 +1,183 @@
+package osusu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+)
+
+// Profile is the information about a user that they can edit.
+type Profile struct {
+	// Name is the name shown to the other members of the group
+	Name string
+	// Locale is the language tag of the preferred language and region of the user (eg: en-US)
+	Locale string
+	// Picture is the URL of the profile picture of the user
+	Picture string
+}
+
+// Profile returns the profile of the user.
+func (u *User) Profile() *Profile {
+	return &Profile{Name: u.Name, Locale: u.Locale, Picture: u.Picture}
+}
+
+// Validate returns an error if the profile is not valid,
+// and otherwise normalizes the locale.
+func (p *Profile) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if len(p.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	if p.Locale != "" {
+		tag, err := language.Parse(p.Locale)
+		if err != nil {
+			return fmt.Errorf("invalid locale %q: %w", p.Locale, err)
+		}
+		p.Locale = tag.String()
+	}
+	if p.Picture != "" {
+		u, err := url.Parse(p.Picture)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid picture URL %q", p.Picture)
+		}
+	}
+	return nil
+}
+
+// UpdateProfile validates the given profile and saves it as the profile of the user.
+func (u *User) UpdateProfile(p *Profile) error {
+	err := p.Validate()
+	if err != nil {
+		return err
+	}
+	u.Name, u.Locale, u.Picture = p.Name, p.Locale, p.Picture
+	return DB.Model(u).Select("name", "locale", "picture").Updates(u).Error
+}
+
+// DeleteAccount permanently deletes the user and their sessions, identities,
+// presets, choices, dismissals, and the audit log of their entries, and removes
+// them as the actor from the rest of the audit log. If anonymize is true, their entries are
+// kept without the user so that they still count in the scores of the group,
+// and otherwise they are deleted. If the user owns their group, ownership is
+// transferred to the member who joined first, and if there are no other members,
+// the group and all of its data are deleted.
+func (u *User) DeleteAccount(anonymize bool) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// the session makes every query start from the unscoped database instead of
+		// adding to the same statement, which would put subqueries inside themselves
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		err := tx.Delete(&AuditRecord{}, "record_table = ? AND record_id IN (?)", "entries", tx.Model(&Entry{}).Select("id").Where("user_id = ?", u.ID)).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&AuditRecord{}).Where("actor_id = ?", u.ID).Update("actor_id", 0).Error
+		if err != nil {
+			return err
+		}
+		if anonymize {
+			err := tx.Model(&Entry{}).Where("user_id = ?", u.ID).Update("user_id", 0).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			err := tx.Delete(&Entry{}, "user_id = ?", u.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, model := range []any{&Session{}, &Identity{}, &Preset{}, &Choice{}, &Dismissal{}} {
+			err := tx.Delete(model, "user_id = ?", u.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		group := &Group{}
+		err = tx.First(group, u.GroupID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && group.OwnerID == u.ID {
+			next := &User{}
+			err := tx.Where("group_id = ? AND id <> ?", group.ID, u.ID).Order("created_at").First(next).Error
+			switch {
+			case err == nil:
+				err = tx.Model(group).Update("owner_id", next.ID).Error
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				err = deleteGroupData(tx, group.ID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Delete(u).Error
+	})
+}
+
+// deleteGroupData permanently deletes the group with the given ID and all of its data.
+func deleteGroupData(tx *gorm.DB, groupID uint) error {
+	meals := tx.Model(&Meal{}).Select("id").Where("group_id = ?", groupID)
+	err := tx.Delete(&Entry{}, "meal_id IN (?)", meals).Error
+	if err != nil {
+		return err
+	}
+	for _, model := range []any{&Meal{}, &IngredientPrice{}, &Dimension{}, &Dismissal{}, &AuditRecord{}} {
+		err := tx.Delete(model, "group_id = ?", groupID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&Group{}, groupID).Error
+}
+
+// PersonalData is all of the personal data of a user, for privacy requests.
+type PersonalData struct {
+	Time       time.Time
+	User       *User
+	Identities []*Identity
+	// Sessions are the sessions of the user, without their tokens
+	Sessions   []*Session
+	Presets    []*Preset
+	Entries    []*Entry
+	Choices    []*Choice
+	Dismissals []*Dismissal
+	// Meals are the meals that the user has entries for
+	Meals []*Meal
+	// AuditRecords are the changes that the user has made
+	AuditRecords []*AuditRecord
+}
+
+// PersonalData returns all of the personal data of the user.
+func (u *User) PersonalData() (*PersonalData, error) {
+	pd := &PersonalData{Time: time.Now(), User: u}
+	err := errors.Join(
+		DB.Find(&pd.Identities, "user_id = ?", u.ID).Error,
+		DB.Omit("token").Find(&pd.Sessions, "user_id = ?", u.ID).Error,
+		DB.Find(&pd.Presets, "user_id = ?", u.ID).Error,
+		DB.Find(&pd.Entries, "user_id = ?", u.ID).Error,
+		DB.Find(&pd.Choices, "user_id = ?", u.ID).Error,
+		DB.Find(&pd.Dismissals, "user_id = ?", u.ID).Error,
+		DB.Find(&pd.Meals, "id IN (?)", DB.Model(&Entry{}).Select("meal_id").Where("user_id = ?", u.ID)).Error,
+		DB.Find(&pd.AuditRecords, "actor_id = ?", u.ID).Error,
+	)
+	return pd, err
+}
+
+// Save saves the personal data as JSON to the given path.
+func (pd *PersonalData) Save(path string) error {
+	b, err := json.MarshalIndent(pd, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0666)
+}
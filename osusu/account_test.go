@@ -0,0 +1,120 @@
+package osusu
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	tests := []struct {
+		anonymize bool
+		// entries is the expected number of entries left in the family group
+		entries int64
+	}{
+		{true, 3},
+		{false, 1},
+	}
+	for _, test := range tests {
+		name := "delete"
+		if test.anonymize {
+			name = "anonymize"
+		}
+		t.Run(name, func(t *testing.T) {
+			testDB(t)
+			td := testSeed(t)
+			err := DB.Model(td.family).Update("owner_id", td.a.ID).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			session, err := NewSession(td.a.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testCreate(t,
+				&Identity{UserID: td.a.ID, Issuer: GoogleIssuer, Subject: "a"},
+				&Identity{UserID: td.b.ID, Issuer: GoogleIssuer, Subject: "b"},
+			)
+			// changes made by the user are kept without them as the actor
+			err = DB.WithContext(WithActor(context.Background(), td.a.ID)).Model(td.tacos).Update("name", "Changed").Error
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = td.a.DeleteAccount(test.anonymize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			left := []struct {
+				model  any
+				column string
+			}{{&User{}, "id"}, {&Session{}, "user_id"}, {&Identity{}, "user_id"}, {&Entry{}, "user_id"}}
+			for _, l := range left {
+				var n int64
+				err := DB.Unscoped().Model(l.model).Where(l.column+" = ?", td.a.ID).Count(&n).Error
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != 0 {
+					t.Errorf("expected no %T records of the user to be left but got %d", l.model, n)
+				}
+			}
+			if _, err := LoadSession(session.Token); err != ErrInvalidSession {
+				t.Errorf("expected the session to be invalid but got %v", err)
+			}
+			var n int64
+			err = DB.Model(&AuditRecord{}).Where("actor_id = ?", td.a.ID).Count(&n).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 0 {
+				t.Errorf("expected no audit records with the user as the actor but got %d", n)
+			}
+
+			if n := testCount(t, &Entry{}, td.family.ID); n != test.entries {
+				t.Errorf("expected %d entries to be left but got %d", test.entries, n)
+			}
+			// the other members keep their accounts and get the group
+			err = DB.Model(&Identity{}).Count(&n).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("expected the identity of the other member to be left but got %d identities", n)
+			}
+			group := &Group{}
+			err = DB.First(group, td.family.ID).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if group.OwnerID != td.b.ID {
+				t.Errorf("expected the group to be owned by the other member %d but got %d", td.b.ID, group.OwnerID)
+			}
+		})
+	}
+}
+
+func TestDeleteAccountLastMember(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	err := DB.Model(td.friends).Update("owner_id", td.c.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the group is deleted with all of its data when its last member leaves
+	err = td.c.DeleteAccount(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []any{&Meal{}, &Entry{}, &User{}} {
+		if n := testCount(t, model, td.friends.ID); n != 0 {
+			t.Errorf("expected no %T records of the deleted group but got %d", model, n)
+		}
+	}
+	err = DB.First(&Group{}, td.friends.ID).Error
+	if err == nil {
+		t.Error("expected the group to be deleted")
+	}
+	if n := testCount(t, &Meal{}, td.family.ID); n != 2 {
+		t.Errorf("expected the other group to still have 2 meals but got %d", n)
+	}
+}
@@ -0,0 +1,327 @@
+package osusu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditActions are the kinds of changes recorded in the audit log.
+type AuditActions int32 //enums:enum
+
+const (
+	AuditCreate  AuditActions = iota // Created
+	AuditUpdate                      // Updated
+	AuditDelete                      // Deleted
+	AuditRestore                     // Restored
+)
+
+// AuditRecord is a record in the audit log of a change to a meal, entry, or group,
+// which is recorded by the hooks of those models. Changes made without an ID in
+// the model, like deleting all of the entries of a meal at once, are not recorded.
+type AuditRecord struct {
+	gorm.Model `display:"-"`
+	// GroupID is the group that the changed record is in
+	GroupID uint `display:"-" gorm:"index"`
+	// MealID is the meal that the changed record is or is for, or 0 for groups
+	MealID uint `display:"-" gorm:"index"`
+	// ActorID is the user who made the change (see [WithActor]), or 0 if unknown
+	ActorID uint `display:"-"`
+	Actor   User `display:"-" json:"-"`
+	Time    time.Time
+	// RecordTable is the name of the table of the changed record
+	RecordTable string
+	RecordID    uint
+	Action      AuditActions
+	// Changes are the changed fields of the record, which are empty for creates and deletes
+	Changes []AuditChange `gorm:"serializer:json"`
+	// Data is the JSON of the record after the change, or before it for deletes,
+	// which can be used to revert the record to this version
+	Data json.RawMessage `display:"-" gorm:"serializer:json"`
+}
+
+// AuditChange is a change to a field of a record in an [AuditRecord].
+type AuditChange struct {
+	Field string
+	// Old and New are the JSON values of the field before and after the change
+	Old, New json.RawMessage
+}
+
+func (c AuditChange) String() string {
+	return fmt.Sprintf("%s: %s → %s", c.Field, c.Old, c.New)
+}
+
+// actorKey is the context key for the actor of changes.
+type actorKey struct{}
+
+// WithActor returns a copy of the given context with the given user ID as the
+// actor of changes made with it, which is recorded in the audit log for changes
+// made with a database with the context (see [gorm.DB.WithContext]).
+func WithActor(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFrom returns the actor in the given context (see [WithActor]), or 0 if there is none.
+func ActorFrom(ctx context.Context) uint {
+	id, _ := ctx.Value(actorKey{}).(uint)
+	return id
+}
+
+// auditOldKey returns the key in the settings of the statement of a change
+// for the version of the record with the given ID before the change. The settings
+// are used directly because hooks are called with a new session, in which
+// [gorm.DB.InstanceSet] would store the value in a new statement.
+func auditOldKey(id uint) string {
+	return fmt.Sprintf("osusu:audit_old:%d", id)
+}
+
+// unauditedFields are the fields of a record that are not compared in audit changes.
+var unauditedFields = []string{"ID", "CreatedAt", "UpdatedAt", "DeletedAt", "DeletionID"}
+
+// auditLoad returns the version of the given record with the given ID
+// that is currently in the database, or nil if there is none.
+func auditLoad(tx *gorm.DB, record any, id uint) (any, error) {
+	current := reflect.New(reflect.TypeOf(record).Elem()).Interface()
+	err := tx.Session(&gorm.Session{NewDB: true}).Unscoped().First(current, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return current, err
+}
+
+// auditBefore saves the version of the given record with the given ID
+// before a change so that it can be compared in [auditAfter].
+func auditBefore(tx *gorm.DB, record any, id uint) error {
+	if id == 0 {
+		return nil
+	}
+	old, err := auditLoad(tx, record, id)
+	if err != nil || old == nil {
+		return err
+	}
+	tx.Statement.Settings.Store(auditOldKey(id), old)
+	return nil
+}
+
+// auditAfter records the change with the given action to the given record
+// with the given ID in the given group and meal in the audit log.
+func auditAfter(tx *gorm.DB, action AuditActions, record any, id, groupID, mealID uint) error {
+	if id == 0 {
+		return nil
+	}
+	ar := &AuditRecord{
+		GroupID:     groupID,
+		MealID:      mealID,
+		ActorID:     ActorFrom(tx.Statement.Context),
+		Time:        time.Now(),
+		RecordTable: tx.Statement.Table,
+		RecordID:    id,
+		Action:      action,
+	}
+	data := record
+	switch action {
+	case AuditUpdate:
+		oldv, ok := tx.Statement.Settings.LoadAndDelete(auditOldKey(id))
+		if !ok {
+			return nil
+		}
+		current, err := auditLoad(tx, record, id)
+		if err != nil || current == nil {
+			return err
+		}
+		data = current
+		ar.Changes, err = auditDiff(oldv, current)
+		if err != nil {
+			return err
+		}
+		// deleting and restoring by setting the deleted time are recorded as such
+		wasDeleted := reflect.ValueOf(oldv).Elem().FieldByName("DeletedAt").Interface().(gorm.DeletedAt).Valid
+		isDeleted := reflect.ValueOf(current).Elem().FieldByName("DeletedAt").Interface().(gorm.DeletedAt).Valid
+		switch {
+		case !wasDeleted && isDeleted:
+			ar.Action, data = AuditDelete, oldv
+		case wasDeleted && !isDeleted:
+			ar.Action = AuditRestore
+		case len(ar.Changes) == 0:
+			return nil
+		}
+	case AuditDelete:
+		oldv, ok := tx.Statement.Settings.LoadAndDelete(auditOldKey(id))
+		if !ok {
+			return nil
+		}
+		data = oldv
+	}
+	var err error
+	ar.Data, err = json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).Create(ar).Error
+}
+
+// auditDiff returns the changes between the given old and new versions of a record.
+func auditDiff(old, new any) ([]AuditChange, error) {
+	fields := func(v any) (map[string]json.RawMessage, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]json.RawMessage{}
+		return m, json.Unmarshal(b, &m)
+	}
+	om, err := fields(old)
+	if err != nil {
+		return nil, err
+	}
+	nm, err := fields(new)
+	if err != nil {
+		return nil, err
+	}
+	var changes []AuditChange
+	// the fields are in the order of the struct
+	t := reflect.TypeOf(new).Elem()
+	for i := range t.NumField() {
+		name := t.Field(i).Name
+		if slices.Contains(unauditedFields, name) {
+			continue
+		}
+		ov, nv := om[name], nm[name]
+		if nv == nil || bytes.Equal(ov, nv) {
+			continue
+		}
+		changes = append(changes, AuditChange{Field: name, Old: ov, New: nv})
+	}
+	return changes, nil
+}
+
+// mealGroupID returns the ID of the group of the meal with the given ID.
+func mealGroupID(tx *gorm.DB, mealID uint) (uint, error) {
+	var ids []uint
+	err := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&Meal{}).Where("id = ?", mealID).Pluck("group_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func (m *Meal) AfterCreate(tx *gorm.DB) error {
+	return auditAfter(tx, AuditCreate, m, m.ID, m.GroupID, m.ID)
+}
+
+func (m *Meal) BeforeUpdate(tx *gorm.DB) error {
+	return auditBefore(tx, m, m.ID)
+}
+
+func (m *Meal) AfterUpdate(tx *gorm.DB) error {
+	return auditAfter(tx, AuditUpdate, m, m.ID, m.GroupID, m.ID)
+}
+
+func (m *Meal) BeforeDelete(tx *gorm.DB) error {
+	return auditBefore(tx, m, m.ID)
+}
+
+func (m *Meal) AfterDelete(tx *gorm.DB) error {
+	return auditAfter(tx, AuditDelete, m, m.ID, m.GroupID, m.ID)
+}
+
+func (e *Entry) AfterCreate(tx *gorm.DB) error {
+	groupID, err := mealGroupID(tx, e.MealID)
+	if err != nil {
+		return err
+	}
+	return auditAfter(tx, AuditCreate, e, e.ID, groupID, e.MealID)
+}
+
+func (e *Entry) BeforeUpdate(tx *gorm.DB) error {
+	return auditBefore(tx, e, e.ID)
+}
+
+func (e *Entry) AfterUpdate(tx *gorm.DB) error {
+	groupID, err := mealGroupID(tx, e.MealID)
+	if err != nil {
+		return err
+	}
+	return auditAfter(tx, AuditUpdate, e, e.ID, groupID, e.MealID)
+}
+
+func (e *Entry) BeforeDelete(tx *gorm.DB) error {
+	return auditBefore(tx, e, e.ID)
+}
+
+func (e *Entry) AfterDelete(tx *gorm.DB) error {
+	groupID, err := mealGroupID(tx, e.MealID)
+	if err != nil {
+		return err
+	}
+	return auditAfter(tx, AuditDelete, e, e.ID, groupID, e.MealID)
+}
+
+func (g *Group) AfterCreate(tx *gorm.DB) error {
+	return auditAfter(tx, AuditCreate, g, g.ID, g.ID, 0)
+}
+
+func (g *Group) BeforeUpdate(tx *gorm.DB) error {
+	return auditBefore(tx, g, g.ID)
+}
+
+func (g *Group) AfterUpdate(tx *gorm.DB) error {
+	return auditAfter(tx, AuditUpdate, g, g.ID, g.ID, 0)
+}
+
+func (g *Group) BeforeDelete(tx *gorm.DB) error {
+	return auditBefore(tx, g, g.ID)
+}
+
+func (g *Group) AfterDelete(tx *gorm.DB) error {
+	return auditAfter(tx, AuditDelete, g, g.ID, g.ID, 0)
+}
+
+// MealHistory returns the audit log of the meal with the given ID
+// and its entries, from the newest change to the oldest.
+func MealHistory(mealID uint) ([]*AuditRecord, error) {
+	var records []*AuditRecord
+	return records, DB.Preload("Actor").Order("time DESC").Find(&records, "meal_id = ?", mealID).Error
+}
+
+// GroupHistory returns the given number of most recent records in the audit
+// log of the group with the given ID, from the newest change to the oldest.
+func GroupHistory(groupID uint, n int) ([]*AuditRecord, error) {
+	var records []*AuditRecord
+	return records, DB.Preload("Actor").Order("time DESC").Limit(n).Find(&records, "group_id = ?", groupID).Error
+}
+
+// RevertMeal reverts the meal with the given ID to the version in the audit
+// record with the given ID, restoring it if it is deleted, and returns it.
+// The given context should have the actor (see [WithActor]).
+func RevertMeal(ctx context.Context, mealID, recordID uint) (*Meal, error) {
+	ar := &AuditRecord{}
+	err := DB.First(ar, "id = ? AND record_table = ? AND record_id = ?", recordID, "meals", mealID).Error
+	if err != nil {
+		return nil, err
+	}
+	meal := &Meal{}
+	err = DB.Unscoped().First(meal, mealID).Error
+	if err != nil {
+		return nil, err
+	}
+	err = meal.Restore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version := &Meal{}
+	err = json.Unmarshal(ar.Data, version)
+	if err != nil {
+		return nil, err
+	}
+	// only the content of the meal is reverted
+	version.Model, version.GroupID, version.CreatorID, version.DeletionID = meal.Model, meal.GroupID, meal.CreatorID, meal.DeletionID
+	return version, DB.WithContext(ctx).Save(version).Error
+}
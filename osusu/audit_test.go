@@ -0,0 +1,117 @@
+package osusu
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	ctx := WithActor(context.Background(), td.b.ID)
+	db := DB.WithContext(ctx)
+
+	err := db.Model(td.tacos).Updates(&Meal{Name: "Nachos", CostPerServing: 3}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	// updates without changes are not recorded
+	err = db.Model(td.tacos).Update("name", "Nachos").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &Entry{}
+	err = DB.First(entry, "meal_id = ? AND user_id = ?", td.tacos.ID, td.b.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Model(entry).Update("taste", 40).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = td.tacos.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = td.tacos.Restore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := MealHistory(td.tacos.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the records are from the newest to the oldest, and the entries deleted and
+	// restored with the meal are not recorded because they are changed all at once
+	tests := []struct {
+		table  string
+		action AuditActions
+		// changes are the expected changed fields, which are only checked for updates
+		changes []string
+	}{
+		{"meals", AuditRestore, nil},
+		{"meals", AuditDelete, nil},
+		{"entries", AuditUpdate, []string{"Taste"}},
+		{"meals", AuditUpdate, []string{"Name", "CostPerServing"}},
+		{"entries", AuditCreate, nil},
+		{"entries", AuditCreate, nil},
+		{"meals", AuditCreate, nil},
+	}
+	if len(records) != len(tests) {
+		t.Fatalf("expected %d audit records but got %d", len(tests), len(records))
+	}
+	// the times can be the same, so the records are sorted by their IDs
+	records = testSortRecords(records)
+	for i, test := range tests {
+		r := records[i]
+		if r.RecordTable != test.table || r.Action != test.action {
+			t.Errorf("%d: expected %s %v but got %s %v", i, test.table, test.action, r.RecordTable, r.Action)
+		}
+		if r.GroupID != td.family.ID || r.MealID != td.tacos.ID {
+			t.Errorf("%d: expected group %d and meal %d but got %d and %d", i, td.family.ID, td.tacos.ID, r.GroupID, r.MealID)
+		}
+		// the seeded records are created without an actor
+		if test.action != AuditCreate && r.ActorID != td.b.ID {
+			t.Errorf("%d: expected the actor %d but got %d", i, td.b.ID, r.ActorID)
+		}
+		if test.action != AuditUpdate {
+			continue
+		}
+		fields := []string{}
+		for _, c := range r.Changes {
+			fields = append(fields, c.Field)
+		}
+		if len(fields) != len(test.changes) || (len(fields) > 0 && fields[0] != test.changes[0]) {
+			t.Errorf("%d: expected the changed fields %v but got %v", i, test.changes, fields)
+		}
+	}
+	if c := records[3].Changes[0]; string(c.Old) != `"Tacos"` || string(c.New) != `"Nachos"` {
+		t.Errorf("expected the name to change from Tacos to Nachos but got %s", c)
+	}
+
+	// reverting to the version in the create record undoes the update
+	meal, err := RevertMeal(ctx, td.tacos.ID, records[6].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meal.Name != "Tacos" || meal.CostPerServing != 2.5 {
+		t.Errorf("expected the meal to be reverted to Tacos for 2.5 but got %s for %g", meal.Name, meal.CostPerServing)
+	}
+	// records of other meals can not be used
+	_, err = RevertMeal(ctx, td.pancakes.ID, records[6].ID)
+	if err == nil {
+		t.Error("expected an error reverting to a record of another meal")
+	}
+}
+
+// testSortRecords returns the given audit records sorted
+// from the newest to the oldest by their IDs.
+func testSortRecords(records []*AuditRecord) []*AuditRecord {
+	slices.SortFunc(records, func(a, b *AuditRecord) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return records
+}
@@ -0,0 +1,319 @@
+package osusu
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BackupVersion is the current version of the backup archive format.
+const BackupVersion = 1
+
+// backupManifestName is the name of the manifest file in a backup archive.
+const backupManifestName = "manifest.json"
+
+// BackupManifest is the manifest of a backup archive, which describes the tables in it.
+type BackupManifest struct {
+	// Version is the version of the archive format (see [BackupVersion])
+	Version int
+	// Time is when the backup was made
+	Time time.Time
+	// Tables are the tables in the backup, in the order of [Models]
+	Tables []BackupTable
+}
+
+// BackupTable is a table in a backup archive, which is stored
+// as a JSON file with the name of the table.
+type BackupTable struct {
+	Name string
+	// Rows is the number of rows in the table
+	Rows int
+	// SHA256 is the hex encoded SHA-256 checksum of the file of the table
+	SHA256 string
+}
+
+// RestoreModes are the ways that a backup can be restored into a database.
+type RestoreModes int32 //enums:enum
+
+const (
+	// RestoreMerge adds the rows in the backup that are not in the database,
+	// keeping all of the existing rows.
+	RestoreMerge RestoreModes = iota
+	// RestoreReplace deletes all of the existing rows and then adds all of the
+	// rows in the backup, so the database is the same as when it was backed up.
+	RestoreReplace
+)
+
+// tableName returns the name of the database table of the given model.
+func tableName(model any) (string, error) {
+	stmt := &gorm.Statement{DB: DB}
+	err := stmt.Parse(model)
+	if err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
+
+// modelSlice returns a new pointer to a slice of the type of the given model pointer.
+func modelSlice(model any) any {
+	return reflect.New(reflect.SliceOf(reflect.TypeOf(model))).Interface()
+}
+
+// Backup writes a gzip compressed tar archive with all of the rows of all
+// of [Models], including soft deleted ones, to the given writer.
+func Backup(w io.Writer) (*BackupManifest, error) {
+	manifest := &BackupManifest{Version: BackupVersion, Time: time.Now()}
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	write := func(name string, b []byte) error {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(b)), ModTime: manifest.Time})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(b)
+		return err
+	}
+	for _, model := range Models {
+		name, err := tableName(model)
+		if err != nil {
+			return nil, err
+		}
+		rows := modelSlice(model)
+		err = DB.Unscoped().Find(rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		b, err := json.Marshal(rows)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		manifest.Tables = append(manifest.Tables, BackupTable{
+			Name:   name,
+			Rows:   reflect.ValueOf(rows).Elem().Len(),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+		err = write(name+".json", b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	b, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	err = errors.Join(write(backupManifestName, b), tw.Close(), gw.Close())
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// BackupArchive is an opened backup archive with verified checksums.
+type BackupArchive struct {
+	Manifest *BackupManifest
+	// files are the contents of the table files, keyed by table name
+	files map[string][]byte
+}
+
+// OpenBackup opens the backup archive from the given reader and verifies
+// the checksums of all of its tables.
+func OpenBackup(r io.Reader) (*BackupArchive, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	ba := &BackupArchive{files: map[string][]byte{}}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if h.Name == backupManifestName {
+			ba.Manifest = &BackupManifest{}
+			err := json.Unmarshal(b, ba.Manifest)
+			if err != nil {
+				return nil, fmt.Errorf("error reading backup manifest: %w", err)
+			}
+			continue
+		}
+		ba.files[strings.TrimSuffix(h.Name, ".json")] = b
+	}
+	if ba.Manifest == nil {
+		return nil, errors.New("backup is missing its manifest")
+	}
+	if ba.Manifest.Version > BackupVersion {
+		return nil, fmt.Errorf("backup has version %d, which is newer than the supported version %d; update the app first", ba.Manifest.Version, BackupVersion)
+	}
+	for _, t := range ba.Manifest.Tables {
+		b, ok := ba.files[t.Name]
+		if !ok {
+			return nil, fmt.Errorf("backup is missing table %s", t.Name)
+		}
+		sum := sha256.Sum256(b)
+		if hex.EncodeToString(sum[:]) != t.SHA256 {
+			return nil, fmt.Errorf("backup table %s does not match its checksum", t.Name)
+		}
+	}
+	return ba, nil
+}
+
+// OpenBackupFile opens and verifies the backup archive at the given path (see [OpenBackup]).
+func OpenBackupFile(path string) (*BackupArchive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return OpenBackup(f)
+}
+
+// Restore restores the backup into the database using the given mode and
+// returns the number of rows added. Tables that are not in the backup are
+// not changed. All of the tables are decoded before anything is written,
+// because the database does not support rolling back transactions.
+func (ba *BackupArchive) Restore(mode RestoreModes) (int, error) {
+	type table struct {
+		model any
+		rows  any
+	}
+	tables := []table{}
+	for _, model := range Models {
+		name, err := tableName(model)
+		if err != nil {
+			return 0, err
+		}
+		b, ok := ba.files[name]
+		if !ok {
+			continue
+		}
+		rows := modelSlice(model)
+		err = json.Unmarshal(b, rows)
+		if err != nil {
+			return 0, fmt.Errorf("error decoding %s: %w", name, err)
+		}
+		tables = append(tables, table{model, rows})
+	}
+
+	added := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if mode == RestoreReplace {
+			// tables are deleted in reverse order so that rows are deleted before the rows they refer to
+			for i := len(tables) - 1; i >= 0; i-- {
+				t := tables[i]
+				err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(t.model).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, t := range tables {
+			rv := reflect.ValueOf(t.rows).Elem()
+			if mode == RestoreMerge {
+				var ids []uint
+				err := tx.Unscoped().Model(t.model).Pluck("id", &ids).Error
+				if err != nil {
+					return err
+				}
+				existing := map[uint]bool{}
+				for _, id := range ids {
+					existing[id] = true
+				}
+				missing := reflect.MakeSlice(rv.Type(), 0, rv.Len())
+				for i := range rv.Len() {
+					row := rv.Index(i)
+					if !existing[uint(row.Elem().FieldByName("ID").Uint())] {
+						missing = reflect.Append(missing, row)
+					}
+				}
+				rv = missing
+			}
+			if rv.Len() == 0 {
+				continue
+			}
+			// hooks are skipped so that the audit log is restored as it was
+			err := tx.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations).CreateInBatches(rv.Interface(), 100).Error
+			if err != nil {
+				return err
+			}
+			added += rv.Len()
+		}
+		return nil
+	})
+	return added, err
+}
+
+// backupTimeLayout is the layout of the times in the names of backup files.
+const backupTimeLayout = "20060102T150405Z"
+
+// BackupToDir backs up the database to a new timestamped archive in the given
+// directory and then deletes the oldest backups in it so that there are at most
+// the given number of backups, or all of them if it is 0. It returns the path
+// of the new backup.
+func BackupToDir(dir string, keep int) (string, error) {
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "osusu-"+time.Now().UTC().Format(backupTimeLayout)+".tar.gz")
+	// we write to a temporary file first so that failed backups are not kept
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	_, err = Backup(f)
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return "", err
+	}
+	return path, PruneBackups(dir, keep)
+}
+
+// PruneBackups deletes the oldest backups made by [BackupToDir] in the given
+// directory so that there are at most the given number of backups. It does
+// nothing if the number is 0.
+func PruneBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(filepath.Join(dir, "osusu-*.tar.gz"))
+	if err != nil {
+		return err
+	}
+	// the names sort chronologically
+	slices.Sort(backups)
+	var errs []error
+	for _, b := range backups[:max(len(backups)-keep, 0)] {
+		errs = append(errs, os.Remove(b))
+	}
+	return errors.Join(errs...)
+}
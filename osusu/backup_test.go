@@ -0,0 +1,296 @@
+package osusu
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// testBackup backs up the test database and returns the archive.
+func testBackup(t *testing.T) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	_, err := Backup(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testRewriteBackup returns the given backup archive with its files
+// changed by the given function, which can change and delete files.
+func testRewriteBackup(t *testing.T, archive []byte, change func(files map[string][]byte)) []byte {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	names := []string{}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[h.Name] = b
+		names = append(names, h.Name)
+	}
+	change(files)
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for _, name := range names {
+		b, ok := files[name]
+		if !ok {
+			continue
+		}
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(b))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tw.Write(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testChangeManifest changes the manifest of the given backup files with the given function.
+func testChangeManifest(t *testing.T, files map[string][]byte, change func(m *BackupManifest)) {
+	t.Helper()
+	m := &BackupManifest{}
+	err := json.Unmarshal(files[backupManifestName], m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change(m)
+	files[backupManifestName], err = json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenBackup(t *testing.T) {
+	testDB(t)
+	testSeed(t)
+	archive := testBackup(t)
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, archive []byte) []byte
+		// err is the expected error message, or "" if the backup is valid
+		err string
+	}{
+		{"valid", func(t *testing.T, archive []byte) []byte { return archive }, ""},
+		{"tampered table", func(t *testing.T, archive []byte) []byte {
+			return testRewriteBackup(t, archive, func(files map[string][]byte) {
+				files["meals.json"] = bytes.Replace(files["meals.json"], []byte("Tacos"), []byte("Nachos"), 1)
+			})
+		}, "backup table meals does not match its checksum"},
+		{"tampered checksum", func(t *testing.T, archive []byte) []byte {
+			return testRewriteBackup(t, archive, func(files map[string][]byte) {
+				testChangeManifest(t, files, func(m *BackupManifest) {
+					m.Tables[0].SHA256 = strings.Repeat("0", 64)
+				})
+			})
+		}, "does not match its checksum"},
+		{"missing table", func(t *testing.T, archive []byte) []byte {
+			return testRewriteBackup(t, archive, func(files map[string][]byte) {
+				delete(files, "entries.json")
+			})
+		}, "backup is missing table entries"},
+		{"missing manifest", func(t *testing.T, archive []byte) []byte {
+			return testRewriteBackup(t, archive, func(files map[string][]byte) {
+				delete(files, backupManifestName)
+			})
+		}, "backup is missing its manifest"},
+		{"newer version", func(t *testing.T, archive []byte) []byte {
+			return testRewriteBackup(t, archive, func(files map[string][]byte) {
+				testChangeManifest(t, files, func(m *BackupManifest) {
+					m.Version = BackupVersion + 1
+				})
+			})
+		}, "which is newer than the supported version"},
+		{"truncated", func(t *testing.T, archive []byte) []byte { return archive[:len(archive)/2] }, "unexpected EOF"},
+		{"not gzip", func(t *testing.T, archive []byte) []byte { return []byte("osusu") }, "EOF"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ba, err := OpenBackup(bytes.NewReader(test.change(t, archive)))
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(ba.Manifest.Tables) != len(Models) {
+					t.Errorf("expected %d tables but got %d", len(Models), len(ba.Manifest.Tables))
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected an error containing %q but got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	// soft deleted rows are backed up too
+	err := td.pancakes.Delete(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := testBackup(t)
+	count := func(t *testing.T) map[string]int64 {
+		t.Helper()
+		res := map[string]int64{}
+		for _, model := range Models {
+			name, err := tableName(model)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var n int64
+			err = DB.Unscoped().Model(model).Count(&n).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			res[name] = n
+		}
+		return res
+	}
+	want := count(t)
+
+	tests := []struct {
+		mode  RestoreModes
+		added int
+		// tacos is the expected name of the tacos meal after restoring
+		tacos string
+	}{
+		// only the permanently deleted soup meal is added back
+		{RestoreMerge, 1, "Changed"},
+		{RestoreReplace, 0, "Tacos"},
+	}
+	for _, test := range tests {
+		t.Run(test.mode.String(), func(t *testing.T) {
+			ba, err := OpenBackup(bytes.NewReader(archive))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the changes are not audited so that only the rows of the backup differ
+			db := DB.Session(&gorm.Session{SkipHooks: true})
+			err = db.Model(td.tacos).Update("name", "Changed").Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = db.Unscoped().Delete(td.soup).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			added, err := ba.Restore(test.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.mode == RestoreReplace {
+				test.added = 0
+				for _, n := range want {
+					test.added += int(n)
+				}
+			}
+			if added != test.added {
+				t.Errorf("expected %d added rows but got %d", test.added, added)
+			}
+			if got := count(t); !maps.Equal(got, want) {
+				t.Errorf("expected the numbers of rows %v but got %v", want, got)
+			}
+			meal := &Meal{}
+			err = DB.First(meal, td.tacos.ID).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meal.Name != test.tacos {
+				t.Errorf("expected the meal to be named %q but got %q", test.tacos, meal.Name)
+			}
+			// the deleted meal is still in the trash
+			meal = &Meal{}
+			err = DB.Unscoped().First(meal, td.pancakes.ID).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !meal.DeletedAt.Valid {
+				t.Error("expected the soft deleted meal to still be deleted")
+			}
+		})
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	backups := []string{
+		"osusu-20240101T000000Z.tar.gz",
+		"osusu-20240301T000000Z.tar.gz",
+		"osusu-20240201T000000Z.tar.gz",
+		"osusu-20231231T235959Z.tar.gz",
+	}
+	tests := []struct {
+		keep int
+		want []string
+	}{
+		{0, []string{"osusu-20231231T235959Z.tar.gz", "osusu-20240101T000000Z.tar.gz", "osusu-20240201T000000Z.tar.gz", "osusu-20240301T000000Z.tar.gz"}},
+		{1, []string{"osusu-20240301T000000Z.tar.gz"}},
+		{2, []string{"osusu-20240201T000000Z.tar.gz", "osusu-20240301T000000Z.tar.gz"}},
+		{10, []string{"osusu-20231231T235959Z.tar.gz", "osusu-20240101T000000Z.tar.gz", "osusu-20240201T000000Z.tar.gz", "osusu-20240301T000000Z.tar.gz"}},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		// other files and unfinished backups are never deleted
+		for _, name := range append(backups, "notes.txt", "osusu-20230101T000000Z.tar.gz.tmp") {
+			err := os.WriteFile(filepath.Join(dir, name), nil, 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := PruneBackups(dir, test.keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := append(test.want, "notes.txt", "osusu-20230101T000000Z.tar.gz.tmp")
+		slices.Sort(want)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, e := range entries {
+			got = append(got, e.Name())
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("keeping %d: expected %v but got %v", test.keep, want, got)
+		}
+	}
+}
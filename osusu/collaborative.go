@@ -0,0 +1,253 @@
+package osusu
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Interaction is an implicit feedback interaction between a user and a recipe,
+// which is the user having entries for a meal added from the recipe.
+type Interaction struct {
+	UserID uint
+	// URL is the URL of the recipe, which links meals across groups
+	URL string
+	// Strength is the strength of the interaction, which is the sum of the
+	// taste ratings from 0 to 1 of the entries of the user for the meal
+	Strength float64
+}
+
+// LoadInteractions returns the interactions of all users in groups that share
+// their data (see [Group.ShareData]). Only the user IDs and recipe URLs are
+// loaded, so no other information about the users, groups, or meals is used.
+func LoadInteractions() ([]Interaction, error) {
+	var rows []struct {
+		UserID    uint
+		SourceURL string
+		Taste     int
+	}
+	err := DB.Table("entries").
+		Select("entries.user_id, meals.source_url, entries.taste").
+		Joins("JOIN meals ON meals.id = entries.meal_id").
+		Joins("JOIN groups ON groups.id = meals.group_id").
+		Where("entries.deleted_at IS NULL AND meals.deleted_at IS NULL AND meals.source_url <> ''").
+		Where("groups.share_data = ?", true).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	type key struct {
+		userID uint
+		url    string
+	}
+	strengths := map[key]float64{}
+	keys := []key{}
+	for _, row := range rows {
+		k := key{row.UserID, row.SourceURL}
+		if _, ok := strengths[k]; !ok {
+			keys = append(keys, k)
+		}
+		strengths[k] += float64(row.Taste) / 100
+	}
+	res := make([]Interaction, len(keys))
+	for i, k := range keys {
+		res[i] = Interaction{UserID: k.userID, URL: k.url, Strength: strengths[k]}
+	}
+	return res, nil
+}
+
+// CollaborativeModelMaxAge is how long the model returned by [LoadCollaborativeModel]
+// is used before it is fitted again to the latest interactions.
+var CollaborativeModelMaxAge = time.Hour
+
+// collaborativeCache is the cached model returned by [LoadCollaborativeModel].
+var collaborativeCache struct {
+	sync.Mutex
+	// model is the fitted model, or nil if there is not enough data to fit one
+	model *CollaborativeModel
+	// fitted is when the model was fitted
+	fitted time.Time
+}
+
+// LoadCollaborativeModel returns a [CollaborativeModel] fitted to the interactions
+// from [LoadInteractions], or nil if there is not enough data to fit one. Fitting
+// the model uses all of the shared data, so the fitted model is cached and only
+// fitted again after [CollaborativeModelMaxAge]. The returned model must not be
+// changed, since it is shared.
+func LoadCollaborativeModel() (*CollaborativeModel, error) {
+	c := &collaborativeCache
+	c.Lock()
+	defer c.Unlock()
+	if !c.fitted.IsZero() && time.Since(c.fitted) < CollaborativeModelMaxAge {
+		return c.model, nil
+	}
+	interactions, err := LoadInteractions()
+	if err != nil {
+		return nil, err
+	}
+	cm := NewCollaborativeModel()
+	// the model can not be fit to degenerate data, in which case only the content is used
+	if cm.Fit(interactions) != nil {
+		cm = nil
+	}
+	c.model, c.fitted = cm, time.Now()
+	return cm, nil
+}
+
+// CollaborativeModel is an implicit feedback matrix factorization model
+// (Hu, Koren, and Volinsky, 2008) fitted with alternating least squares,
+// which predicts how much a user will like a recipe based on the recipes
+// that similar users like.
+type CollaborativeModel struct {
+	// Factors is the number of latent factors
+	Factors int
+	// Regularization is the L2 regularization strength
+	Regularization float64
+	// Alpha is how much the strength of an interaction increases its confidence
+	Alpha float64
+	// Iterations is the number of alternating least squares iterations
+	Iterations int
+	// Seed is the seed for the random initialization of the factors
+	Seed uint64
+
+	users map[uint]int
+	items map[string]int
+	// userFactors has a row of factors for each user
+	userFactors *mat.Dense
+	// itemFactors has a row of factors for each item
+	itemFactors *mat.Dense
+}
+
+// NewCollaborativeModel returns a new [CollaborativeModel] with default parameters.
+func NewCollaborativeModel() *CollaborativeModel {
+	return &CollaborativeModel{
+		Factors:        10,
+		Regularization: 0.1,
+		Alpha:          20,
+		Iterations:     10,
+		Seed:           1,
+	}
+}
+
+// Fit fits the model to the given interactions.
+func (cm *CollaborativeModel) Fit(interactions []Interaction) error {
+	if len(interactions) == 0 {
+		return errors.New("no interactions to fit the collaborative filtering model to")
+	}
+	cm.users = map[uint]int{}
+	cm.items = map[string]int{}
+	for _, in := range interactions {
+		if _, ok := cm.users[in.UserID]; !ok {
+			cm.users[in.UserID] = len(cm.users)
+		}
+		if _, ok := cm.items[in.URL]; !ok {
+			cm.items[in.URL] = len(cm.items)
+		}
+	}
+	nu, ni, f := len(cm.users), len(cm.items), cm.Factors
+
+	// the interactions of each user and item, with the index of the other side
+	type observed struct {
+		index      int
+		confidence float64
+	}
+	byUser := make([][]observed, nu)
+	byItem := make([][]observed, ni)
+	for _, in := range interactions {
+		u, i := cm.users[in.UserID], cm.items[in.URL]
+		c := 1 + cm.Alpha*in.Strength
+		byUser[u] = append(byUser[u], observed{i, c})
+		byItem[i] = append(byItem[i], observed{u, c})
+	}
+
+	rng := rand.New(rand.NewPCG(cm.Seed, cm.Seed))
+	random := func(r, c int) *mat.Dense {
+		data := make([]float64, r*c)
+		for i := range data {
+			data[i] = 0.1 * rng.Float64()
+		}
+		return mat.NewDense(r, c, data)
+	}
+	cm.userFactors = random(nu, f)
+	cm.itemFactors = random(ni, f)
+
+	// solve solves for the factors of each row of dst given the fixed factors
+	// of the other side by minimizing the confidence weighted squared loss
+	solve := func(dst, fixed *mat.Dense, obs [][]observed) error {
+		// YᵀY is shared by all rows
+		var yty mat.SymDense
+		yty.SymOuterK(1, fixed.T())
+		a := mat.NewSymDense(f, nil)
+		b := mat.NewVecDense(f, nil)
+		x := mat.NewVecDense(f, nil)
+		var chol mat.Cholesky
+		for r, os := range obs {
+			// A = YᵀY + Yᵀ(C - I)Y + λI and b = YᵀCp, where p is 1 for observed interactions
+			a.CopySym(&yty)
+			b.Zero()
+			for _, o := range os {
+				y := fixed.RowView(o.index)
+				a.SymRankOne(a, o.confidence-1, y)
+				b.AddScaledVec(b, o.confidence, y)
+			}
+			for j := range f {
+				a.SetSym(j, j, a.At(j, j)+cm.Regularization)
+			}
+			if !chol.Factorize(a) {
+				return errors.New("collaborative filtering model is not positive definite")
+			}
+			err := chol.SolveVecTo(x, b)
+			if err != nil {
+				return err
+			}
+			dst.SetRow(r, x.RawVector().Data)
+		}
+		return nil
+	}
+	for range cm.Iterations {
+		if err := solve(cm.userFactors, cm.itemFactors, byUser); err != nil {
+			return err
+		}
+		if err := solve(cm.itemFactors, cm.userFactors, byItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Predict returns the predicted preference of the given user for the recipe with
+// the given URL, which is roughly from 0 to 1, and whether there is a prediction,
+// which requires both the user and the recipe to be in the fitted interactions.
+func (cm *CollaborativeModel) Predict(userID uint, url string) (float64, bool) {
+	u, ok := cm.users[userID]
+	if !ok {
+		return 0, false
+	}
+	i, ok := cm.items[url]
+	if !ok {
+		return 0, false
+	}
+	return mat.Dot(cm.userFactors.RowView(u), cm.itemFactors.RowView(i)), true
+}
+
+// BlendCollaborative blends the predictions of the given fitted model for the given
+// user into the total scores of the given recipes based on [Options.CollaborativeWeight].
+// Recipes without predictions keep their scores. The recipes should already have
+// their total scores computed.
+func BlendCollaborative(recipes []*Recipe, cm *CollaborativeModel, userID uint, opts *Options) {
+	w := float64(opts.CollaborativeWeight) / 100
+	if w == 0 {
+		return
+	}
+	for _, recipe := range recipes {
+		pred, ok := cm.Predict(userID, recipe.URL)
+		if !ok {
+			continue
+		}
+		score := min(max(100*pred, 0), 100)
+		recipe.Score.Total = (1-w)*recipe.Score.Total + w*score
+	}
+}
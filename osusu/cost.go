@@ -0,0 +1,344 @@
+package osusu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// IngredientPrice is the price of an ingredient in the price table of a group.
+type IngredientPrice struct {
+	gorm.Model `display:"-"`
+	GroupID    uint `display:"-"`
+	// Name is the name of the ingredient, which is matched against recipe ingredient lines
+	Name string
+	// Amount is the amount of the ingredient in Unit that costs Price
+	Amount float64 `def:"1"`
+	// Unit is the unit of Amount (eg: lb, g, cup), or blank for a count
+	Unit     string
+	Price    float64
+	Currency string
+}
+
+// PriceTable is a table of ingredient prices.
+type PriceTable []IngredientPrice
+
+// DefaultIngredientCost is the cost used for an ingredient that does not match
+// any item in the price table when no ingredients in the recipe match any items.
+// Otherwise, the average cost of the matched ingredients is used.
+var DefaultIngredientCost = 1.0
+
+// CostEstimate is an estimate of the cost of a recipe.
+type CostEstimate struct {
+	// Total is the estimated total cost of the recipe, including Estimated
+	Total float64
+	// Currency is the currency of the total, which is the currency of the
+	// most matched ingredient lines
+	Currency string
+	// Estimated is the part of the total for the unmatched ingredient lines,
+	// each of which costs the average cost of the matched lines
+	Estimated float64
+	// Matched are the ingredient lines that matched an item in the price table
+	Matched []string
+	// Unmatched are the ingredient lines that did not match an item in the
+	// price table with the currency of the estimate
+	Unmatched []string
+	// Explanation is a human-readable explanation of the estimate
+	Explanation string
+}
+
+// EstimateCost returns an estimate of the cost of the given ingredient lines.
+// Prices in different currencies are not converted, so the estimate only uses
+// the prices in the currency of the most lines, and the lines that match prices
+// in other currencies are unmatched.
+func (pt PriceTable) EstimateCost(ingredients []string) *CostEstimate {
+	type match struct {
+		line     string
+		cost     float64
+		currency string
+	}
+	matches := make([]*match, len(ingredients))
+	counts := map[string]int{}
+	for i, line := range ingredients {
+		ing := ParseIngredient(line)
+		price := pt.Match(ing.Name)
+		if price == nil {
+			continue
+		}
+		cost, ok := price.Cost(ing)
+		if !ok {
+			continue
+		}
+		matches[i] = &match{line, cost, price.Currency}
+		counts[price.Currency]++
+	}
+
+	ce := &CostEstimate{}
+	// ties are broken by the order of the price table so that the currency is deterministic
+	for _, price := range pt {
+		if counts[price.Currency] > counts[ce.Currency] {
+			ce.Currency = price.Currency
+		}
+	}
+	for i, line := range ingredients {
+		m := matches[i]
+		if m == nil || m.currency != ce.Currency {
+			ce.Unmatched = append(ce.Unmatched, line)
+			continue
+		}
+		ce.Total += m.cost
+		ce.Matched = append(ce.Matched, line)
+	}
+	unmatchedCost := DefaultIngredientCost
+	if len(ce.Matched) > 0 {
+		unmatchedCost = ce.Total / float64(len(ce.Matched))
+	}
+	ce.Estimated = unmatchedCost * float64(len(ce.Unmatched))
+	ce.Total += ce.Estimated
+
+	switch {
+	case len(ce.Unmatched) == 0:
+		ce.Explanation = fmt.Sprintf("all %d ingredients have prices", len(ce.Matched))
+	case len(ce.Matched) == 0:
+		ce.Explanation = fmt.Sprintf("no ingredients have prices, so each is estimated at %.2f", unmatchedCost)
+	default:
+		ce.Explanation = fmt.Sprintf("%d of %d ingredients have prices; the other %d are estimated at their average cost of %.2f each (%.2f in total)",
+			len(ce.Matched), len(ingredients), len(ce.Unmatched), unmatchedCost, ce.Estimated)
+	}
+	if ce.Currency != "" {
+		ce.Explanation = ce.Currency + ": " + ce.Explanation
+	}
+	return ce
+}
+
+// Match returns the item in the price table that best matches the
+// given ingredient name, or nil if there is no match. An item matches
+// if all of the words in its name are in the ingredient name, and the
+// item with the most words is the best match.
+func (pt PriceTable) Match(name string) *IngredientPrice {
+	words := ingredientWords(name)
+	var best *IngredientPrice
+	bestWords := 0
+	for i := range pt {
+		price := &pt[i]
+		pwords := ingredientWords(price.Name)
+		if len(pwords) <= bestWords {
+			continue
+		}
+		matches := true
+		for pw := range pwords {
+			if !words[pw] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			best = price
+			bestWords = len(pwords)
+		}
+	}
+	return best
+}
+
+// Cost returns the cost of the given parsed ingredient based on the price,
+// and whether the cost could be determined, which requires the units to be
+// convertible.
+func (ip *IngredientPrice) Cost(ing *ParsedIngredient) (float64, bool) {
+	amount := ip.Amount
+	if amount <= 0 {
+		amount = 1
+	}
+	quantity := ing.Quantity
+	if quantity <= 0 {
+		quantity = 1
+	}
+	factor, ok := ConvertUnit(ing.Unit, ip.Unit)
+	if !ok {
+		return 0, false
+	}
+	return ip.Price * quantity * factor / amount, true
+}
+
+// EstimateCost estimates the cost of the recipe using the given price table,
+// and sets [Recipe.CostPerServing] and [Recipe.CostExplanation] based on it.
+func (r *Recipe) EstimateCost(pt PriceTable) *CostEstimate {
+	ce := pt.EstimateCost(r.Ingredients)
+	r.CostPerServing = ce.Total
+	r.CostExplanation = ce.Explanation
+	if r.Yield > 0 {
+		r.CostPerServing /= float64(r.Yield)
+	}
+	return ce
+}
+
+// ParsedIngredient is a parsed recipe ingredient line.
+type ParsedIngredient struct {
+	// Quantity is the amount of the ingredient in Unit, or 0 if it is not specified
+	Quantity float64
+	// Unit is the canonical unit of Quantity (see [ConvertUnit]), or blank for a count
+	Unit string
+	// Name is the rest of the ingredient line
+	Name string
+}
+
+// unicodeFractions are the values of unicode vulgar fraction characters.
+var unicodeFractions = map[rune]float64{
+	'¼': 1.0 / 4, '½': 1.0 / 2, '¾': 3.0 / 4, '⅓': 1.0 / 3, '⅔': 2.0 / 3,
+	'⅕': 1.0 / 5, '⅙': 1.0 / 6, '⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+// ParseIngredient parses the given recipe ingredient line (eg: "1 1/2 cups flour").
+func ParseIngredient(line string) *ParsedIngredient {
+	ing := &ParsedIngredient{}
+	fields := strings.Fields(line)
+	i := 0
+	// quantities can be made of multiple fields (eg: 1 1/2)
+	for ; i < len(fields); i++ {
+		q, ok := parseQuantity(fields[i])
+		if !ok {
+			break
+		}
+		ing.Quantity += q
+	}
+	unit := func(s string) (string, bool) {
+		u, ok := unitAliases[strings.ToLower(strings.TrimRight(s, ".,"))]
+		return u, ok
+	}
+	// units can be two fields (eg: fl oz)
+	if i+1 < len(fields) {
+		if u, ok := unit(fields[i] + " " + fields[i+1]); ok {
+			ing.Unit = u
+			i += 2
+		}
+	}
+	if ing.Unit == "" && i < len(fields) {
+		if u, ok := unit(fields[i]); ok {
+			ing.Unit = u
+			i++
+		}
+	}
+	ing.Name = strings.Join(fields[i:], " ")
+	return ing
+}
+
+// parseQuantity parses the given field as a quantity, handling
+// integers, decimals, fractions, unicode fractions, and ranges
+// (for which the lower bound is used).
+func parseQuantity(s string) (float64, bool) {
+	if lo, _, ok := strings.Cut(s, "-"); ok && lo != "" {
+		s = lo
+	}
+	res := 0.0
+	// unicode fractions can be attached to a whole number (eg: 1½)
+	for _, r := range s {
+		if f, ok := unicodeFractions[r]; ok {
+			res += f
+			s = strings.Replace(s, string(r), "", 1)
+		}
+	}
+	if s == "" {
+		return res, res > 0
+	}
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, false
+		}
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil || d == 0 {
+			return 0, false
+		}
+		return res + n/d, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return res + f, true
+}
+
+// unitAliases maps lowercase unit names to their canonical names.
+var unitAliases = map[string]string{
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"tbsp": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp", "tbs": "tbsp",
+	"cup": "cup", "cups": "cup",
+	"fl oz": "floz", "floz": "floz",
+	"pint": "pint", "pints": "pint", "pt": "pint",
+	"quart": "quart", "quarts": "quart", "qt": "quart",
+	"gallon": "gallon", "gallons": "gallon", "gal": "gallon",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml",
+	"l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"g": "g", "gram": "g", "grams": "g",
+	"kg": "kg", "kilogram": "kg", "kilograms": "kg",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"clove": "clove", "cloves": "clove",
+	"can": "can", "cans": "can",
+	"package": "package", "packages": "package", "pkg": "package",
+	"slice": "slice", "slices": "slice",
+	"pinch": "pinch", "dash": "dash",
+}
+
+// unitVolumes are the sizes of volume units in ml.
+var unitVolumes = map[string]float64{
+	"tsp": 4.92892, "tbsp": 14.7868, "cup": 236.588, "floz": 29.5735,
+	"pint": 473.176, "quart": 946.353, "gallon": 3785.41, "ml": 1, "l": 1000,
+}
+
+// unitMasses are the sizes of mass units in g.
+var unitMasses = map[string]float64{
+	"g": 1, "kg": 1000, "oz": 28.3495, "lb": 453.592,
+}
+
+// ConvertUnit returns the factor to multiply a quantity in the given unit by
+// to get the quantity in the given target unit, and whether the units can be
+// converted. Units are canonicalized, and blank units are counts. Volumes can
+// not be converted to masses, since that requires the density of the ingredient.
+func ConvertUnit(from, to string) (float64, bool) {
+	canonical := func(unit string) string {
+		unit = strings.ToLower(strings.TrimSpace(unit))
+		if c, ok := unitAliases[unit]; ok {
+			return c
+		}
+		return unit
+	}
+	from, to = canonical(from), canonical(to)
+	if from == to {
+		return 1, true
+	}
+	if fv, ok := unitVolumes[from]; ok {
+		if tv, ok := unitVolumes[to]; ok {
+			return fv / tv, true
+		}
+	}
+	if fm, ok := unitMasses[from]; ok {
+		if tm, ok := unitMasses[to]; ok {
+			return fm / tm, true
+		}
+	}
+	return 0, false
+}
+
+// ingredientWords returns the set of normalized words in the given ingredient name.
+// Words are lowercased, and plurals are made singular using a simple heuristic.
+func ingredientWords(name string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	res := map[string]bool{}
+	for _, w := range words {
+		switch {
+		case strings.HasSuffix(w, "oes"):
+			w = strings.TrimSuffix(w, "es")
+		case strings.HasSuffix(w, "ies"):
+			w = strings.TrimSuffix(w, "ies") + "y"
+		case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss"):
+			w = strings.TrimSuffix(w, "s")
+		}
+		res[w] = true
+	}
+	return res
+}
@@ -0,0 +1,26 @@
+package osusu
+
+import "testing"
+
+func TestEstimateCostCurrencies(t *testing.T) {
+	pt := PriceTable{
+		{Name: "flour", Amount: 1, Unit: "cup", Price: 0.5, Currency: "USD"},
+		{Name: "sugar", Amount: 1, Unit: "cup", Price: 1, Currency: "USD"},
+		{Name: "butter", Amount: 1, Unit: "cup", Price: 3, Currency: "EUR"},
+	}
+	ce := pt.EstimateCost([]string{"2 cups flour", "1 cup sugar", "1 cup butter", "1 egg"})
+	if ce.Currency != "USD" {
+		t.Errorf("expected the currency of the most lines, USD, but got %q", ce.Currency)
+	}
+	// the butter is in another currency, so it is unmatched like the egg
+	if len(ce.Matched) != 2 || len(ce.Unmatched) != 2 {
+		t.Fatalf("expected 2 matched and 2 unmatched lines, but got %v and %v", ce.Matched, ce.Unmatched)
+	}
+	// the unmatched lines cost the average of 1 and 1
+	if ce.Estimated != 2 || ce.Total != 4 {
+		t.Errorf("expected an estimated cost of 2 and a total of 4, but got %g and %g", ce.Estimated, ce.Total)
+	}
+	if ce.Explanation == "" {
+		t.Error("expected an explanation of the estimated cost")
+	}
+}
@@ -0,0 +1,620 @@
+package osusu
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cogentcore.org/core/enums"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DataVersion is the current version of the schema of exported data. It is
+// increased whenever the schema changes in a way that older versions can not read.
+const DataVersion = 1
+
+// DataFormats are the formats that data can be exported to and imported from.
+type DataFormats int32 //enums:enum
+
+const (
+	// DataJSON is a single JSON file with a schema version, which is lossless.
+	DataJSON DataFormats = iota
+	// DataCSV is a directory with one CSV file per table, with bit flags as
+	// readable names, which can be edited in spreadsheets.
+	DataCSV
+)
+
+// ImportConflicts are the ways of handling imported records with the same IDs as existing records.
+type ImportConflicts int32 //enums:enum
+
+const (
+	// ImportSkip keeps the existing records and skips the imported ones.
+	ImportSkip ImportConflicts = iota
+	// ImportOverwrite replaces the existing records with the imported ones.
+	ImportOverwrite
+	// ImportNew creates all of the imported records with new IDs and updates the
+	// references between them, so that no existing records are changed. Imported
+	// users are matched to existing users by email, only in the group that the
+	// data is imported into if there is one.
+	ImportNew
+)
+
+// DataExport is exported meal, entry, group, and user data.
+type DataExport struct {
+	// Version is the schema version of the data (see [DataVersion])
+	Version int
+	// Time is when the data was exported
+	Time    time.Time
+	Groups  []*Group
+	Users   []*User
+	Meals   []*Meal
+	Entries []*Entry
+}
+
+// ExportData returns all of the data of the group with the given ID,
+// which is its members, meals, and entries, or all of the data in the
+// database if the group ID is 0.
+func ExportData(groupID uint) (*DataExport, error) {
+	de := &DataExport{Version: DataVersion, Time: time.Now()}
+	if groupID == 0 {
+		err := errors.Join(
+			DB.Find(&de.Groups).Error,
+			DB.Find(&de.Users).Error,
+			DB.Find(&de.Meals).Error,
+			DB.Find(&de.Entries).Error,
+		)
+		return de, err
+	}
+	err := errors.Join(
+		DB.Find(&de.Groups, groupID).Error,
+		DB.Find(&de.Users, "group_id = ?", groupID).Error,
+		DB.Find(&de.Meals, "group_id = ?", groupID).Error,
+		DB.Find(&de.Entries, "meal_id IN (?)", DB.Model(&Meal{}).Select("id").Where("group_id = ?", groupID)).Error,
+	)
+	return de, err
+}
+
+// Save saves the data to the given path in the given format. For [DataCSV],
+// the path is a directory, which is created if it does not exist.
+func (de *DataExport) Save(path string, format DataFormats) error {
+	if format == DataJSON {
+		b, err := json.MarshalIndent(de, "", "\t")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, b, 0666)
+	}
+	err := os.MkdirAll(path, 0777)
+	if err != nil {
+		return err
+	}
+	for _, table := range de.tables() {
+		err := writeCSV(filepath.Join(path, table.name+".csv"), table.records)
+		if err != nil {
+			return fmt.Errorf("error saving %s: %w", table.name, err)
+		}
+	}
+	return nil
+}
+
+// OpenDataExport opens exported data from the given path in the given format.
+// For [DataCSV], the path is a directory or a file in it, and tables without
+// files are empty; the files only need the columns that have values.
+func OpenDataExport(path string, format DataFormats) (*DataExport, error) {
+	de := &DataExport{}
+	if format == DataJSON {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(b, de)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case de.Version == 0:
+			return nil, errors.New("exported data is missing a schema version")
+		case de.Version > DataVersion:
+			return nil, fmt.Errorf("exported data has schema version %d, which is newer than the supported version %d; update the app first", de.Version, DataVersion)
+		}
+		return de, nil
+	}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		path = filepath.Dir(path)
+	}
+	de.Version = DataVersion
+	for _, table := range de.tables() {
+		err := readCSV(filepath.Join(path, table.name+".csv"), table.records)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error opening %s: %w", table.name, err)
+		}
+	}
+	return de, nil
+}
+
+// dataTable is a table of a [DataExport], with a pointer to its records.
+type dataTable struct {
+	name    string
+	records any
+}
+
+// tables returns the tables of the data in the order they need to be imported.
+func (de *DataExport) tables() []dataTable {
+	return []dataTable{
+		{"groups", &de.Groups},
+		{"users", &de.Users},
+		{"meals", &de.Meals},
+		{"entries", &de.Entries},
+	}
+}
+
+// ImportOptions are the options for importing data in [DataExport.Import].
+type ImportOptions struct {
+	// Conflicts is how imported records with the same IDs as existing records are handled
+	Conflicts ImportConflicts
+	// GroupID is, if it is not 0, the ID of the group that all imported users and
+	// meals are put in, in which case the imported groups are not imported and
+	// only the records of the group can be skipped, overwritten, or referenced
+	GroupID uint
+	// UserID is the ID of the user that imported entries without users are given
+	UserID uint
+}
+
+// ImportResult is the result of importing data in [DataExport.Import].
+type ImportResult struct {
+	Created int
+	Updated int
+	Skipped int
+}
+
+func (ir *ImportResult) String() string {
+	return fmt.Sprintf("Imported %d records, updated %d, and skipped %d", ir.Created, ir.Updated, ir.Skipped)
+}
+
+// Import imports the data into the database with the given options. When
+// importing into a group, records of other groups are never changed: imported
+// records with the IDs of records in other groups are created with new IDs instead.
+//
+// The references of the data are checked before anything is imported, but the
+// database does not support rolling back transactions, so if writing a record
+// fails, the records imported before it stay imported and are counted in the
+// returned result along with the error. Importing the same data again with
+// [ImportSkip] or [ImportOverwrite] then finishes the import, while importing
+// it again with [ImportNew] creates the already imported records a second time.
+func (de *DataExport) Import(opts *ImportOptions) (*ImportResult, error) {
+	err := de.checkReferences(opts)
+	if err != nil {
+		return nil, err
+	}
+	res := &ImportResult{}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// the new IDs of imported records with IDs, keyed by their old IDs
+		groupIDs, userIDs, mealIDs := map[uint]uint{}, map[uint]uint{}, map[uint]uint{}
+		remap := func(ids map[uint]uint, id uint) uint {
+			if nid, ok := ids[id]; ok {
+				return nid
+			}
+			return id
+		}
+
+		if opts.GroupID == 0 {
+			for _, g := range de.Groups {
+				old := g.ID
+				if opts.Conflicts == ImportNew {
+					g.DefaultPresetID = 0
+				}
+				err := importRecord(tx, g, &g.ID, opts, res)
+				if err != nil {
+					return err
+				}
+				if old != 0 {
+					groupIDs[old] = g.ID
+				}
+			}
+		}
+		for _, u := range de.Users {
+			old := u.ID
+			if u.Email != "" {
+				existing := &User{}
+				err := tx.Where("email = ?", u.Email).Limit(1).Find(existing).Error
+				if err != nil {
+					return err
+				}
+				switch {
+				case existing.ID == 0:
+				case opts.GroupID != 0 && existing.GroupID != opts.GroupID:
+					// the email is of an account in another group, which
+					// must not be merged with or duplicated by the imported user
+					u.Email = ""
+				case opts.Conflicts == ImportNew:
+					userIDs[old] = existing.ID
+					res.Skipped++
+					continue
+				}
+			}
+			if opts.GroupID != 0 {
+				u.GroupID = opts.GroupID
+			} else {
+				u.GroupID = remap(groupIDs, u.GroupID)
+			}
+			if opts.Conflicts == ImportNew {
+				u.PresetID = 0
+			}
+			err := importRecord(tx, u, &u.ID, opts, res)
+			if err != nil {
+				return err
+			}
+			if old != 0 {
+				userIDs[old] = u.ID
+			}
+		}
+		// owners can only be updated once their users have been imported
+		for _, g := range de.Groups {
+			if owner := remap(userIDs, g.OwnerID); opts.GroupID == 0 && owner != g.OwnerID {
+				g.OwnerID = owner
+				err := tx.Model(g).Update("owner_id", owner).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, m := range de.Meals {
+			old := m.ID
+			if opts.GroupID != 0 {
+				m.GroupID = opts.GroupID
+			} else {
+				m.GroupID = remap(groupIDs, m.GroupID)
+			}
+			if creator, ok := userIDs[m.CreatorID]; ok {
+				m.CreatorID = creator
+			} else if opts.GroupID != 0 {
+				// the creator is not an imported user, so it could be anyone
+				m.CreatorID = 0
+			}
+			creator := m.CreatorID
+			err := importRecord(tx, m, &m.ID, opts, res)
+			if err != nil {
+				return err
+			}
+			// created meals are given the actor as their creator, so the imported one is set again
+			if m.ID != 0 && m.CreatorID != creator {
+				m.CreatorID = creator
+				err := tx.Model(m).UpdateColumn("creator_id", creator).Error
+				if err != nil {
+					return err
+				}
+			}
+			if old != 0 {
+				mealIDs[old] = m.ID
+			}
+		}
+		for _, e := range de.Entries {
+			e.MealID = remap(mealIDs, e.MealID)
+			if e.UserID == 0 {
+				e.UserID = opts.UserID
+			} else {
+				e.UserID = remap(userIDs, e.UserID)
+			}
+			err := importRecord(tx, e, &e.ID, opts, res)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return res, err
+}
+
+// importRecord imports the given record with the given ID field
+// using the given import options.
+func importRecord[T any](tx *gorm.DB, record *T, id *uint, opts *ImportOptions, res *ImportResult) error {
+	if opts.Conflicts == ImportNew {
+		*id = 0
+	}
+	if *id != 0 {
+		var count int64
+		err := tx.Model(new(T)).Unscoped().Where("id = ?", *id).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 && opts.GroupID != 0 {
+			var inGroupCount int64
+			err := inGroup(tx.Model(new(T)).Unscoped().Where("id = ?", *id), record, opts.GroupID).Count(&inGroupCount).Error
+			if err != nil {
+				return err
+			}
+			if inGroupCount == 0 {
+				// the ID is used by a record in another group, which must not be changed
+				*id = 0
+				count = 0
+			}
+		}
+		if count > 0 {
+			if opts.Conflicts == ImportSkip {
+				res.Skipped++
+				return nil
+			}
+			res.Updated++
+			return tx.Omit(clause.Associations).Save(record).Error
+		}
+	}
+	res.Created++
+	return tx.Omit(clause.Associations).Create(record).Error
+}
+
+// inGroup returns the given query limited to the records of the type of the given
+// model that are in the group with the given ID.
+func inGroup(q *gorm.DB, model any, groupID uint) *gorm.DB {
+	switch model.(type) {
+	case *Group:
+		return q.Where("id = ?", groupID)
+	case *Entry:
+		meals := q.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&Meal{}).Select("id").Where("group_id = ?", groupID)
+		return q.Where("meal_id IN (?)", meals)
+	}
+	return q.Where("group_id = ?", groupID)
+}
+
+// checkReferences returns an error if any of the records of the data refer to
+// records that are neither in the data nor in the database. When importing into
+// a group, the records in the database must be in the group.
+func (de *DataExport) checkReferences(opts *ImportOptions) error {
+	groups, users, meals := map[uint]bool{}, map[uint]bool{}, map[uint]bool{}
+	for _, g := range de.Groups {
+		groups[g.ID] = true
+	}
+	for _, u := range de.Users {
+		users[u.ID] = true
+	}
+	for _, m := range de.Meals {
+		meals[m.ID] = true
+	}
+	// check returns an error if the given ID is not in the data or the database
+	check := func(model any, ids map[uint]bool, id uint, what string) error {
+		if id == 0 || ids[id] {
+			return nil
+		}
+		q := DB.Model(model).Where("id = ?", id)
+		if opts.GroupID != 0 {
+			q = inGroup(q, model, opts.GroupID)
+		}
+		var count int64
+		err := q.Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			if opts.GroupID != 0 {
+				return fmt.Errorf("%s %d does not exist in the group", what, id)
+			}
+			return fmt.Errorf("%s %d does not exist", what, id)
+		}
+		ids[id] = true
+		return nil
+	}
+	if opts.GroupID == 0 {
+		for _, u := range de.Users {
+			if err := check(&Group{}, groups, u.GroupID, "group"); err != nil {
+				return fmt.Errorf("user %q: %w", u.Name, err)
+			}
+		}
+		for _, m := range de.Meals {
+			if err := check(&Group{}, groups, m.GroupID, "group"); err != nil {
+				return fmt.Errorf("meal %q: %w", m.Name, err)
+			}
+		}
+	}
+	for _, e := range de.Entries {
+		if e.MealID == 0 {
+			return fmt.Errorf("entry %d does not have a meal", e.ID)
+		}
+		if err := check(&Meal{}, meals, e.MealID, "meal"); err != nil {
+			return fmt.Errorf("entry %d: %w", e.ID, err)
+		}
+		if err := check(&User{}, users, e.UserID, "user"); err != nil {
+			return fmt.Errorf("entry %d: %w", e.ID, err)
+		}
+	}
+	return nil
+}
+
+// csvSchemas are the cached gorm schemas used for CSV columns.
+var csvSchemas sync.Map
+
+// csvFields returns the fields of the given slice of model pointers that are CSV columns,
+// which are the database columns other than the deleted time.
+func csvFields(records reflect.Value) ([]*schema.Field, error) {
+	sch, err := schema.Parse(reflect.New(records.Type().Elem().Elem()).Interface(), &csvSchemas, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+	fields := []*schema.Field{}
+	for _, f := range sch.Fields {
+		if f.DBName != "" && f.DBName != "deleted_at" {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// writeCSV writes the given pointer to a slice of model pointers to a CSV file at the given path.
+func writeCSV(path string, records any) error {
+	rv := reflect.ValueOf(records).Elem()
+	fields, err := csvFields(rv)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = field.DBName
+	}
+	w.Write(header)
+	for i := range rv.Len() {
+		record := rv.Index(i).Elem()
+		row := make([]string, len(fields))
+		for j, field := range fields {
+			row[j], err = formatCSV(field.ReflectValueOf(context.Background(), record))
+			if err != nil {
+				return fmt.Errorf("row %d, column %s: %w", i+1, field.DBName, err)
+			}
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return errors.Join(w.Error(), f.Close())
+}
+
+// readCSV reads a CSV file at the given path into the given pointer to a slice of model pointers.
+func readCSV(path string, records any) error {
+	rv := reflect.ValueOf(records).Elem()
+	fields, err := csvFields(rv)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	columns := make([]*schema.Field, len(rows[0]))
+	for i, name := range rows[0] {
+		name = strings.TrimSpace(name)
+		for _, field := range fields {
+			if field.DBName == name {
+				columns[i] = field
+			}
+		}
+		if columns[i] == nil {
+			return fmt.Errorf("unknown column %q", name)
+		}
+	}
+	for i, row := range rows[1:] {
+		record := reflect.New(rv.Type().Elem().Elem())
+		for j, value := range row {
+			field := columns[j]
+			err := parseCSV(field.ReflectValueOf(context.Background(), record.Elem()), strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("row %d, column %s: %w", i+1, field.DBName, err)
+			}
+		}
+		rv.Set(reflect.Append(rv, record))
+	}
+	return nil
+}
+
+// formatCSV returns the given value formatted for a CSV file.
+func formatCSV(v reflect.Value) (string, error) {
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return "", nil
+		}
+		return x.Format(time.RFC3339Nano), nil
+	case encoding.TextMarshaler:
+		b, err := x.MarshalText()
+		return string(b), err
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		if v.Len() == 0 {
+			return "", nil
+		}
+		b, err := json.Marshal(v.Interface())
+		return string(b), err
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// csvTimeLayouts are the layouts that times can be in in CSV files,
+// including ones that spreadsheets commonly use.
+var csvTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "1/2/2006 15:04", "1/2/2006"}
+
+// parseCSV sets the given value from the given value in a CSV file.
+func parseCSV(v reflect.Value, s string) error {
+	if s == "" {
+		v.SetZero()
+		return nil
+	}
+	switch x := v.Addr().Interface().(type) {
+	case *time.Time:
+		for _, layout := range csvTimeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				*x = t
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid time %q", s)
+	case enums.BitFlagSetter:
+		// spreadsheet users may separate bit flags with commas
+		s = strings.ReplaceAll(strings.ReplaceAll(s, ", ", "|"), ",", "|")
+		return x.SetString(s)
+	case enums.EnumSetter:
+		return x.SetString(s)
+	case encoding.TextUnmarshaler:
+		return x.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return json.Unmarshal([]byte(s), v.Addr().Interface())
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
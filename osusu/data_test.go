@@ -0,0 +1,307 @@
+package osusu
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testData is the data created by [testSeed].
+type testData struct {
+	family, friends *Group
+	a, b, c         *User
+	tacos, pancakes *Meal
+	soup            *Meal
+}
+
+// testSeed creates a family group with two users, two meals, and three entries,
+// and a friends group with one user, one meal, and one entry.
+func testSeed(t *testing.T) *testData {
+	t.Helper()
+	td := &testData{family: &Group{Name: "Family"}, friends: &Group{Name: "Friends"}}
+	testCreate(t, td.family, td.friends)
+	td.a = &User{GroupID: td.family.ID, Email: "a@example.com", Name: "A", Targets: NutritionTargets{Calories: 2000}}
+	td.b = &User{GroupID: td.family.ID, Email: "b@example.com", Name: "B"}
+	td.c = &User{GroupID: td.friends.ID, Email: "c@example.com", Name: "C"}
+	testCreate(t, td.a, td.b, td.c)
+
+	td.tacos = &Meal{GroupID: td.family.ID, Name: "Tacos", Description: "With \"salsa\",\nand lime", CostPerServing: 2.5,
+		Nutrition: Nutrition{Calories: 600, Protein: 30}}
+	td.tacos.Category.SetFlag(true, Lunch, Dinner)
+	td.tacos.Cuisine.SetFlag(true, Mexican, American)
+	td.tacos.Source.SetFlag(true, Cooking, Takeout)
+	td.pancakes = &Meal{GroupID: td.family.ID, Name: "Pancakes", SourceURL: "https://example.com/pancakes"}
+	td.pancakes.Category.SetFlag(true, Breakfast)
+	td.soup = &Meal{GroupID: td.friends.ID, Name: "Soup"}
+	testCreate(t, td.tacos, td.pancakes, td.soup)
+
+	at := time.Date(2024, 6, 1, 18, 30, 0, 0, time.UTC)
+	testCreate(t,
+		&Entry{MealID: td.tacos.ID, UserID: td.a.ID, Time: at, Taste: 90, Cost: 40, Effort: 60, Healthiness: 50, Servings: 1.5,
+			Ratings: map[string]int{"Spiciness": 4}, Category: td.tacos.Category},
+		&Entry{MealID: td.tacos.ID, UserID: td.b.ID, Time: at, Taste: 70, Servings: 1},
+		&Entry{MealID: td.pancakes.ID, UserID: td.a.ID, Time: at.Add(-24 * time.Hour), Taste: 80, Servings: 2},
+		&Entry{MealID: td.soup.ID, UserID: td.c.ID, Time: at, Taste: 60, Servings: 1},
+	)
+	return td
+}
+
+// testTables returns the JSON of the tables of the given data,
+// without the export version and time, for comparing data.
+func testTables(t *testing.T, de *DataExport) string {
+	t.Helper()
+	b, err := json.MarshalIndent([]any{de.Groups, de.Users, de.Meals, de.Entries}, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// testSaveOpen saves the given data in the given format and opens it again.
+func testSaveOpen(t *testing.T, de *DataExport, format DataFormats) *DataExport {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "osusu.json")
+	if format == DataCSV {
+		path = filepath.Join(t.TempDir(), "osusu")
+	}
+	err := de.Save(path, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := OpenDataExport(path, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// testCount returns the number of records of the given model in the given group.
+func testCount(t *testing.T, model any, groupID uint) int64 {
+	t.Helper()
+	var n int64
+	err := inGroup(DB.Model(model), model, groupID).Count(&n).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDataRoundTrip(t *testing.T) {
+	for _, format := range DataFormatsValues() {
+		t.Run(format.String(), func(t *testing.T) {
+			testDB(t)
+			testSeed(t)
+			de, err := ExportData(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := testTables(t, de)
+			opened := testSaveOpen(t, de, format)
+			if got := testTables(t, opened); got != want {
+				t.Fatalf("expected the opened data to be the same as the saved data:\n%s\nbut got:\n%s", want, got)
+			}
+
+			// importing into an empty database results in the same data
+			testDB(t)
+			res, err := opened.Import(&ImportOptions{Conflicts: ImportSkip})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Created != 12 || res.Updated != 0 || res.Skipped != 0 {
+				t.Errorf("expected 12 created records but got %s", res)
+			}
+			imported, err := ExportData(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := testTables(t, imported); got != want {
+				t.Errorf("expected the imported data to be the same as the exported data:\n%s\nbut got:\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestDataCSVFlags(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	de, err := ExportData(td.family.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	err = de.Save(dir, DataCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "meals.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bit flags are saved as their names so that they can be edited in spreadsheets
+	for _, want := range []string{"Lunch|Dinner", "American|Mexican", "Cooking|Takeout", "Breakfast"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected meals.csv to contain %q:\n%s", want, b)
+		}
+	}
+
+	// and can be separated with commas and spaces by spreadsheet users
+	err = os.WriteFile(filepath.Join(dir, "meals.csv"), []byte("name,category,cuisine\nSalad,\"Lunch, Side\",\"Greek,Italian\"\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := OpenDataExport(dir, DataCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := opened.Meals[0]
+	if !m.Category.HasFlag(Lunch) || !m.Category.HasFlag(Side) || m.Category.HasFlag(Dinner) {
+		t.Errorf("expected the categories Lunch and Side but got %v", m.Category)
+	}
+	if !m.Cuisine.HasFlag(Greek) || !m.Cuisine.HasFlag(Italian) {
+		t.Errorf("expected the cuisines Greek and Italian but got %v", m.Cuisine)
+	}
+}
+
+func TestDataImportConflicts(t *testing.T) {
+	tests := []struct {
+		conflicts ImportConflicts
+		// the expected numbers of meals and entries in the family group,
+		// and the name of the changed meal, after importing
+		meals, entries int64
+		name           string
+		created        int
+		updated        int
+		skipped        int
+	}{
+		{ImportSkip, 2, 3, "Changed", 0, 0, 7},
+		{ImportOverwrite, 2, 3, "Tacos", 0, 7, 0},
+		// users are matched by email, so only the meals and entries are new
+		{ImportNew, 4, 6, "Changed", 5, 0, 2},
+	}
+	for _, format := range DataFormatsValues() {
+		for _, test := range tests {
+			t.Run(format.String()+"/"+test.conflicts.String(), func(t *testing.T) {
+				testDB(t)
+				td := testSeed(t)
+				de, err := ExportData(td.family.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				de = testSaveOpen(t, de, format)
+				err = DB.Model(td.tacos).Update("name", "Changed").Error
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				res, err := de.Import(&ImportOptions{Conflicts: test.conflicts, GroupID: td.family.ID, UserID: td.a.ID})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Created != test.created || res.Updated != test.updated || res.Skipped != test.skipped {
+					t.Errorf("expected %d created, %d updated, and %d skipped but got %s", test.created, test.updated, test.skipped, res)
+				}
+				if n := testCount(t, &Meal{}, td.family.ID); n != test.meals {
+					t.Errorf("expected %d meals but got %d", test.meals, n)
+				}
+				if n := testCount(t, &Entry{}, td.family.ID); n != test.entries {
+					t.Errorf("expected %d entries but got %d", test.entries, n)
+				}
+				if n := testCount(t, &User{}, td.family.ID); n != 2 {
+					t.Errorf("expected 2 users but got %d", n)
+				}
+				meal := &Meal{}
+				err = DB.First(meal, td.tacos.ID).Error
+				if err != nil {
+					t.Fatal(err)
+				}
+				if meal.Name != test.name {
+					t.Errorf("expected the existing meal to be named %q but got %q", test.name, meal.Name)
+				}
+				if n := testCount(t, &Meal{}, td.friends.ID); n != 1 {
+					t.Errorf("expected the other group to still have 1 meal but got %d", n)
+				}
+			})
+		}
+	}
+}
+
+func TestDataImportOtherGroup(t *testing.T) {
+	// importing the data of one group into another with the same IDs
+	// never changes the records of the first group
+	for _, conflicts := range ImportConflictsValues() {
+		t.Run(conflicts.String(), func(t *testing.T) {
+			testDB(t)
+			td := testSeed(t)
+			de, err := ExportData(td.family.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			de = testSaveOpen(t, de, DataJSON)
+			err = DB.Model(td.tacos).Update("name", "Changed").Error
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = de.Import(&ImportOptions{Conflicts: conflicts, GroupID: td.friends.ID, UserID: td.c.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := testCount(t, &Meal{}, td.family.ID); n != 2 {
+				t.Errorf("expected the family group to still have 2 meals but got %d", n)
+			}
+			if n := testCount(t, &Entry{}, td.family.ID); n != 3 {
+				t.Errorf("expected the family group to still have 3 entries but got %d", n)
+			}
+			if n := testCount(t, &User{}, td.family.ID); n != 2 {
+				t.Errorf("expected the family group to still have 2 users but got %d", n)
+			}
+			meal := &Meal{}
+			err = DB.First(meal, td.tacos.ID).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meal.Name != "Changed" || meal.GroupID != td.family.ID {
+				t.Errorf("expected the meal of the family group to be unchanged but got %q in group %d", meal.Name, meal.GroupID)
+			}
+
+			if n := testCount(t, &Meal{}, td.friends.ID); n != 3 {
+				t.Errorf("expected the friends group to have 3 meals but got %d", n)
+			}
+			if n := testCount(t, &Entry{}, td.friends.ID); n != 4 {
+				t.Errorf("expected the friends group to have 4 entries but got %d", n)
+			}
+			// the users of the other group keep their accounts, so the imported
+			// users are created without their emails
+			var users []*User
+			err = DB.Find(&users, "group_id = ?", td.friends.ID).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 3 {
+				t.Fatalf("expected the friends group to have 3 users but got %d", len(users))
+			}
+			for _, u := range users[1:] {
+				if u.Email != "" {
+					t.Errorf("expected imported user %s to not have an email but got %q", u.Name, u.Email)
+				}
+			}
+		})
+	}
+}
+
+func TestDataImportReferences(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	// entries can not refer to meals of other groups
+	de := &DataExport{Version: DataVersion, Entries: []*Entry{{MealID: td.soup.ID, UserID: td.a.ID}}}
+	_, err := de.Import(&ImportOptions{Conflicts: ImportNew, GroupID: td.family.ID, UserID: td.a.ID})
+	if err == nil || !strings.Contains(err.Error(), "does not exist in the group") {
+		t.Errorf("expected an error for an entry for a meal of another group but got %v", err)
+	}
+	if n := testCount(t, &Entry{}, td.friends.ID); n != 1 {
+		t.Errorf("expected nothing to be imported but the friends group has %d entries", n)
+	}
+}
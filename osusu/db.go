@@ -8,6 +8,9 @@ import (
 // DB is the gorm database for the app.
 var DB *gorm.DB
 
+// Models are all of the database models, in the order they are migrated.
+var Models = []any{&User{}, &Group{}, &Meal{}, &Entry{}, &IngredientPrice{}, &Dimension{}, &Choice{}, &Preset{}, &Dismissal{}, &Session{}, &Identity{}, &AuditRecord{}}
+
 // OpenDB opens and sets up the database.
 func OpenDB() error {
 	db, err := gorm.Open(rqlite.Open("http://"))
@@ -15,5 +18,48 @@ func OpenDB() error {
 		return err
 	}
 	DB = db
-	return db.AutoMigrate(&User{}, &Group{}, &Meal{}, &Entry{})
+	return migrate(db)
+}
+
+// migrate migrates the given database to the current models
+// and backfills the values of new columns.
+func migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(Models...)
+	if err != nil {
+		return err
+	}
+	err = backfillMealGroups(db)
+	if err != nil {
+		return err
+	}
+	return backfillMealCreators(db)
+}
+
+// backfillMealGroups sets the group of meals from before meals had groups to
+// the current group of the user with the first entry for the meal, so that the
+// meals are only available to that group. Meals without entries from users in
+// groups can not be assigned to a group, so they are not available to any group.
+func backfillMealGroups(db *gorm.DB) error {
+	group := db.Table("entries").
+		Select("users.group_id").
+		Joins("JOIN users ON users.id = entries.user_id").
+		Where("entries.meal_id = meals.id AND users.group_id <> 0").
+		Order("entries.created_at").Limit(1)
+	return db.Model(&Meal{}).Unscoped().
+		Where("group_id = 0 AND EXISTS (?)", group).
+		UpdateColumn("group_id", group).Error
+}
+
+// backfillMealCreators sets the creator of meals from before meals had creators
+// to the actor of their creation in the audit log. Meals created before the audit
+// log do not have a known creator, so only the owner of their group can purge them
+// when they have entries of other members.
+func backfillMealCreators(db *gorm.DB) error {
+	creator := db.Model(&AuditRecord{}).
+		Select("actor_id").
+		Where("record_table = ? AND record_id = meals.id AND action = ? AND actor_id <> 0", "meals", AuditCreate).
+		Order("id").Limit(1)
+	return db.Model(&Meal{}).Unscoped().
+		Where("creator_id = 0 AND EXISTS (?)", creator).
+		UpdateColumn("creator_id", creator).Error
 }
@@ -0,0 +1,82 @@
+package osusu
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB sets [DB] to a new migrated SQLite database in a temporary directory
+// for the rest of the given test. rqlite uses SQLite, so the SQL is the same,
+// but transactions can be rolled back, which they can not be with rqlite.
+func testDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "osusu.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = old
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	err = migrate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testCreate creates the given records in the test database.
+func testCreate(t *testing.T, records ...any) {
+	t.Helper()
+	for _, r := range records {
+		err := DB.Create(r).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	testDB(t)
+	g := &Group{Name: "Family"}
+	testCreate(t, g)
+	u := &User{Name: "A", Email: "a@example.com", GroupID: g.ID}
+	testCreate(t, u)
+	ctx := WithActor(context.Background(), u.ID)
+	// a meal from before meals had groups and creators, which are backfilled
+	// from the users of its entries and the actor of its creation in the audit log
+	m := &Meal{Name: "Tacos"}
+	err := DB.WithContext(ctx).Create(m).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DB.Model(m).UpdateColumns(map[string]any{"group_id": 0, "creator_id": 0}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCreate(t, &Entry{MealID: m.ID, UserID: u.ID})
+
+	err = migrate(DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Meal{}
+	err = DB.First(got, m.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GroupID != g.ID {
+		t.Errorf("expected the meal to be backfilled into group %d but got %d", g.ID, got.GroupID)
+	}
+	if got.CreatorID != u.ID {
+		t.Errorf("expected the creator of the meal to be backfilled to %d but got %d", u.ID, got.CreatorID)
+	}
+}
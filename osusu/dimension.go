@@ -0,0 +1,322 @@
+package osusu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Dimension is a dimension that meals are rated and scored on. The built-in
+// dimensions are Taste, Recency, Cost, Effort, and Healthiness, and groups
+// can define custom dimensions (eg: kid-friendly, spiciness).
+type Dimension struct {
+	gorm.Model `display:"-"`
+	// GroupID is the group that defined the dimension, or 0 for built-in dimensions
+	GroupID uint `display:"-"`
+	// Name is the name of the dimension, which must be unique
+	Name string
+	// Label is the short label for the dimension shown with scores
+	Label string
+	// HigherIsBetter is whether a higher rating is better, such as for taste but not cost
+	HigherIsBetter bool `def:"true"`
+	// DefaultImportance is the importance of the dimension when it is not set in the options
+	DefaultImportance int `display:"slider" min:"0" def:"50" max:"100"`
+	// Min is the minimum rating value
+	Min int
+	// Max is the maximum rating value
+	Max int `def:"100"`
+	// Computed is whether the dimension is computed instead of rated in entries
+	Computed bool `display:"-" gorm:"-"`
+}
+
+// The names of the built-in dimensions.
+const (
+	Taste       = "Taste"
+	Recency     = "Recency"
+	Cost        = "Cost"
+	Effort      = "Effort"
+	Healthiness = "Healthiness"
+)
+
+// BuiltinDimensions are the built-in dimensions, which are always
+// at the start of the dimensions returned by [LoadDimensions].
+var BuiltinDimensions = []*Dimension{
+	{Name: Taste, Label: "Taste", HigherIsBetter: true, DefaultImportance: 50, Max: 100},
+	{Name: Recency, Label: "New", HigherIsBetter: true, DefaultImportance: 50, Max: 100, Computed: true},
+	{Name: Cost, Label: "Cost", HigherIsBetter: false, DefaultImportance: 50, Max: 100},
+	{Name: Effort, Label: "Effort", HigherIsBetter: false, DefaultImportance: 50, Max: 100},
+	{Name: Healthiness, Label: "Health", HigherIsBetter: true, DefaultImportance: 50, Max: 100},
+}
+
+// LoadDimensions returns all of the dimensions of the group with the given ID,
+// which are the built-in dimensions followed by the custom dimensions of the group.
+// The dimensions are passed to everything that scores meals for the group.
+func LoadDimensions(groupID uint) ([]*Dimension, error) {
+	var custom []*Dimension
+	err := DB.Find(&custom, "group_id = ?", groupID).Error
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]*Dimension{}, BuiltinDimensions...), custom...), nil
+}
+
+// SaveDimensions saves the given custom dimensions as all of the custom dimensions
+// of the group with the given ID, deleting any others. Ratings and importances are
+// keyed by dimension name, so the ratings of the entries of the group and the
+// importances in the presets of its members are moved to the new names of any
+// renamed dimensions.
+func SaveDimensions(ctx context.Context, groupID uint, dims []*Dimension) error {
+	for _, d := range dims {
+		err := d.Validate(dims)
+		if err != nil {
+			return err
+		}
+		if d.Label == "" {
+			d.Label = d.Name
+		}
+	}
+	return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old []*Dimension
+		err := tx.Find(&old, "group_id = ?", groupID).Error
+		if err != nil {
+			return err
+		}
+		oldByID := map[uint]*Dimension{}
+		for _, o := range old {
+			oldByID[o.ID] = o
+		}
+		renames := map[string]string{}
+		for _, d := range dims {
+			d.GroupID = groupID
+			if d.ID == 0 {
+				continue
+			}
+			o, ok := oldByID[d.ID]
+			if !ok {
+				return fmt.Errorf("dimension %q is not a dimension of the group", d.Name)
+			}
+			if d.Name != o.Name {
+				renames[o.Name] = d.Name
+			}
+			delete(oldByID, d.ID)
+		}
+		// any remaining old dimensions were removed
+		for _, o := range oldByID {
+			err := tx.Delete(o).Error
+			if err != nil {
+				return err
+			}
+		}
+		err = renameDimensions(tx, groupID, renames)
+		if err != nil {
+			return err
+		}
+		if len(dims) == 0 {
+			return nil
+		}
+		return tx.Save(&dims).Error
+	})
+}
+
+// renameDimensions moves the ratings of the entries of the group with the given
+// ID and the importances in the presets of its members from the old names of
+// dimensions to their new names, given as a map from old to new names. The
+// names are all changed at once, so dimensions can swap names.
+func renameDimensions(tx *gorm.DB, groupID uint, renames map[string]string) error {
+	if len(renames) == 0 {
+		return nil
+	}
+	rename := func(m map[string]int) (map[string]int, bool) {
+		changed := false
+		res := make(map[string]int, len(m))
+		for k, v := range m {
+			if n, ok := renames[k]; ok {
+				k = n
+				changed = true
+			}
+			res[k] = v
+		}
+		return res, changed
+	}
+
+	var entries []*Entry
+	err := tx.Find(&entries, "meal_id IN (?)", tx.Model(&Meal{}).Select("id").Where("group_id = ?", groupID)).Error
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ratings, changed := rename(entry.Ratings)
+		if !changed {
+			continue
+		}
+		entry.Ratings = ratings
+		err := tx.Save(entry).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	var presets []*Preset
+	err = tx.Find(&presets, "user_id IN (?)", tx.Model(&User{}).Select("id").Where("group_id = ?", groupID)).Error
+	if err != nil {
+		return err
+	}
+	for _, preset := range presets {
+		importances, changed := rename(preset.Options.Importances)
+		if !changed {
+			continue
+		}
+		preset.Options.Importances = importances
+		err := tx.Save(preset).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsBuiltin returns whether the dimension is a built-in dimension.
+func (d *Dimension) IsBuiltin() bool {
+	for _, b := range BuiltinDimensions {
+		if b.Name == d.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the custom dimension is not valid
+// given the other custom dimensions of its group.
+func (d *Dimension) Validate(others []*Dimension) error {
+	if strings.TrimSpace(d.Name) == "" {
+		return fmt.Errorf("dimension name must not be blank")
+	}
+	if d.IsBuiltin() {
+		return fmt.Errorf("dimension name %q is already used by a built-in dimension", d.Name)
+	}
+	for _, o := range others {
+		if o != d && o.Name == d.Name {
+			return fmt.Errorf("dimension name %q is used more than once", d.Name)
+		}
+	}
+	if d.Max <= d.Min {
+		return fmt.Errorf("dimension %q must have a maximum greater than its minimum", d.Name)
+	}
+	return nil
+}
+
+// Normalize returns the score value from 0 to 100 for the given rating
+// on the dimension, where higher scores are always better.
+func (d *Dimension) Normalize(rating int) float64 {
+	if d.Max <= d.Min {
+		return 0
+	}
+	v := 100 * float64(rating-d.Min) / float64(d.Max-d.Min)
+	if !d.HigherIsBetter {
+		v = 100 - v
+	}
+	return v
+}
+
+// DefaultRating returns the default rating for the dimension in new entries,
+// which is the middle of its range.
+func (d *Dimension) DefaultRating() int {
+	return (d.Min + d.Max) / 2
+}
+
+// Value returns the value of the score on the given dimension,
+// and whether the score has a value for it.
+func (s *Score) Value(d *Dimension) (float64, bool) {
+	switch d.Name {
+	case Taste:
+		return s.Taste, true
+	case Recency:
+		return s.Recency, true
+	case Cost:
+		return s.Cost, true
+	case Effort:
+		return s.Effort, true
+	case Healthiness:
+		return s.Healthiness, true
+	}
+	v, ok := s.Custom[d.Name]
+	return v, ok
+}
+
+// SetValue sets the value of the score on the given dimension.
+func (s *Score) SetValue(d *Dimension, v float64) {
+	switch d.Name {
+	case Taste:
+		s.Taste = v
+	case Recency:
+		s.Recency = v
+	case Cost:
+		s.Cost = v
+	case Effort:
+		s.Effort = v
+	case Healthiness:
+		s.Healthiness = v
+	default:
+		if s.Custom == nil {
+			s.Custom = map[string]float64{}
+		}
+		s.Custom[d.Name] = v
+	}
+}
+
+// Importance returns the importance of the given dimension in the options.
+func (o *Options) Importance(d *Dimension) int {
+	switch d.Name {
+	case Taste:
+		return o.TasteImportance
+	case Recency:
+		return o.RecencyImportance
+	case Cost:
+		return o.CostImportance
+	case Effort:
+		return o.EffortImportance
+	case Healthiness:
+		return o.HealthinessImportance
+	}
+	if imp, ok := o.Importances[d.Name]; ok {
+		return imp
+	}
+	return d.DefaultImportance
+}
+
+// SetImportance sets the importance of the given dimension in the options.
+func (o *Options) SetImportance(d *Dimension, importance int) {
+	switch d.Name {
+	case Taste:
+		o.TasteImportance = importance
+	case Recency:
+		o.RecencyImportance = importance
+	case Cost:
+		o.CostImportance = importance
+	case Effort:
+		o.EffortImportance = importance
+	case Healthiness:
+		o.HealthinessImportance = importance
+	default:
+		if o.Importances == nil {
+			o.Importances = map[string]int{}
+		}
+		o.Importances[d.Name] = importance
+	}
+}
+
+// AddDimensionImportances adds the default importances of any of
+// the given custom dimensions that are not already in the options,
+// so that they can be edited.
+func (o *Options) AddDimensionImportances(dims []*Dimension) {
+	for _, d := range dims {
+		if d.IsBuiltin() {
+			continue
+		}
+		if _, ok := o.Importances[d.Name]; !ok {
+			o.SetImportance(d, d.DefaultImportance)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package osusu
+
+import "gorm.io/gorm"
+
+// Dismissal is a record of a user saying that they are not
+// interested in a recipe, which hides it from Discover.
+type Dismissal struct {
+	gorm.Model `display:"-"`
+	UserID     uint `display:"-"`
+	GroupID    uint `display:"-"`
+	// URL is the URL of the dismissed recipe
+	URL    string `display:"-"`
+	Reason DismissReasons
+	// ForGroup is whether to hide the recipe for the whole group instead of just the user
+	ForGroup bool `label:"Hide for whole group"`
+	// Merged is whether the recipe was dismissed because its meal was merged
+	// into a duplicate (see [MergeMeals]), in which case it is only hidden
+	// from Discover and does not make similar recipes score lower
+	Merged bool `display:"-"`
+}
+
+// DismissReasons are the reasons that a user can dismiss a recipe.
+type DismissReasons int32 //enums:enum
+
+const (
+	DontLike DismissReasons = iota // Don't like
+	TooHard                        // Too hard
+	Allergic
+	OtherReason // Other
+)
+
+// dismissalPenalties are how much the total score of a recipe is reduced
+// for each reason when it is identical to a dismissed recipe.
+var dismissalPenalties = map[DismissReasons]float64{
+	DontLike:    30,
+	TooHard:     15,
+	Allergic:    50,
+	OtherReason: 10,
+}
+
+// LoadDismissals returns the dismissals of the given user and
+// the dismissals for the whole group of the given group.
+func LoadDismissals(userID, groupID uint) ([]Dismissal, error) {
+	var dismissals []Dismissal
+	err := DB.Find(&dismissals, "user_id = ? OR (group_id = ? AND for_group = ?)", userID, groupID, true).Error
+	return dismissals, err
+}
+
+// ExcludeRecipes returns the given recipes without the ones that have
+// already been added as one of the given meals or have been dismissed.
+func ExcludeRecipes(recipes []*Recipe, meals []*Meal, dismissals []Dismissal) []*Recipe {
+	exclude := map[string]bool{}
+	for _, meal := range meals {
+		if meal.SourceURL != "" {
+			exclude[meal.SourceURL] = true
+		}
+	}
+	for _, d := range dismissals {
+		exclude[d.URL] = true
+	}
+	res := []*Recipe{}
+	for _, recipe := range recipes {
+		if !exclude[recipe.URL] {
+			res = append(res, recipe)
+		}
+	}
+	return res
+}
+
+// ApplyDismissals reduces the total scores of the given recipes based on how
+// similar they are to the given dismissed recipes, so that dismissals also act
+// as a negative signal for similar recipes. The similarity is the cosine similarity
+// of the text encoding vectors, which are keyed by recipe URL. The recipes should
+// already have their total scores computed.
+func ApplyDismissals(recipes []*Recipe, vectors map[string][]float32, dismissals []Dismissal) {
+	for _, recipe := range recipes {
+		rv := vectors[recipe.URL]
+		rn := vectorNorm(rv)
+		if rn == 0 {
+			continue
+		}
+		penalty := 0.0
+		for _, d := range dismissals {
+			if d.Merged {
+				continue
+			}
+			dv := vectors[d.URL]
+			dn := vectorNorm(dv)
+			if dn == 0 {
+				continue
+			}
+			sim := vectorDot(rv, dv) / (rn * dn)
+			if sim > 0 {
+				penalty = max(penalty, sim*dismissalPenalties[d.Reason])
+			}
+		}
+		recipe.Score.Total -= penalty
+	}
+}
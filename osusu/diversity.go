@@ -0,0 +1,92 @@
+package osusu
+
+import (
+	"cmp"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// Rerank returns the first n of the given recipes reordered by maximal marginal
+// relevance, so that the results explore different directions instead of all
+// being similar to each other. Each next recipe is the one that maximizes
+//
+//	(1 - diversity) * relevance + diversity * (1 - maxSimilarity + novelty) / 2
+//
+// where the diversity is [Options.Diversity] from 0 to 1, the relevance is the
+// total score of the recipe from 0 to 1, the max similarity is the highest cosine
+// similarity between the text encoding vector of the recipe and those of the
+// recipes already selected, and the novelty is the fraction of the categories
+// and cuisines of the recipe that are not yet covered by the recipes already
+// selected. The text encoding vectors are keyed by recipe URL. The recipes should
+// already have their total scores computed.
+func Rerank(recipes []*Recipe, vectors map[string][]float32, diversity float64, n int) []*Recipe {
+	candidates := slices.Clone(recipes)
+	slices.SortFunc(candidates, func(a, b *Recipe) int {
+		return cmp.Compare(b.Score.Total, a.Score.Total)
+	})
+	n = min(n, len(candidates))
+	if diversity <= 0 {
+		return candidates[:n]
+	}
+	// only consider the most relevant recipes for efficiency
+	candidates = candidates[:min(len(candidates), 10*n)]
+
+	norms := make([]float64, len(candidates))
+	for i, c := range candidates {
+		norms[i] = vectorNorm(vectors[c.URL])
+	}
+	// maxSims are the max similarities of each candidate to the selected recipes
+	maxSims := make([]float64, len(candidates))
+	selected := make([]bool, len(candidates))
+	var categories Categories
+	var cuisines Cuisines
+
+	res := make([]*Recipe, 0, n)
+	for len(res) < n {
+		best, bestValue := -1, math.Inf(-1)
+		for i, c := range candidates {
+			if selected[i] {
+				continue
+			}
+			flags := bits.OnesCount64(uint64(c.CategoryFlag)) + bits.OnesCount64(uint64(c.CuisineFlag))
+			novelty := 0.0
+			if flags > 0 {
+				newFlags := bits.OnesCount64(uint64(c.CategoryFlag&^categories)) + bits.OnesCount64(uint64(c.CuisineFlag&^cuisines))
+				novelty = float64(newFlags) / float64(flags)
+			}
+			value := (1-diversity)*c.Score.Total/100 + diversity*(1-maxSims[i]+novelty)/2
+			if value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		selected[best] = true
+		b := candidates[best]
+		res = append(res, b)
+		categories |= b.CategoryFlag
+		cuisines |= b.CuisineFlag
+		bv := vectors[b.URL]
+		for i, c := range candidates {
+			if selected[i] || norms[i] == 0 || norms[best] == 0 {
+				continue
+			}
+			sim := vectorDot(vectors[c.URL], bv) / (norms[i] * norms[best])
+			maxSims[i] = max(maxSims[i], sim)
+		}
+	}
+	return res
+}
+
+// vectorDot returns the dot product of the given vectors.
+func vectorDot(a, b []float32) float64 {
+	res := 0.0
+	for i := range min(len(a), len(b)) {
+		res += float64(a[i]) * float64(b[i])
+	}
+	return res
+}
+
+// vectorNorm returns the Euclidean norm of the given vector.
+func vectorNorm(v []float32) float64 {
+	return math.Sqrt(vectorDot(v, v))
+}
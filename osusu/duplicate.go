@@ -0,0 +1,300 @@
+package osusu
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// DuplicateConfig is the configuration of how [FindDuplicates] decides whether
+// meals are duplicates of each other.
+type DuplicateConfig struct {
+	// MinSimilarity is the minimum similarity from 0 to 1 for two meals
+	// to be considered duplicates
+	MinSimilarity float64
+	// NameWeight is the weight from 0 to 1 of the similarity of the names of meals,
+	// with the rest of the weight going to the similarity of their text encoding
+	// vectors; only the names are used for meals without vectors
+	NameWeight float64
+}
+
+// DefaultDuplicateConfig returns the default [DuplicateConfig], in which names and
+// text encodings are equally important.
+func DefaultDuplicateConfig() *DuplicateConfig {
+	return &DuplicateConfig{
+		MinSimilarity: 0.8,
+		NameWeight:    0.5,
+	}
+}
+
+// Duplicates are meals that are likely to be the same meal.
+type Duplicates struct {
+	// Meals are the duplicate meals, from the oldest to the newest
+	Meals []*Meal
+	// Similarity is the lowest similarity between any two
+	// of the meals, from 0 to 1
+	Similarity float64
+}
+
+// nameWords returns the lowercase words in the given meal name without
+// punctuation and plural endings, so that "Tacos (homemade)" is "taco homemade".
+func nameWords(name string) []string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			words[i] = strings.TrimSuffix(w, "s")
+		}
+	}
+	return words
+}
+
+// bigrams returns the set of pairs of adjacent characters in the given string.
+func bigrams(s string) map[string]bool {
+	res := map[string]bool{}
+	rs := []rune(s)
+	for i := range len(rs) - 1 {
+		res[string(rs[i:i+2])] = true
+	}
+	return res
+}
+
+// NameSimilarity returns how similar the given meal names are, from 0 to 1.
+// It is the greater of how many of the words of the shorter name are in the
+// other name, which matches names with extra words like "Taco night" and
+// "Tacos", and how many pairs of characters the names share, which matches
+// names with different spellings.
+func NameSimilarity(a, b string) float64 {
+	wa, wb := nameWords(a), nameWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	shared := 0
+	for _, w := range wa {
+		if slices.Contains(wb, w) {
+			shared++
+		}
+	}
+	words := float64(shared) / float64(min(len(wa), len(wb)))
+
+	ba, bb := bigrams(strings.Join(wa, " ")), bigrams(strings.Join(wb, " "))
+	if len(ba)+len(bb) == 0 {
+		return words
+	}
+	sharedBigrams := 0
+	for bg := range ba {
+		if bb[bg] {
+			sharedBigrams++
+		}
+	}
+	dice := 2 * float64(sharedBigrams) / float64(len(ba)+len(bb))
+	return max(words, dice)
+}
+
+// MealSimilarity returns how similar the given meals are, from 0 to 1, based on
+// their names and the cosine similarity of their text encoding vectors keyed by
+// meal ID (see [Meal.Text]), which are combined using the given config.
+func MealSimilarity(a, b *Meal, vectors map[uint][]float32, cfg *DuplicateConfig) float64 {
+	name := NameSimilarity(a.Name, b.Name)
+	va, oka := vectors[a.ID]
+	vb, okb := vectors[b.ID]
+	if !oka || !okb {
+		return name
+	}
+	norm := vectorNorm(va) * vectorNorm(vb)
+	if norm == 0 {
+		return name
+	}
+	encoding := max(vectorDot(va, vb)/norm, 0)
+	return cfg.NameWeight*name + (1-cfg.NameWeight)*encoding
+}
+
+// FindDuplicates returns the groups of duplicates in the given meals, from the most
+// similar to the least, using the given text encoding vectors of the meals keyed by
+// meal ID and the given config. Meals are duplicates if every meal in the group is
+// at least [DuplicateConfig.MinSimilarity] similar to every other meal in it, so
+// that a chain of similar meals does not join dissimilar meals together.
+func FindDuplicates(meals []*Meal, vectors map[uint][]float32, cfg *DuplicateConfig) []*Duplicates {
+	// sims are the similarities between the sets of duplicates, which start as the
+	// similarities between the meals; meals in different groups are never duplicates
+	n := len(meals)
+	sims := make([][]float64, n)
+	for i, a := range meals {
+		sims[i] = make([]float64, n)
+		for j := range i {
+			b := meals[j]
+			if a.GroupID == b.GroupID {
+				sims[i][j] = MealSimilarity(a, b, vectors, cfg)
+			} else {
+				sims[i][j] = -1
+			}
+			sims[j][i] = sims[i][j]
+		}
+	}
+	sets := make([]*Duplicates, n)
+	for i, meal := range meals {
+		sets[i] = &Duplicates{Meals: []*Meal{meal}, Similarity: 1}
+	}
+	// the most similar sets are joined until no sets are similar enough,
+	// where the similarity of two sets is that of their least similar meals
+	for {
+		bi, bj, best := -1, -1, cfg.MinSimilarity
+		for i := range n {
+			if sets[i] == nil {
+				continue
+			}
+			for j := range i {
+				if sets[j] != nil && sims[i][j] >= best {
+					bi, bj, best = i, j, sims[i][j]
+				}
+			}
+		}
+		if bi < 0 {
+			break
+		}
+		sa, sb := sets[bj], sets[bi]
+		sa.Meals = append(sa.Meals, sb.Meals...)
+		sa.Similarity = min(sa.Similarity, sb.Similarity, best)
+		sets[bi] = nil
+		for k := range n {
+			sims[bj][k] = min(sims[bj][k], sims[bi][k])
+			sims[k][bj] = sims[bj][k]
+		}
+	}
+
+	var res []*Duplicates
+	for _, d := range sets {
+		if d == nil || len(d.Meals) < 2 {
+			continue
+		}
+		slices.SortFunc(d.Meals, func(a, b *Meal) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+		res = append(res, d)
+	}
+	slices.SortStableFunc(res, func(a, b *Duplicates) int {
+		return cmp.Compare(b.Similarity, a.Similarity)
+	})
+	return res
+}
+
+// ErrMergeGroups is returned by [MergeMeals] for meals in different groups.
+var ErrMergeGroups = errors.New("only meals in the same group can be merged")
+
+// MergeMeals merges the given duplicate meals into the given meal, which is kept.
+// The entries of the duplicates are moved to the meal, their descriptions are
+// added to its description, their categories, sources, and cuisines are added to
+// its flags, and any of its other fields that are empty are set from them. The
+// source URLs of the duplicates that are not kept are dismissed for the group
+// so that their recipes do not appear in Discover again. The duplicates are then
+// moved to the trash (see [Meal.Delete]), from which they can be restored without
+// their entries, which stay with the meal. The context is used for the database,
+// so it should have the actor of the change (see [WithActor]).
+//
+// The database does not support transactions, so the changes are made in an
+// order in which merging the same meals again after an error finishes the merge:
+// merging the fields of a meal more than once does not change them again, and
+// each duplicate is only deleted once everything else for it is done.
+func MergeMeals(ctx context.Context, meal *Meal, duplicates []*Meal) error {
+	for _, dup := range duplicates {
+		if dup.GroupID != meal.GroupID {
+			return ErrMergeGroups
+		}
+	}
+	duplicates = slices.DeleteFunc(slices.Clone(duplicates), func(dup *Meal) bool {
+		return dup.ID == meal.ID
+	})
+	if len(duplicates) == 0 {
+		return nil
+	}
+	for _, dup := range duplicates {
+		meal.merge(dup)
+	}
+	db := DB.WithContext(ctx)
+	err := db.Save(meal).Error
+	if err != nil {
+		return err
+	}
+	for _, dup := range duplicates {
+		err := dismissMerged(db, meal, dup)
+		if err != nil {
+			return err
+		}
+		var entries []*Entry
+		err = db.Unscoped().Find(&entries, "meal_id = ?", dup.ID).Error
+		if err != nil {
+			return err
+		}
+		// the entries are moved one at a time so that each move is in the audit log
+		for _, entry := range entries {
+			err := db.Unscoped().Model(entry).Update("meal_id", meal.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		// the history of the entries moves with them
+		err = db.Model(&AuditRecord{}).Where("meal_id = ? AND record_table = ?", dup.ID, "entries").Update("meal_id", meal.ID).Error
+		if err != nil {
+			return err
+		}
+		err = dup.Delete(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dismissMerged dismisses the source URL of the given duplicate meal for
+// the group if it is not the source URL of the given meal that it was merged
+// into and it has not already been dismissed for the group.
+func dismissMerged(tx *gorm.DB, meal, dup *Meal) error {
+	if dup.SourceURL == "" || dup.SourceURL == meal.SourceURL {
+		return nil
+	}
+	var n int64
+	err := tx.Model(&Dismissal{}).Where("group_id = ? AND url = ? AND for_group = ?", meal.GroupID, dup.SourceURL, true).Count(&n).Error
+	if err != nil || n > 0 {
+		return err
+	}
+	return tx.Create(&Dismissal{
+		UserID:   ActorFrom(tx.Statement.Context),
+		GroupID:  meal.GroupID,
+		URL:      dup.SourceURL,
+		ForGroup: true,
+		Merged:   true,
+	}).Error
+}
+
+// merge merges the fields of the given duplicate meal into the meal.
+func (m *Meal) merge(dup *Meal) {
+	desc := strings.TrimSpace(dup.Description)
+	switch {
+	case desc == "" || strings.Contains(m.Description, desc):
+	case strings.TrimSpace(m.Description) == "":
+		m.Description = desc
+	default:
+		m.Description += "\n\n" + desc
+	}
+	m.Source |= dup.Source
+	m.Category |= dup.Category
+	m.Cuisine |= dup.Cuisine
+	if m.Image == "" {
+		m.Image = dup.Image
+	}
+	if m.SourceURL == "" {
+		m.SourceURL = dup.SourceURL
+	}
+	if m.Nutrition == (Nutrition{}) {
+		m.Nutrition = dup.Nutrition
+	}
+	if m.CostPerServing == 0 {
+		m.CostPerServing = dup.CostPerServing
+	}
+}
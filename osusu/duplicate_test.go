@@ -0,0 +1,114 @@
+package osusu
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// testMeal returns a meal with the given ID and name in the given group.
+func testMeal(id, groupID uint, name string) *Meal {
+	m := &Meal{Name: name, GroupID: groupID}
+	m.ID = id
+	return m
+}
+
+// testVector returns a unit vector at the given angle in degrees.
+func testVector(deg float64) []float32 {
+	rad := deg * math.Pi / 180
+	return []float32{float32(math.Cos(rad)), float32(math.Sin(rad))}
+}
+
+func TestFindDuplicatesCompleteLink(t *testing.T) {
+	// only the vectors are used, so a is similar to b and b to c, but a is not similar to c
+	cfg := &DuplicateConfig{MinSimilarity: 0.8, NameWeight: 0}
+	a, b, c := testMeal(1, 1, "Pancakes"), testMeal(2, 1, "Waffles"), testMeal(3, 1, "Crepes")
+	vectors := map[uint][]float32{1: testVector(0), 2: testVector(25), 3: testVector(55)}
+	dups := FindDuplicates([]*Meal{a, b, c}, vectors, cfg)
+	if len(dups) != 1 || len(dups[0].Meals) != 2 {
+		t.Fatalf("expected one pair of duplicates but got %v", dups)
+	}
+	// b is more similar to a than to c
+	if dups[0].Meals[0] != a || dups[0].Meals[1] != b {
+		t.Errorf("expected %s and %s to be duplicates but got %s and %s", a.Name, b.Name, dups[0].Meals[0].Name, dups[0].Meals[1].Name)
+	}
+	want := math.Cos(25 * math.Pi / 180)
+	if math.Abs(dups[0].Similarity-want) > 1e-6 {
+		t.Errorf("expected a similarity of %v but got %v", want, dups[0].Similarity)
+	}
+
+	// meals that are all similar to each other are one set of duplicates
+	vectors[3] = testVector(10)
+	dups = FindDuplicates([]*Meal{a, b, c}, vectors, cfg)
+	if len(dups) != 1 || len(dups[0].Meals) != 3 {
+		t.Fatalf("expected three duplicates but got %v", dups)
+	}
+
+	// but not if they are in different groups
+	c.GroupID = 2
+	dups = FindDuplicates([]*Meal{a, b, c}, vectors, cfg)
+	if len(dups) != 1 || len(dups[0].Meals) != 2 || dups[0].Meals[1] == c {
+		t.Errorf("expected only the meals in the same group to be duplicates but got %v", dups)
+	}
+}
+
+func TestMergeMeals(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	ctx := WithActor(context.Background(), td.a.ID)
+	dup := &Meal{GroupID: td.family.ID, Name: "Taco night", Description: "Crunchy shells", SourceURL: "https://example.com/tacos", Image: "tacos.jpg"}
+	dup.Category.SetFlag(true, Snack)
+	err := DB.WithContext(WithActor(context.Background(), td.b.ID)).Create(dup).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCreate(t, &Entry{MealID: dup.ID, UserID: td.b.ID, Taste: 100})
+	td.tacos.SourceURL = "https://example.com/al-pastor"
+
+	err = MergeMeals(ctx, td.tacos, []*Meal{dup, td.tacos})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// merging again, like after an error, does not change anything again
+	err = MergeMeals(ctx, td.tacos, []*Meal{dup})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meal := &Meal{}
+	err = DB.First(meal, td.tacos.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meal.Description != "With \"salsa\",\nand lime\n\nCrunchy shells" || meal.Image != "tacos.jpg" || !meal.Category.HasFlag(Snack) {
+		t.Errorf("expected the fields of the duplicate to be merged but got %q, %q, and %v", meal.Description, meal.Image, meal.Category)
+	}
+	if n := testLiveEntries(t, td.tacos.ID); n != 3 {
+		t.Errorf("expected the meal to have its 2 entries and the one of the duplicate but got %d", n)
+	}
+	var dismissals []*Dismissal
+	err = DB.Find(&dismissals, "group_id = ?", td.family.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dismissals) != 1 || dismissals[0].URL != dup.SourceURL || !dismissals[0].Merged {
+		t.Errorf("expected the source URL of the duplicate to be dismissed once but got %v", dismissals)
+	}
+
+	// the duplicate is in the trash, from which it can be restored
+	trash, err := LoadTrash(td.a.ID, td.family.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash.Meals) != 1 || trash.Meals[0].ID != dup.ID {
+		t.Fatalf("expected the duplicate to be in the trash but got %v", trash.Meals)
+	}
+	err = trash.Meals[0].Restore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DB.First(&Meal{}, dup.ID).Error
+	if err != nil {
+		t.Errorf("expected the duplicate to be restored: %v", err)
+	}
+}
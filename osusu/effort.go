@@ -0,0 +1,232 @@
+package osusu
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EffortEstimate is an estimate of the effort required to make a recipe.
+type EffortEstimate struct {
+	// Points is the total number of effort points, where higher = more effort
+	Points int
+	// ActiveTime is the time spent actively working on the recipe
+	ActiveTime time.Duration
+	// PassiveTime is the time spent waiting (eg: baking, simmering, resting)
+	PassiveTime time.Duration
+	// Steps is the number of instruction steps
+	Steps int
+	// Techniques are the names of the cooking techniques detected in the instructions
+	Techniques []string
+	// Equipment are the names of the equipment detected in the instructions and ingredients
+	Equipment []string
+	// Explanation is a human-readable explanation of the estimate
+	Explanation string
+}
+
+// effortKeyword is a technique or piece of equipment that
+// adds effort points to a recipe if it is mentioned.
+type effortKeyword struct {
+	// Name is the user-facing name
+	Name string
+	// Phrases are the base forms of the words that mention it, one of which must
+	// appear as consecutive whole words in the text, with each word in any of its
+	// inflections (see [inflections])
+	Phrases []string
+	// Points is the number of effort points added
+	Points int
+}
+
+// effortTechniques are the techniques detected in recipe instructions.
+var effortTechniques = []effortKeyword{
+	{"knead", []string{"knead"}, 10},
+	{"fold", []string{"fold"}, 4},
+	{"whisk", []string{"whisk"}, 3},
+	{"whip", []string{"whip"}, 5},
+	{"sauté", []string{"sauté", "saute"}, 4},
+	{"stir-fry", []string{"stir fry"}, 5},
+	{"deep fry", []string{"deep fry"}, 10},
+	{"sear", []string{"sear"}, 4},
+	{"blanch", []string{"blanch"}, 5},
+	{"caramelize", []string{"caramelize", "caramelise"}, 6},
+	{"temper", []string{"temper"}, 10},
+	{"emulsify", []string{"emulsify"}, 8},
+	{"flambé", []string{"flambé", "flambe"}, 10},
+	{"julienne", []string{"julienne"}, 6},
+	{"dice", []string{"dice"}, 3},
+	{"mince", []string{"mince"}, 3},
+	{"chop", []string{"chop"}, 2},
+	{"roll out", []string{"roll out"}, 6},
+	{"pipe", []string{"pipe"}, 8},
+	{"fillet", []string{"fillet"}, 10},
+	{"debone", []string{"debone"}, 10},
+	{"dredge", []string{"dredge"}, 5},
+	{"laminate", []string{"laminate"}, 15},
+}
+
+// effortEquipment are the pieces of equipment detected in recipe instructions and ingredients.
+var effortEquipment = []effortKeyword{
+	{"stand mixer", []string{"stand mixer"}, 3},
+	{"hand mixer", []string{"hand mixer"}, 2},
+	{"food processor", []string{"food processor"}, 3},
+	{"blender", []string{"blender"}, 2},
+	{"thermometer", []string{"thermometer"}, 5},
+	{"piping bag", []string{"piping bag"}, 5},
+	{"deep fryer", []string{"fryer"}, 5},
+	{"pressure cooker", []string{"pressure cooker"}, 2},
+	{"water bath", []string{"water bath"}, 6},
+	{"rolling pin", []string{"rolling pin"}, 3},
+	{"grill", []string{"grill"}, 4},
+	{"mandoline", []string{"mandoline", "mandolin"}, 3},
+	{"skewers", []string{"skewer"}, 4},
+	{"springform pan", []string{"springform"}, 3},
+}
+
+// EstimateEffort returns an estimate of the effort required to make the recipe
+// based on its time, ingredients, and instructions. Active time counts much more
+// than passive time, so a long simmer is less effort than a short but involved
+// recipe. [Recipe.Init] must be called first.
+func (r *Recipe) EstimateEffort() *EffortEstimate {
+	ee := &EffortEstimate{
+		ActiveTime:  r.PrepTimeDuration,
+		PassiveTime: r.CookTimeDuration,
+		Steps:       len(r.Instructions),
+	}
+	switch {
+	case ee.ActiveTime == 0 && ee.PassiveTime == 0:
+		// without a breakdown, we assume that half of the total time is active,
+		// using a generic total time duration of one hour if it isn't defined
+		total := r.TotalTimeDuration
+		if total == 0 {
+			total = time.Hour
+		}
+		ee.ActiveTime = total / 2
+		ee.PassiveTime = total - ee.ActiveTime
+	case ee.PassiveTime == 0 && r.TotalTimeDuration > ee.ActiveTime:
+		ee.PassiveTime = r.TotalTimeDuration - ee.ActiveTime
+	}
+
+	instructions := textWords(strings.Join(r.Instructions, "\n"))
+	all := append(textWords(strings.Join(r.Ingredients, "\n")), instructions...)
+
+	techniquePoints := 0
+	for _, t := range effortTechniques {
+		if containsPhrase(instructions, t.Phrases) {
+			ee.Techniques = append(ee.Techniques, t.Name)
+			techniquePoints += t.Points
+		}
+	}
+	equipmentPoints := 0
+	for _, e := range effortEquipment {
+		if containsPhrase(all, e.Phrases) {
+			ee.Equipment = append(ee.Equipment, e.Name)
+			equipmentPoints += e.Points
+		}
+	}
+
+	// passive time is worth one sixth of active time, since it only needs occasional attention
+	ee.Points = int(ee.ActiveTime.Minutes()) + int(ee.PassiveTime.Minutes())/6 +
+		3*ee.Steps + 2*len(r.Ingredients) + techniquePoints + equipmentPoints
+
+	parts := []string{
+		fmt.Sprintf("%s active", friendlyDuration(ee.ActiveTime)),
+		fmt.Sprintf("%s passive", friendlyDuration(ee.PassiveTime)),
+		fmt.Sprintf("%d ingredients", len(r.Ingredients)),
+	}
+	if ee.Steps > 0 {
+		parts = append(parts, fmt.Sprintf("%d steps", ee.Steps))
+	}
+	if len(ee.Techniques) > 0 {
+		parts = append(parts, "techniques: "+strings.Join(ee.Techniques, ", "))
+	}
+	if len(ee.Equipment) > 0 {
+		parts = append(parts, "equipment: "+strings.Join(ee.Equipment, ", "))
+	}
+	ee.Explanation = fmt.Sprintf("%d effort points (%s)", ee.Points, strings.Join(parts, "; "))
+	return ee
+}
+
+// textWords returns the lowercase words in the given text.
+func textWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// containsPhrase returns whether the given words contain any of the given
+// phrases as consecutive whole words, with each word in any of its inflections.
+func containsPhrase(words []string, phrases []string) bool {
+	for _, phrase := range phrases {
+		forms := []map[string]bool{}
+		for _, w := range strings.Fields(phrase) {
+			forms = append(forms, inflections(w))
+		}
+		for i := 0; i+len(forms) <= len(words); i++ {
+			match := true
+			for j, f := range forms {
+				if !f[words[i+j]] {
+					match = false
+					break
+				}
+			}
+			if match {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inflections returns the set of the regular inflections of the given base word
+// as a verb or noun (eg: dice, dices, diced, and dicing).
+func inflections(word string) map[string]bool {
+	res := map[string]bool{word: true}
+	add := func(forms ...string) {
+		for _, f := range forms {
+			res[f] = true
+		}
+	}
+	rs := []rune(word)
+	n := len(rs)
+	if n == 0 {
+		return res
+	}
+	isVowel := func(r rune) bool {
+		return strings.ContainsRune("aeioué", r)
+	}
+	last := rs[n-1]
+	stem := string(rs[:n-1])
+	switch {
+	case last == 'e' || last == 'é':
+		add(word+"s", word+"d", stem+"ing", word+"ing")
+		if last == 'é' {
+			add(word + "ed")
+		}
+	case last == 'y' && n > 1 && !isVowel(rs[n-2]):
+		add(stem+"ies", stem+"ied", word+"ing")
+	case strings.HasSuffix(word, "ch") || strings.HasSuffix(word, "sh") || last == 's' || last == 'x':
+		add(word+"es", word+"ed", word+"ing")
+	default:
+		add(word+"s", word+"ed", word+"ing")
+		// short words ending in a consonant after a single vowel double it (eg: chopped)
+		if n <= 4 && n > 2 && !isVowel(last) && !strings.ContainsRune("wxy", last) && isVowel(rs[n-2]) && !isVowel(rs[n-3]) {
+			add(word+string(last)+"ed", word+string(last)+"ing")
+		}
+	}
+	return res
+}
+
+// friendlyDuration returns the given duration formatted
+// in hours and minutes (eg: 1 h 30 min).
+func friendlyDuration(d time.Duration) string {
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%d min", m)
+	case m == 0:
+		return fmt.Sprintf("%d h", h)
+	}
+	return fmt.Sprintf("%d h %d min", h, m)
+}
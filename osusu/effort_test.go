@@ -0,0 +1,59 @@
+package osusu
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEffortTechniques(t *testing.T) {
+	tests := []struct {
+		instruction string
+		technique   string
+		want        bool
+	}{
+		{"Bring the chocolate to room temperature.", "temper", false},
+		{"Temper the chocolate over a water bath.", "temper", true},
+		{"Cook, stirring frequently, until thick.", "stir-fry", false},
+		{"Stir frequently so that it does not burn.", "stir-fry", false},
+		{"Stir-fry the vegetables for 2 minutes.", "stir-fry", true},
+		{"Stir fried rice with the eggs.", "stir-fry", true},
+		{"Search for ripe avocados.", "sear", false},
+		{"Sear the steak on both sides.", "sear", true},
+		{"Continue searing until browned.", "sear", true},
+		{"Finish by piping the frosting onto the cupcakes.", "pipe", true},
+		{"Pipe rosettes of cream.", "pipe", true},
+		{"Add the pipette of vanilla.", "pipe", false},
+		{"Add the chopped onions.", "chop", true},
+		{"Add the diced tomatoes.", "dice", true},
+		{"Add the dicey mixture.", "dice", false},
+		{"Deep-fried until golden.", "deep fry", true},
+		{"Emulsifying the dressing takes time.", "emulsify", true},
+		{"Knead the dough until smooth.", "knead", true},
+		{"Sautéed mushrooms are served on top.", "sauté", true},
+	}
+	for _, test := range tests {
+		r := &Recipe{Instructions: Instructions{test.instruction}}
+		got := slices.Contains(r.EstimateEffort().Techniques, test.technique)
+		if got != test.want {
+			t.Errorf("%q: expected technique %s to be %v but got %v", test.instruction, test.technique, test.want, got)
+		}
+	}
+}
+
+func TestInflections(t *testing.T) {
+	tests := map[string][]string{
+		"pipe":  {"pipe", "pipes", "piped", "piping"},
+		"chop":  {"chop", "chops", "chopped", "chopping"},
+		"fry":   {"fry", "fries", "fried", "frying"},
+		"whisk": {"whisk", "whisks", "whisked", "whisking"},
+		"sear":  {"sear", "sears", "seared", "searing"},
+	}
+	for word, forms := range tests {
+		inf := inflections(word)
+		for _, f := range forms {
+			if !inf[f] {
+				t.Errorf("expected %q to be an inflection of %q", f, word)
+			}
+		}
+	}
+}
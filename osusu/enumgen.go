@@ -8,6 +8,300 @@ import (
 	"cogentcore.org/core/enums"
 )
 
+var _AuditActionsValues = []AuditActions{0, 1, 2, 3}
+
+// AuditActionsN is the highest valid value for type AuditActions, plus one.
+const AuditActionsN AuditActions = 4
+
+var _AuditActionsValueMap = map[string]AuditActions{`AuditCreate`: 0, `AuditUpdate`: 1, `AuditDelete`: 2, `AuditRestore`: 3}
+
+var _AuditActionsDescMap = map[AuditActions]string{0: ``, 1: ``, 2: ``, 3: ``}
+
+var _AuditActionsMap = map[AuditActions]string{0: `AuditCreate`, 1: `AuditUpdate`, 2: `AuditDelete`, 3: `AuditRestore`}
+
+// String returns the string representation of this AuditActions value.
+func (i AuditActions) String() string { return enums.String(i, _AuditActionsMap) }
+
+// SetString sets the AuditActions value from its string representation,
+// and returns an error if the string is invalid.
+func (i *AuditActions) SetString(s string) error {
+	return enums.SetString(i, s, _AuditActionsValueMap, "AuditActions")
+}
+
+// Int64 returns the AuditActions value as an int64.
+func (i AuditActions) Int64() int64 { return int64(i) }
+
+// SetInt64 sets the AuditActions value from an int64.
+func (i *AuditActions) SetInt64(in int64) { *i = AuditActions(in) }
+
+// Desc returns the description of the AuditActions value.
+func (i AuditActions) Desc() string { return enums.Desc(i, _AuditActionsDescMap) }
+
+// AuditActionsValues returns all possible values for the type AuditActions.
+func AuditActionsValues() []AuditActions { return _AuditActionsValues }
+
+// Values returns all possible values for the type AuditActions.
+func (i AuditActions) Values() []enums.Enum { return enums.Values(_AuditActionsValues) }
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+func (i AuditActions) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (i *AuditActions) UnmarshalText(text []byte) error {
+	return enums.UnmarshalText(i, text, "AuditActions")
+}
+
+// Value implements the [driver.Valuer] interface.
+func (i AuditActions) Value() (driver.Value, error) { return i.String(), nil }
+
+// Scan implements the [sql.Scanner] interface.
+func (i *AuditActions) Scan(value any) error { return enums.Scan(i, value, "AuditActions") }
+
+var _RestoreModesValues = []RestoreModes{0, 1}
+
+// RestoreModesN is the highest valid value for type RestoreModes, plus one.
+const RestoreModesN RestoreModes = 2
+
+var _RestoreModesValueMap = map[string]RestoreModes{`RestoreMerge`: 0, `RestoreReplace`: 1}
+
+var _RestoreModesDescMap = map[RestoreModes]string{0: `RestoreMerge adds the rows in the backup that are not in the database, keeping all of the existing rows.`, 1: `RestoreReplace deletes all of the existing rows and then adds all of the rows in the backup, so the database is the same as when it was backed up.`}
+
+var _RestoreModesMap = map[RestoreModes]string{0: `RestoreMerge`, 1: `RestoreReplace`}
+
+// String returns the string representation of this RestoreModes value.
+func (i RestoreModes) String() string { return enums.String(i, _RestoreModesMap) }
+
+// SetString sets the RestoreModes value from its string representation,
+// and returns an error if the string is invalid.
+func (i *RestoreModes) SetString(s string) error {
+	return enums.SetString(i, s, _RestoreModesValueMap, "RestoreModes")
+}
+
+// Int64 returns the RestoreModes value as an int64.
+func (i RestoreModes) Int64() int64 { return int64(i) }
+
+// SetInt64 sets the RestoreModes value from an int64.
+func (i *RestoreModes) SetInt64(in int64) { *i = RestoreModes(in) }
+
+// Desc returns the description of the RestoreModes value.
+func (i RestoreModes) Desc() string { return enums.Desc(i, _RestoreModesDescMap) }
+
+// RestoreModesValues returns all possible values for the type RestoreModes.
+func RestoreModesValues() []RestoreModes { return _RestoreModesValues }
+
+// Values returns all possible values for the type RestoreModes.
+func (i RestoreModes) Values() []enums.Enum { return enums.Values(_RestoreModesValues) }
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+func (i RestoreModes) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (i *RestoreModes) UnmarshalText(text []byte) error {
+	return enums.UnmarshalText(i, text, "RestoreModes")
+}
+
+// Value implements the [driver.Valuer] interface.
+func (i RestoreModes) Value() (driver.Value, error) { return i.String(), nil }
+
+// Scan implements the [sql.Scanner] interface.
+func (i *RestoreModes) Scan(value any) error { return enums.Scan(i, value, "RestoreModes") }
+
+var _DataFormatsValues = []DataFormats{0, 1}
+
+// DataFormatsN is the highest valid value for type DataFormats, plus one.
+const DataFormatsN DataFormats = 2
+
+var _DataFormatsValueMap = map[string]DataFormats{`DataJSON`: 0, `DataCSV`: 1}
+
+var _DataFormatsDescMap = map[DataFormats]string{0: `DataJSON is a single JSON file with a schema version, which is lossless.`, 1: `DataCSV is a directory with one CSV file per table, with bit flags as readable names, which can be edited in spreadsheets.`}
+
+var _DataFormatsMap = map[DataFormats]string{0: `DataJSON`, 1: `DataCSV`}
+
+// String returns the string representation of this DataFormats value.
+func (i DataFormats) String() string { return enums.String(i, _DataFormatsMap) }
+
+// SetString sets the DataFormats value from its string representation,
+// and returns an error if the string is invalid.
+func (i *DataFormats) SetString(s string) error {
+	return enums.SetString(i, s, _DataFormatsValueMap, "DataFormats")
+}
+
+// Int64 returns the DataFormats value as an int64.
+func (i DataFormats) Int64() int64 { return int64(i) }
+
+// SetInt64 sets the DataFormats value from an int64.
+func (i *DataFormats) SetInt64(in int64) { *i = DataFormats(in) }
+
+// Desc returns the description of the DataFormats value.
+func (i DataFormats) Desc() string { return enums.Desc(i, _DataFormatsDescMap) }
+
+// DataFormatsValues returns all possible values for the type DataFormats.
+func DataFormatsValues() []DataFormats { return _DataFormatsValues }
+
+// Values returns all possible values for the type DataFormats.
+func (i DataFormats) Values() []enums.Enum { return enums.Values(_DataFormatsValues) }
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+func (i DataFormats) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (i *DataFormats) UnmarshalText(text []byte) error {
+	return enums.UnmarshalText(i, text, "DataFormats")
+}
+
+// Value implements the [driver.Valuer] interface.
+func (i DataFormats) Value() (driver.Value, error) { return i.String(), nil }
+
+// Scan implements the [sql.Scanner] interface.
+func (i *DataFormats) Scan(value any) error { return enums.Scan(i, value, "DataFormats") }
+
+var _ImportConflictsValues = []ImportConflicts{0, 1, 2}
+
+// ImportConflictsN is the highest valid value for type ImportConflicts, plus one.
+const ImportConflictsN ImportConflicts = 3
+
+var _ImportConflictsValueMap = map[string]ImportConflicts{`ImportSkip`: 0, `ImportOverwrite`: 1, `ImportNew`: 2}
+
+var _ImportConflictsDescMap = map[ImportConflicts]string{0: `ImportSkip keeps the existing records and skips the imported ones.`, 1: `ImportOverwrite replaces the existing records with the imported ones.`, 2: `ImportNew creates all of the imported records with new IDs and updates the references between them, so that no existing records are changed. Imported users are matched to existing users by email, only in the group that the data is imported into if there is one.`}
+
+var _ImportConflictsMap = map[ImportConflicts]string{0: `ImportSkip`, 1: `ImportOverwrite`, 2: `ImportNew`}
+
+// String returns the string representation of this ImportConflicts value.
+func (i ImportConflicts) String() string { return enums.String(i, _ImportConflictsMap) }
+
+// SetString sets the ImportConflicts value from its string representation,
+// and returns an error if the string is invalid.
+func (i *ImportConflicts) SetString(s string) error {
+	return enums.SetString(i, s, _ImportConflictsValueMap, "ImportConflicts")
+}
+
+// Int64 returns the ImportConflicts value as an int64.
+func (i ImportConflicts) Int64() int64 { return int64(i) }
+
+// SetInt64 sets the ImportConflicts value from an int64.
+func (i *ImportConflicts) SetInt64(in int64) { *i = ImportConflicts(in) }
+
+// Desc returns the description of the ImportConflicts value.
+func (i ImportConflicts) Desc() string { return enums.Desc(i, _ImportConflictsDescMap) }
+
+// ImportConflictsValues returns all possible values for the type ImportConflicts.
+func ImportConflictsValues() []ImportConflicts { return _ImportConflictsValues }
+
+// Values returns all possible values for the type ImportConflicts.
+func (i ImportConflicts) Values() []enums.Enum { return enums.Values(_ImportConflictsValues) }
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+func (i ImportConflicts) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (i *ImportConflicts) UnmarshalText(text []byte) error {
+	return enums.UnmarshalText(i, text, "ImportConflicts")
+}
+
+// Value implements the [driver.Valuer] interface.
+func (i ImportConflicts) Value() (driver.Value, error) { return i.String(), nil }
+
+// Scan implements the [sql.Scanner] interface.
+func (i *ImportConflicts) Scan(value any) error { return enums.Scan(i, value, "ImportConflicts") }
+
+var _DismissReasonsValues = []DismissReasons{0, 1, 2, 3}
+
+// DismissReasonsN is the highest valid value for type DismissReasons, plus one.
+const DismissReasonsN DismissReasons = 4
+
+var _DismissReasonsValueMap = map[string]DismissReasons{`DontLike`: 0, `TooHard`: 1, `Allergic`: 2, `OtherReason`: 3}
+
+var _DismissReasonsDescMap = map[DismissReasons]string{0: ``, 1: ``, 2: ``, 3: ``}
+
+var _DismissReasonsMap = map[DismissReasons]string{0: `DontLike`, 1: `TooHard`, 2: `Allergic`, 3: `OtherReason`}
+
+// String returns the string representation of this DismissReasons value.
+func (i DismissReasons) String() string { return enums.String(i, _DismissReasonsMap) }
+
+// SetString sets the DismissReasons value from its string representation,
+// and returns an error if the string is invalid.
+func (i *DismissReasons) SetString(s string) error {
+	return enums.SetString(i, s, _DismissReasonsValueMap, "DismissReasons")
+}
+
+// Int64 returns the DismissReasons value as an int64.
+func (i DismissReasons) Int64() int64 { return int64(i) }
+
+// SetInt64 sets the DismissReasons value from an int64.
+func (i *DismissReasons) SetInt64(in int64) { *i = DismissReasons(in) }
+
+// Desc returns the description of the DismissReasons value.
+func (i DismissReasons) Desc() string { return enums.Desc(i, _DismissReasonsDescMap) }
+
+// DismissReasonsValues returns all possible values for the type DismissReasons.
+func DismissReasonsValues() []DismissReasons { return _DismissReasonsValues }
+
+// Values returns all possible values for the type DismissReasons.
+func (i DismissReasons) Values() []enums.Enum { return enums.Values(_DismissReasonsValues) }
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+func (i DismissReasons) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (i *DismissReasons) UnmarshalText(text []byte) error {
+	return enums.UnmarshalText(i, text, "DismissReasons")
+}
+
+// Value implements the [driver.Valuer] interface.
+func (i DismissReasons) Value() (driver.Value, error) { return i.String(), nil }
+
+// Scan implements the [sql.Scanner] interface.
+func (i *DismissReasons) Scan(value any) error { return enums.Scan(i, value, "DismissReasons") }
+
+var _ChoiceKindsValues = []ChoiceKinds{0, 1}
+
+// ChoiceKindsN is the highest valid value for type ChoiceKinds, plus one.
+const ChoiceKindsN ChoiceKinds = 2
+
+var _ChoiceKindsValueMap = map[string]ChoiceKinds{`EntryChoice`: 0, `DiscoverChoice`: 1}
+
+var _ChoiceKindsDescMap = map[ChoiceKinds]string{0: `EntryChoice is when a user creates an entry for a meal in the search results.`, 1: `DiscoverChoice is when a user adds a recipe from the discover results.`}
+
+var _ChoiceKindsMap = map[ChoiceKinds]string{0: `EntryChoice`, 1: `DiscoverChoice`}
+
+// String returns the string representation of this ChoiceKinds value.
+func (i ChoiceKinds) String() string { return enums.String(i, _ChoiceKindsMap) }
+
+// SetString sets the ChoiceKinds value from its string representation,
+// and returns an error if the string is invalid.
+func (i *ChoiceKinds) SetString(s string) error {
+	return enums.SetString(i, s, _ChoiceKindsValueMap, "ChoiceKinds")
+}
+
+// Int64 returns the ChoiceKinds value as an int64.
+func (i ChoiceKinds) Int64() int64 { return int64(i) }
+
+// SetInt64 sets the ChoiceKinds value from an int64.
+func (i *ChoiceKinds) SetInt64(in int64) { *i = ChoiceKinds(in) }
+
+// Desc returns the description of the ChoiceKinds value.
+func (i ChoiceKinds) Desc() string { return enums.Desc(i, _ChoiceKindsDescMap) }
+
+// ChoiceKindsValues returns all possible values for the type ChoiceKinds.
+func ChoiceKindsValues() []ChoiceKinds { return _ChoiceKindsValues }
+
+// Values returns all possible values for the type ChoiceKinds.
+func (i ChoiceKinds) Values() []enums.Enum { return enums.Values(_ChoiceKindsValues) }
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+func (i ChoiceKinds) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (i *ChoiceKinds) UnmarshalText(text []byte) error {
+	return enums.UnmarshalText(i, text, "ChoiceKinds")
+}
+
+// Value implements the [driver.Valuer] interface.
+func (i ChoiceKinds) Value() (driver.Value, error) { return i.String(), nil }
+
+// Scan implements the [sql.Scanner] interface.
+func (i *ChoiceKinds) Scan(value any) error { return enums.Scan(i, value, "ChoiceKinds") }
+
 var _SourcesValues = []Sources{0, 1, 2, 3}
 
 // SourcesN is the highest valid value for type Sources, plus one.
@@ -198,3 +492,101 @@ func (i Cuisines) Value() (driver.Value, error) { return i.String(), nil }
 
 // Scan implements the [sql.Scanner] interface.
 func (i *Cuisines) Scan(value any) error { return enums.Scan(i, value, "Cuisines") }
+
+var _NormStrategiesValues = []NormStrategies{0, 1, 2, 3}
+
+// NormStrategiesN is the highest valid value for type NormStrategies, plus one.
+const NormStrategiesN NormStrategies = 4
+
+var _NormStrategiesValueMap = map[string]NormStrategies{`NormZScore`: 0, `NormPercentile`: 1, `NormRobust`: 2, `NormMinMax`: 3}
+
+var _NormStrategiesDescMap = map[NormStrategies]string{0: `NormZScore uses z-scores based on the mean and standard deviation centered at 50, which are affected by outliers. It is the default, since scores were always normalized this way before the other strategies.`, 1: `NormPercentile uses the percentile rank of each value, which only depends on the order of the values and is not affected by outliers.`, 2: `NormRobust uses z-scores based on the median and the median absolute deviation, which are not affected by outliers, centered at 50.`, 3: `NormMinMax scales the values linearly from the minimum to the maximum after clipping them to the 1st and 99th percentiles.`}
+
+var _NormStrategiesMap = map[NormStrategies]string{0: `NormZScore`, 1: `NormPercentile`, 2: `NormRobust`, 3: `NormMinMax`}
+
+// String returns the string representation of this NormStrategies value.
+func (i NormStrategies) String() string { return enums.String(i, _NormStrategiesMap) }
+
+// SetString sets the NormStrategies value from its string representation,
+// and returns an error if the string is invalid.
+func (i *NormStrategies) SetString(s string) error {
+	return enums.SetString(i, s, _NormStrategiesValueMap, "NormStrategies")
+}
+
+// Int64 returns the NormStrategies value as an int64.
+func (i NormStrategies) Int64() int64 { return int64(i) }
+
+// SetInt64 sets the NormStrategies value from an int64.
+func (i *NormStrategies) SetInt64(in int64) { *i = NormStrategies(in) }
+
+// Desc returns the description of the NormStrategies value.
+func (i NormStrategies) Desc() string { return enums.Desc(i, _NormStrategiesDescMap) }
+
+// NormStrategiesValues returns all possible values for the type NormStrategies.
+func NormStrategiesValues() []NormStrategies { return _NormStrategiesValues }
+
+// Values returns all possible values for the type NormStrategies.
+func (i NormStrategies) Values() []enums.Enum { return enums.Values(_NormStrategiesValues) }
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+func (i NormStrategies) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (i *NormStrategies) UnmarshalText(text []byte) error {
+	return enums.UnmarshalText(i, text, "NormStrategies")
+}
+
+// Value implements the [driver.Valuer] interface.
+func (i NormStrategies) Value() (driver.Value, error) { return i.String(), nil }
+
+// Scan implements the [sql.Scanner] interface.
+func (i *NormStrategies) Scan(value any) error { return enums.Scan(i, value, "NormStrategies") }
+
+var _RecencyCurvesValues = []RecencyCurves{0, 1}
+
+// RecencyCurvesN is the highest valid value for type RecencyCurves, plus one.
+const RecencyCurvesN RecencyCurves = 2
+
+var _RecencyCurvesValueMap = map[string]RecencyCurves{`RecencyLinear`: 0, `RecencyLogarithmic`: 1}
+
+var _RecencyCurvesDescMap = map[RecencyCurves]string{0: `RecencyLinear increases the recency score at a constant rate until the end of the comfort period.`, 1: `RecencyLogarithmic increases the recency score quickly at first and then more slowly until the end of the comfort period, so meals become acceptable again sooner.`}
+
+var _RecencyCurvesMap = map[RecencyCurves]string{0: `RecencyLinear`, 1: `RecencyLogarithmic`}
+
+// String returns the string representation of this RecencyCurves value.
+func (i RecencyCurves) String() string { return enums.String(i, _RecencyCurvesMap) }
+
+// SetString sets the RecencyCurves value from its string representation,
+// and returns an error if the string is invalid.
+func (i *RecencyCurves) SetString(s string) error {
+	return enums.SetString(i, s, _RecencyCurvesValueMap, "RecencyCurves")
+}
+
+// Int64 returns the RecencyCurves value as an int64.
+func (i RecencyCurves) Int64() int64 { return int64(i) }
+
+// SetInt64 sets the RecencyCurves value from an int64.
+func (i *RecencyCurves) SetInt64(in int64) { *i = RecencyCurves(in) }
+
+// Desc returns the description of the RecencyCurves value.
+func (i RecencyCurves) Desc() string { return enums.Desc(i, _RecencyCurvesDescMap) }
+
+// RecencyCurvesValues returns all possible values for the type RecencyCurves.
+func RecencyCurvesValues() []RecencyCurves { return _RecencyCurvesValues }
+
+// Values returns all possible values for the type RecencyCurves.
+func (i RecencyCurves) Values() []enums.Enum { return enums.Values(_RecencyCurvesValues) }
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+func (i RecencyCurves) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+func (i *RecencyCurves) UnmarshalText(text []byte) error {
+	return enums.UnmarshalText(i, text, "RecencyCurves")
+}
+
+// Value implements the [driver.Valuer] interface.
+func (i RecencyCurves) Value() (driver.Value, error) { return i.String(), nil }
+
+// Scan implements the [sql.Scanner] interface.
+func (i *RecencyCurves) Scan(value any) error { return enums.Scan(i, value, "RecencyCurves") }
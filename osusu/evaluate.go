@@ -0,0 +1,318 @@
+package osusu
+
+import (
+	"cmp"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// EvalData is the historical data that the recommender is evaluated on in [Evaluate].
+type EvalData struct {
+	Meals   []*Meal
+	Entries []Entry
+}
+
+// EvalConfig is a configuration of the recommender that is evaluated in [Evaluate].
+type EvalConfig struct {
+	Name      string
+	Options   *Options
+	Recommend *RecommendConfig
+}
+
+// DefaultEvalConfig returns the [EvalConfig] with the default options and
+// recommendation configuration.
+func DefaultEvalConfig() *EvalConfig {
+	return &EvalConfig{
+		Name:      "default",
+		Options:   DefaultOptions(),
+		Recommend: DefaultRecommendConfig(),
+	}
+}
+
+// EvalParams are the parameters of an evaluation in [Evaluate].
+type EvalParams struct {
+	// K is the number of recommendations evaluated at each point
+	K int
+	// Horizon is the number of next new meals of the user that are relevant at each point
+	Horizon int
+	// MinHistory is the number of entries a user needs before they are evaluated
+	MinHistory int
+}
+
+// DefaultEvalParams returns the default [EvalParams].
+func DefaultEvalParams() *EvalParams {
+	return &EvalParams{
+		K:          10,
+		Horizon:    5,
+		MinHistory: 5,
+	}
+}
+
+// EvalResult is the result of evaluating a configuration of the recommender in [Evaluate].
+// The metrics are averaged over all of the evaluation points.
+type EvalResult struct {
+	Config string
+	// Points is the number of evaluation points
+	Points int
+	// Precision is the fraction of the top K recommendations that are relevant
+	Precision float64
+	// NDCG is the normalized discounted cumulative gain of the top K recommendations,
+	// which also rewards relevant recommendations for being ranked higher
+	NDCG float64
+	// Coverage is the fraction of all recipes that are in the top K recommendations at
+	// any point, which is low when the same recipes are always recommended
+	Coverage float64
+	// Diversity is the average cosine distance between the text encoding vectors of
+	// pairs of the top K recommendations
+	Diversity float64
+}
+
+// Evaluate evaluates the given configuration of the recommender on the given historical
+// data by replaying the entries chronologically. Each time a user first eats a meal added
+// from one of the given recipes, the recipes are recommended to them using only the meals
+// and entries before that time, and the recommendations are compared to the next new
+// meals added from recipes that the user eats. The text encoding vectors of the meals are
+// those of their source recipes, since the text encoding model is not used, so meals
+// without source recipes do not count toward the encoding scores. Only the built-in
+// dimensions are used, since each group has its own custom dimensions. Collaborative filtering
+// and dismissals are not evaluated. [Recipe.EstimateCost] should be called on the recipes
+// first, and the recipes should have their category and cuisine flags set.
+func Evaluate(data *EvalData, recipes []*Recipe, vectors map[string][]float32, cfg *EvalConfig, params *EvalParams) *EvalResult {
+	entries := slices.Clone(data.Entries)
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return a.Time.Compare(b.Time)
+	})
+	meals := map[uint]*Meal{}
+	for _, meal := range data.Meals {
+		meals[meal.ID] = meal
+	}
+	isRecipe := map[string]bool{}
+	for _, recipe := range recipes {
+		isRecipe[recipe.URL] = true
+	}
+
+	res := &EvalResult{Config: cfg.Name}
+	recommended := map[string]bool{}
+	// eaten is the meals each user has already eaten, keyed by user ID and then meal ID
+	eaten := map[uint]map[uint]bool{}
+	for i, entry := range entries {
+		meal := meals[entry.MealID]
+		if meal == nil {
+			continue
+		}
+		if eaten[entry.UserID] == nil {
+			eaten[entry.UserID] = map[uint]bool{}
+		}
+		first := !eaten[entry.UserID][meal.ID]
+		eaten[entry.UserID][meal.ID] = true
+		if !first || !isRecipe[meal.SourceURL] {
+			continue
+		}
+
+		past := entries[:i]
+		known := knownMeals(past, meals, meal.GroupID)
+		knownURLs := map[string]bool{}
+		for _, m := range known {
+			knownURLs[m.SourceURL] = true
+		}
+		if knownURLs[meal.SourceURL] {
+			// the meal was already added by someone else in the group
+			continue
+		}
+		var userEntries, groupEntries []Entry
+		for _, e := range past {
+			if m := meals[e.MealID]; m == nil || m.GroupID != meal.GroupID {
+				continue
+			}
+			groupEntries = append(groupEntries, e)
+			if e.UserID == entry.UserID {
+				userEntries = append(userEntries, e)
+			}
+		}
+		if len(userEntries) < params.MinHistory {
+			continue
+		}
+		relevant := nextNewMeals(entries[i:], meals, entry.UserID, knownURLs, isRecipe, params.Horizon)
+
+		mealEntries := map[uint][]Entry{}
+		for _, e := range userEntries {
+			mealEntries[e.MealID] = append(mealEntries[e.MealID], e)
+		}
+		mealVectors := map[uint][]float32{}
+		for _, m := range known {
+			if v, ok := vectors[m.SourceURL]; ok {
+				mealVectors[m.ID] = v
+			}
+		}
+		// the recommendations are made at the time of the entry
+		prior := ComputePrior(userEntries, groupEntries, cfg.Options, BuiltinDimensions, entry.Time)
+		ScoreRecipes(recipes, known, mealEntries, mealVectors, vectors, prior, cfg.Options, BuiltinDimensions, cfg.Recommend, entry.Time)
+		ranked := Rerank(ExcludeRecipes(recipes, known, nil), vectors, float64(cfg.Options.Diversity)/100, params.K)
+
+		res.Points++
+		res.Precision += precisionAtK(ranked, relevant, params.K)
+		res.NDCG += ndcgAtK(ranked, relevant, params.K)
+		res.Diversity += intraListDiversity(ranked, vectors)
+		for _, r := range ranked {
+			recommended[r.URL] = true
+		}
+	}
+	if res.Points > 0 {
+		n := float64(res.Points)
+		res.Precision /= n
+		res.NDCG /= n
+		res.Diversity /= n
+	}
+	if len(recipes) > 0 {
+		res.Coverage = float64(len(recommended)) / float64(len(recipes))
+	}
+	return res
+}
+
+// knownMeals returns the meals of the given group that have entries in the given entries.
+func knownMeals(entries []Entry, meals map[uint]*Meal, groupID uint) []*Meal {
+	res := []*Meal{}
+	seen := map[uint]bool{}
+	for _, e := range entries {
+		m := meals[e.MealID]
+		if m == nil || m.GroupID != groupID || seen[m.ID] {
+			continue
+		}
+		seen[m.ID] = true
+		res = append(res, m)
+	}
+	return res
+}
+
+// nextNewMeals returns the source URLs of the first n meals added from recipes that the
+// given user eats in the given entries and that are not already known.
+func nextNewMeals(entries []Entry, meals map[uint]*Meal, userID uint, known, isRecipe map[string]bool, n int) map[string]bool {
+	res := map[string]bool{}
+	for _, e := range entries {
+		if len(res) >= n {
+			break
+		}
+		m := meals[e.MealID]
+		if e.UserID != userID || m == nil || known[m.SourceURL] || !isRecipe[m.SourceURL] {
+			continue
+		}
+		res[m.SourceURL] = true
+	}
+	return res
+}
+
+// precisionAtK returns the fraction of the first k of the given recipes that are relevant.
+func precisionAtK(ranked []*Recipe, relevant map[string]bool, k int) float64 {
+	if k <= 0 {
+		return 0
+	}
+	hits := 0
+	for _, r := range ranked[:min(k, len(ranked))] {
+		if relevant[r.URL] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(k)
+}
+
+// ndcgAtK returns the normalized discounted cumulative gain of the first
+// k of the given recipes with binary relevance.
+func ndcgAtK(ranked []*Recipe, relevant map[string]bool, k int) float64 {
+	dcg := 0.0
+	for i, r := range ranked[:min(k, len(ranked))] {
+		if relevant[r.URL] {
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+	idcg := 0.0
+	for i := range min(k, len(relevant)) {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+	if idcg == 0 {
+		return 0
+	}
+	return dcg / idcg
+}
+
+// intraListDiversity returns the average cosine distance between the text
+// encoding vectors of pairs of the given recipes, keyed by recipe URL.
+func intraListDiversity(recipes []*Recipe, vectors map[string][]float32) float64 {
+	total, pairs := 0.0, 0
+	for i, a := range recipes {
+		av := vectors[a.URL]
+		an := vectorNorm(av)
+		for _, b := range recipes[i+1:] {
+			bv := vectors[b.URL]
+			bn := vectorNorm(bv)
+			if an == 0 || bn == 0 {
+				continue
+			}
+			total += 1 - vectorDot(av, bv)/(an*bn)
+			pairs++
+		}
+	}
+	if pairs == 0 {
+		return 0
+	}
+	return total / float64(pairs)
+}
+
+// SyntheticEvalData returns synthetic historical data for [Evaluate] with the given
+// number of users, each in their own group and with the given number of entries, one
+// per day. Each user has a taste for recipes similar to a random recipe, so they add
+// new meals from the recipes most similar to it and eat their favorite meals again.
+// The text encoding vectors are keyed by recipe URL, and the data is deterministic
+// for a given seed.
+func SyntheticEvalData(recipes []*Recipe, vectors map[string][]float32, users, entries int, seed uint64) *EvalData {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	data := &EvalData{}
+	start := time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)
+	if len(recipes) == 0 {
+		return data
+	}
+	for u := range users {
+		userID, groupID := uint(u+1), uint(u+1)
+		taste := vectors[recipes[rng.IntN(len(recipes))].URL]
+		tn := vectorNorm(taste)
+		similarity := func(r *Recipe) float64 {
+			v := vectors[r.URL]
+			if vn := vectorNorm(v); vn != 0 && tn != 0 {
+				return vectorDot(taste, v) / (vn * tn)
+			}
+			return 0
+		}
+		// users pick randomly from the recipes most similar to their taste
+		liked := slices.Clone(recipes)
+		slices.SortFunc(liked, func(a, b *Recipe) int {
+			return cmp.Compare(similarity(b), similarity(a))
+		})
+		liked = liked[:min(len(liked), 5*entries)]
+		rng.Shuffle(len(liked), func(i, j int) {
+			liked[i], liked[j] = liked[j], liked[i]
+		})
+
+		var own []*Meal
+		for e := range entries {
+			var meal *Meal
+			if len(own) > 0 && (len(liked) == 0 || rng.Float64() < 0.5) {
+				meal = own[rng.IntN(len(own))]
+			} else if len(liked) > 0 {
+				r := liked[0]
+				liked = liked[1:]
+				meal = &Meal{GroupID: groupID, Name: r.Name, SourceURL: r.URL, Category: r.CategoryFlag, Cuisine: r.CuisineFlag}
+				meal.ID = uint(len(data.Meals) + 1)
+				data.Meals = append(data.Meals, meal)
+				own = append(own, meal)
+			} else {
+				break
+			}
+			rating := Round(min(max(50+50*similarity(&Recipe{URL: meal.SourceURL})+10*rng.NormFloat64(), 0), 100))
+			entry := Entry{MealID: meal.ID, UserID: userID, Time: start.AddDate(0, 0, e), Category: meal.Category, Taste: rating, Cost: 50, Effort: 50, Healthiness: 50, Servings: 1}
+			entry.ID = uint(len(data.Entries) + 1)
+			data.Entries = append(data.Entries, entry)
+		}
+	}
+	return data
+}
@@ -0,0 +1,144 @@
+package osusu
+
+// A HealthinessModel computes a healthiness index from the nutritional
+// information for one serving of a recipe or meal. Higher values are healthier.
+// The index values only need to be comparable between the results of the same
+// model, since they are normalized in [ComputeNormScores]. The index is not ok
+// if there is not enough nutritional information to compute it, in which case
+// the healthiness is unknown.
+type HealthinessModel interface {
+	Healthiness(n Nutrition) (index int, ok bool)
+}
+
+// DefaultHealthinessModel is the [HealthinessModel] used in [Recipe.ComputeBaseScoreIndex].
+var DefaultHealthinessModel HealthinessModel = NutriScore{}
+
+// SugarProteinRatio is a [HealthinessModel] that only uses the
+// ratio of sugar to protein, where more sugar is worse.
+type SugarProteinRatio struct{}
+
+func (SugarProteinRatio) Healthiness(n Nutrition) (int, bool) {
+	if n.Sugar == 0 && n.Protein == 0 {
+		return 0, false
+	}
+	// avoid div by 0
+	if n.Protein == 0 {
+		return -n.Sugar * 10, true
+	}
+	return -100 * n.Sugar / n.Protein, true
+}
+
+// NutriScore is a [HealthinessModel] based on the points system of the
+// Nutri-Score nutrient profiling scheme, using the thresholds of the original
+// (2017) algorithm for general foods specified by Santé publique France. The
+// index is the negative of the points, so lower points result in a higher
+// healthiness index.
+//
+// Nutri-Score is defined per 100 g of food, but recipes do not have serving
+// weights, so every serving is assumed to weigh [NutriScore.ServingGrams].
+// The fruit, vegetable, and nut component is not used, since it can not be
+// determined from the nutritional information.
+type NutriScore struct {
+	// ServingGrams is the assumed weight of one serving in grams,
+	// or 0 to use [DefaultServingGrams]
+	ServingGrams float64
+}
+
+// DefaultServingGrams is the default assumed weight of one serving in grams
+// for [NutriScore], which is a typical portion of a main dish.
+const DefaultServingGrams = 300
+
+var (
+	// nutriScoreEnergy are the thresholds for the energy points in kJ
+	nutriScoreEnergy = []float64{335, 670, 1005, 1340, 1675, 2010, 2345, 2680, 3015, 3350}
+	// nutriScoreSugar are the thresholds for the sugar points in g
+	nutriScoreSugar = []float64{4.5, 9, 13.5, 18, 22.5, 27, 31, 36, 40, 45}
+	// nutriScoreSaturatedFat are the thresholds for the saturated fat points in g
+	nutriScoreSaturatedFat = []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	// nutriScoreSodium are the thresholds for the sodium points in mg
+	nutriScoreSodium = []float64{90, 180, 270, 360, 450, 540, 630, 720, 810, 900}
+	// nutriScoreFiber are the thresholds for the fiber points in g
+	nutriScoreFiber = []float64{0.9, 1.9, 2.8, 3.7, 4.7}
+	// nutriScoreProtein are the thresholds for the protein points in g
+	nutriScoreProtein = []float64{1.6, 3.2, 4.8, 6.4, 8}
+)
+
+// kilojoulesPerKcal is the number of kilojoules in a kilocalorie.
+const kilojoulesPerKcal = 4.184
+
+func (ns NutriScore) Healthiness(n Nutrition) (int, bool) {
+	points, ok := ns.Points(n)
+	return -points, ok
+}
+
+// Points returns the Nutri-Score points for the given nutritional information
+// for one serving, where lower points are healthier. The points are not ok if
+// there is no calorie information, since every other nutrient could then be
+// missing as well, which would make the recipe look healthy.
+func (ns NutriScore) Points(n Nutrition) (points int, ok bool) {
+	if n.Calories <= 0 {
+		return 0, false
+	}
+	grams := ns.ServingGrams
+	if grams <= 0 {
+		grams = DefaultServingGrams
+	}
+	return nutriScorePoints(n, 100/grams), true
+}
+
+// Grade returns the Nutri-Score grade (A to E) for the given nutritional
+// information for one serving, using the thresholds for general foods,
+// or "" if the points are not ok (see [NutriScore.Points]).
+func (ns NutriScore) Grade(n Nutrition) string {
+	points, ok := ns.Points(n)
+	if !ok {
+		return ""
+	}
+	return nutriScoreGrade(points)
+}
+
+// nutriScorePoints returns the Nutri-Score points for the given nutritional
+// information multiplied by the given factor to make it per 100 g.
+func nutriScorePoints(n Nutrition, factor float64) int {
+	per := func(v int) float64 {
+		return float64(v) * factor
+	}
+	negative := nutriScoreThresholds(per(n.Calories)*kilojoulesPerKcal, nutriScoreEnergy) +
+		nutriScoreThresholds(per(n.Sugar), nutriScoreSugar) +
+		nutriScoreThresholds(per(n.SaturatedFat), nutriScoreSaturatedFat) +
+		nutriScoreThresholds(per(n.Sodium), nutriScoreSodium)
+	positive := nutriScoreThresholds(per(n.Fiber), nutriScoreFiber)
+	// protein is not counted for foods with 11 or more negative points,
+	// since the fruit and vegetable points that would allow it are not known
+	if negative < 11 {
+		positive += nutriScoreThresholds(per(n.Protein), nutriScoreProtein)
+	}
+	return negative - positive
+}
+
+// nutriScoreGrade returns the Nutri-Score grade (A to E) for the given points,
+// using the thresholds for general foods.
+func nutriScoreGrade(points int) string {
+	switch {
+	case points <= -1:
+		return "A"
+	case points <= 2:
+		return "B"
+	case points <= 10:
+		return "C"
+	case points <= 18:
+		return "D"
+	}
+	return "E"
+}
+
+// nutriScoreThresholds returns the number of the given thresholds that the given value exceeds.
+func nutriScoreThresholds(value float64, thresholds []float64) int {
+	points := 0
+	for _, t := range thresholds {
+		if value > t {
+			points++
+		}
+	}
+	return points
+}
@@ -0,0 +1,86 @@
+package osusu
+
+import "testing"
+
+// The reference foods have typical nutritional information per 100 g, and
+// their points and grades are computed by hand with the official tables.
+func TestNutriScoreReference(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      Nutrition
+		points int
+		grade  string
+	}{
+		// 1100 kJ (3), 5 g sugar (1), 490 mg sodium (5); 3 g fiber (3), 9 g protein (5)
+		{"white bread", Nutrition{Calories: 263, Sugar: 5, Sodium: 490, Fiber: 3, Protein: 9}, 1, "B"},
+		// 1586 kJ (4), 1 g saturated fat (0); 10 g fiber (5), 13 g protein (5)
+		{"rolled oats", Nutrition{Calories: 379, Sugar: 1, SaturatedFat: 1, Sodium: 6, Fiber: 10, Protein: 13}, -6, "A"},
+		// 2238 kJ (6), 56 g sugar (10), 19 g saturated fat (10); 2 g fiber (2), protein not counted
+		{"milk chocolate", Nutrition{Calories: 535, Sugar: 56, SaturatedFat: 19, Sodium: 80, Fiber: 2, Protein: 8}, 24, "E"},
+		// 2243 kJ (6), 3 g saturated fat (2), 560 mg sodium (6); 4 g fiber (4), protein not counted
+		{"potato chips", Nutrition{Calories: 536, Sugar: 1, SaturatedFat: 3, Sodium: 560, Fiber: 4, Protein: 7}, 10, "C"},
+	}
+	for _, test := range tests {
+		points := nutriScorePoints(test.n, 1)
+		if points != test.points {
+			t.Errorf("%s: expected %d points but got %d", test.name, test.points, points)
+		}
+		if grade := nutriScoreGrade(points); grade != test.grade {
+			t.Errorf("%s: expected grade %s but got %s", test.name, test.grade, grade)
+		}
+	}
+}
+
+func TestNutriScoreProteinCap(t *testing.T) {
+	// 10 negative points (sodium), so 10 g protein (5) is counted
+	n := Nutrition{Calories: 50, Sodium: 1000, Protein: 10}
+	if points := nutriScorePoints(n, 1); points != 5 {
+		t.Errorf("expected protein to be counted with 10 negative points, but got %d points", points)
+	}
+	// 11 negative points (sodium and sugar), so protein is not counted
+	n.Sugar = 5
+	if points := nutriScorePoints(n, 1); points != 11 {
+		t.Errorf("expected protein to not be counted with 11 negative points, but got %d points", points)
+	}
+}
+
+func TestNutriScoreServing(t *testing.T) {
+	n := Nutrition{Calories: 263, Sugar: 5, Sodium: 490, Fiber: 3, Protein: 9}
+	if points, _ := (NutriScore{ServingGrams: 100}).Points(n); points != 1 {
+		t.Errorf("expected a 100 g serving to have the points per 100 g, but got %d", points)
+	}
+	// a 300 g serving with three times the nutrients is the same food
+	if points, _ := (NutriScore{}).Points(n.Scale(3)); points != 1 {
+		t.Errorf("expected a default serving to be 300 g, but got %d points", points)
+	}
+}
+
+func TestNutriScoreMissing(t *testing.T) {
+	for _, n := range []Nutrition{{}, {Sugar: 50}, {Protein: 20, Fiber: 10}} {
+		if _, ok := (NutriScore{}).Points(n); ok {
+			t.Errorf("%+v: expected the points to not be ok without calories", n)
+		}
+		if _, ok := (NutriScore{}).Healthiness(n); ok {
+			t.Errorf("%+v: expected the healthiness to not be ok without calories", n)
+		}
+		if grade := (NutriScore{}).Grade(n); grade != "" {
+			t.Errorf("%+v: expected no grade without calories, but got %s", n, grade)
+		}
+	}
+
+	// recipes without nutritional information are in the middle, not the healthiest
+	healthy := &Recipe{Nutrition: Nutrition{Calories: 379, Sugar: 1, SaturatedFat: 1, Sodium: 6, Fiber: 10, Protein: 13}}
+	unhealthy := &Recipe{Nutrition: Nutrition{Calories: 535, Sugar: 56, SaturatedFat: 19, Sodium: 80, Fiber: 2, Protein: 8}}
+	missing := &Recipe{}
+	recipes := []*Recipe{healthy, unhealthy, missing}
+	for _, r := range recipes {
+		r.ComputeBaseScoreIndex()
+	}
+	ComputeNormScores(recipes, BuiltinDimensions, NormMinMax)
+	if h := missing.BaseScore.Healthiness; h != 50 {
+		t.Errorf("expected a recipe without nutritional information to have a healthiness of 50 but got %v", h)
+	}
+	if healthy.BaseScore.Healthiness <= unhealthy.BaseScore.Healthiness {
+		t.Errorf("expected the healthy recipe to be healthier: %v <= %v", healthy.BaseScore.Healthiness, unhealthy.BaseScore.Healthiness)
+	}
+}
@@ -0,0 +1,163 @@
+package osusu
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Identity is an account of a user with an OpenID Connect provider, which
+// users log in with. Users can have identities with multiple providers.
+// Identities are identified by the issuer and subject of the provider,
+// which unlike emails are stable and can not be reused by someone else.
+type Identity struct {
+	gorm.Model
+	UserID uint
+	User   User `json:"-"`
+	// Issuer is the issuer URL of the provider (eg: https://accounts.google.com)
+	Issuer string `gorm:"uniqueIndex:idx_identity_issuer_subject"`
+	// Subject is the ID of the user with the provider
+	Subject string `gorm:"uniqueIndex:idx_identity_issuer_subject"`
+	// Email is the email of the user with the provider when they last logged in
+	Email string
+}
+
+// GoogleIssuer is the issuer URL of Google, which is the provider used in the app.
+const GoogleIssuer = "https://accounts.google.com"
+
+// Claims are the claims about a user from an OpenID Connect provider
+// that are used to log them in. They can be decoded from a verified
+// ID token or from the user info returned by the provider.
+type Claims struct {
+	Issuer        string `json:"iss"`
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	Locale        string `json:"locale"`
+	// Nonce is the nonce that the client requested the ID token with, if any
+	Nonce string `json:"nonce"`
+}
+
+var (
+	// ErrEmailInUse is returned by [Login] when there is already a user with the
+	// email of a new identity, which must be linked from that user instead.
+	ErrEmailInUse = errors.New("there is already an account with this email; log in with the provider of that account and then link this one")
+
+	// ErrIdentityInUse is returned by [User.LinkIdentity] when the identity belongs to another user.
+	ErrIdentityInUse = errors.New("this account is already linked to another user")
+
+	// ErrLastIdentity is returned by [User.UnlinkIdentity] for the last identity
+	// of a user, without which they could not log in.
+	ErrLastIdentity = errors.New("can not unlink the last account that you log in with")
+)
+
+// Login returns the user with the identity in the given claims, creating
+// a new user if there is none. Users that were created before identities were
+// used are found by email if the provider has verified it and the user does
+// not have any identities yet, in which case the identity is linked to them.
+func Login(c *Claims) (*User, error) {
+	if c.Issuer == "" || c.Subject == "" {
+		return nil, errors.New("claims are missing the issuer or subject")
+	}
+	identity := &Identity{}
+	err := DB.Preload("User").First(identity, "issuer = ? AND subject = ?", c.Issuer, c.Subject).Error
+	if err == nil {
+		if identity.Email != c.Email {
+			identity.Email = c.Email
+			err := DB.Save(identity).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &identity.User, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user := &User{}
+	err = DB.First(user, "email = ?", c.Email).Error
+	if err == nil {
+		var n int64
+		err := DB.Model(&Identity{}).Where("user_id = ?", user.ID).Count(&n).Error
+		if err != nil {
+			return nil, err
+		}
+		err = checkEmailLink(c, n)
+		if err != nil {
+			return nil, err
+		}
+		_, err = user.LinkIdentity(c)
+		return user, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user = &User{Email: c.Email, Name: c.Name, Picture: c.Picture, Locale: c.Locale}
+	err = DB.Create(user).Error
+	if err != nil {
+		return nil, err
+	}
+	_, err = user.LinkIdentity(c)
+	return user, err
+}
+
+// checkEmailLink returns [ErrEmailInUse] if the identity in the given claims
+// can not be linked to the existing user with its email, who has the given
+// number of identities. Only users without identities can be found by email,
+// and only if the provider has verified the email, since otherwise anyone
+// could take over an account by making an identity with its email.
+func checkEmailLink(c *Claims, identities int64) error {
+	if identities > 0 || !c.EmailVerified {
+		return ErrEmailInUse
+	}
+	return nil
+}
+
+// LinkIdentity links the identity in the given claims to the user so that
+// they can log in with it, and returns it. It does nothing if it is already
+// linked to the user, and returns [ErrIdentityInUse] if it is linked to another user.
+func (u *User) LinkIdentity(c *Claims) (*Identity, error) {
+	identity := &Identity{}
+	err := DB.First(identity, "issuer = ? AND subject = ?", c.Issuer, c.Subject).Error
+	if err == nil {
+		if identity.UserID != u.ID {
+			return nil, ErrIdentityInUse
+		}
+		return identity, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	identity = &Identity{UserID: u.ID, Issuer: c.Issuer, Subject: c.Subject, Email: c.Email}
+	return identity, DB.Create(identity).Error
+}
+
+// Identities returns the identities of the user.
+func (u *User) Identities() ([]*Identity, error) {
+	var identities []*Identity
+	return identities, DB.Find(&identities, "user_id = ?", u.ID).Error
+}
+
+// UnlinkIdentity deletes the identity of the user with the given ID.
+func (u *User) UnlinkIdentity(id uint) error {
+	identities, err := u.Identities()
+	if err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		if identity.ID != id {
+			continue
+		}
+		if len(identities) == 1 {
+			return ErrLastIdentity
+		}
+		// identities are deleted permanently so that they can be linked again
+		return DB.Unscoped().Delete(identity).Error
+	}
+	return fmt.Errorf("identity %d: %w", id, gorm.ErrRecordNotFound)
+}
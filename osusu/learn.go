@@ -0,0 +1,159 @@
+package osusu
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/optimize"
+	"gorm.io/gorm"
+)
+
+// ChoiceKinds are the kinds of choices that a user can make between recommendations.
+type ChoiceKinds int32 //enums:enum
+
+const (
+	// EntryChoice is when a user creates an entry for a meal in the search results.
+	EntryChoice ChoiceKinds = iota
+	// DiscoverChoice is when a user adds a recipe from the discover results.
+	DiscoverChoice
+)
+
+// Choice is a record of a user choosing one meal or recipe from recommendations,
+// which is used to learn the importances that the user implicitly gives to each
+// dimension in [LearnImportances].
+type Choice struct {
+	gorm.Model `display:"-"`
+	UserID     uint `display:"-"`
+	Time       time.Time
+	Kind       ChoiceKinds
+	// Chosen is the score of the meal or recipe that was chosen
+	Chosen Score `gorm:"serializer:json"`
+	// Alternatives are the scores of the other meals or recipes that were recommended
+	Alternatives []Score `gorm:"serializer:json"`
+}
+
+// MaxChoiceAlternatives is the maximum number of alternatives stored in a [Choice].
+const MaxChoiceAlternatives = 20
+
+// MinChoices is the minimum number of choices needed to learn importances.
+const MinChoices = 5
+
+// NewChoice returns a new [Choice] for the given user choosing the item with the given
+// score out of the given scores of all of the recommended items, which can include the
+// chosen score. The alternatives are the highest scoring of the other items.
+func NewChoice(userID uint, kind ChoiceKinds, chosen *Score, all []*Score) *Choice {
+	alts := slices.DeleteFunc(slices.Clone(all), func(s *Score) bool {
+		return s == chosen
+	})
+	slices.SortFunc(alts, func(a, b *Score) int {
+		return cmp.Compare(b.Total, a.Total)
+	})
+	choice := &Choice{
+		UserID: userID,
+		Time:   time.Now(),
+		Kind:   kind,
+		Chosen: *chosen,
+	}
+	for _, alt := range alts[:min(len(alts), MaxChoiceAlternatives)] {
+		choice.Alternatives = append(choice.Alternatives, *alt)
+	}
+	return choice
+}
+
+// LearnedImportances are importances learned from the choices of a user.
+type LearnedImportances struct {
+	// Coefficients are the fitted coefficients of the model for each dimension,
+	// keyed by name; higher coefficients mean that the dimension has more
+	// influence on what the user chooses
+	Coefficients map[string]float64
+	// Importances are the suggested importances for each dimension, keyed by name
+	Importances map[string]int
+	// Choices is the number of choices the model was fitted to
+	Choices int
+}
+
+// LearnImportances fits a pairwise logistic model to the given choices, in which
+// the probability of choosing a meal over an alternative is the logistic function
+// of the weighted difference between their scores on each of the given dimensions. The
+// coefficients are L2 regularized with the given strength, and the suggested
+// importances are the positive coefficients scaled so that the largest is 100.
+func LearnImportances(choices []Choice, dims []*Dimension, regularization float64) (*LearnedImportances, error) {
+	if len(choices) < MinChoices {
+		return nil, errors.New("not enough choices to learn importances; create more entries and add more recipes first")
+	}
+	// each row is the difference between the chosen and alternative scores, scaled to about -1 to 1
+	var rows [][]float64
+	for _, c := range choices {
+		for _, alt := range c.Alternatives {
+			row := make([]float64, len(dims))
+			for j, d := range dims {
+				cv, cok := c.Chosen.Value(d)
+				av, aok := alt.Value(d)
+				if cok && aok {
+					row[j] = (cv - av) / 100
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no alternatives to learn importances from")
+	}
+
+	p := optimize.Problem{
+		Func: func(w []float64) float64 {
+			loss := regularization / 2 * floats.Dot(w, w)
+			for _, row := range rows {
+				// log(1 + exp(-w·x)), computed stably
+				z := floats.Dot(w, row)
+				loss += math.Max(-z, 0) + math.Log1p(math.Exp(-math.Abs(z)))
+			}
+			return loss
+		},
+		Grad: func(grad, w []float64) {
+			for j := range grad {
+				grad[j] = regularization * w[j]
+			}
+			for _, row := range rows {
+				z := floats.Dot(w, row)
+				// derivative of log(1 + exp(-z)) is -sigmoid(-z)
+				floats.AddScaled(grad, -1/(1+math.Exp(z)), row)
+			}
+		},
+	}
+	res, err := optimize.Minimize(p, make([]float64, len(dims)), nil, &optimize.BFGS{})
+	if err != nil {
+		return nil, err
+	}
+
+	li := &LearnedImportances{
+		Coefficients: map[string]float64{},
+		Importances:  map[string]int{},
+		Choices:      len(choices),
+	}
+	maxCoef := floats.Max(res.X)
+	for j, d := range dims {
+		coef := res.X[j]
+		li.Coefficients[d.Name] = coef
+		imp := 0
+		if coef > 0 && maxCoef > 0 {
+			imp = Round(100 * coef / maxCoef)
+		}
+		li.Importances[d.Name] = imp
+	}
+	return li, nil
+}
+
+// Apply sets the importances of the given dimensions in the given options
+// to the learned importances.
+func (li *LearnedImportances) Apply(opts *Options, dims []*Dimension) {
+	for _, d := range dims {
+		if imp, ok := li.Importances[d.Name]; ok {
+			opts.SetImportance(d, imp)
+		}
+	}
+}
@@ -12,21 +12,31 @@ import (
 type Meal struct {
 	gorm.Model  `display:"-"`
 	GroupID     uint  `display:"-"`
-	Group       Group `display:"-"`
+	Group       Group `display:"-" json:"-"`
 	Name        string
 	Description string
 	Image       string
+	SourceURL   string `label:"Source URL"`
 	Source      Sources
 	Category    Categories
 	Cuisine     Cuisines
+	// Nutrition is the nutritional information for one serving of the meal
+	Nutrition Nutrition `gorm:"embedded;embeddedPrefix:nutrition_"`
+	// CostPerServing is the cost of one serving of the meal, in the currency of the group's price table
+	CostPerServing float64 `label:"Cost per serving"`
+	// CreatorID is the user who created the meal, or 0 if unknown
+	CreatorID uint `display:"-"`
+	// DeletionID is the ID of the deletion of the meal, which its entries
+	// that are deleted with it also have, so that only they are restored with it
+	DeletionID string `display:"-"`
 }
 
 type Entry struct {
 	gorm.Model  `display:"-"`
 	MealID      uint `display:"-"`
-	Meal        Meal `display:"-"`
+	Meal        Meal `display:"-" json:"-"`
 	UserID      uint `display:"-"`
-	User        User `display:"-"`
+	User        User `display:"-" json:"-"`
 	Time        time.Time
 	Category    Categories
 	Source      Sources
@@ -34,6 +44,22 @@ type Entry struct {
 	Cost        int `display:"slider" min:"0" def:"50" max:"100"`
 	Effort      int `display:"slider" min:"0" def:"50" max:"100"`
 	Healthiness int `display:"slider" min:"0" def:"50" max:"100"`
+	// Ratings are the ratings on the custom dimensions of the group, keyed by name
+	Ratings map[string]int `gorm:"serializer:json"`
+	// Servings is the number of servings of the meal eaten
+	Servings float64 `min:"0" def:"1" step:"0.5"`
+	// DeletionID is the [Meal.DeletionID] of the meal if the entry was deleted with it
+	DeletionID string `display:"-"`
+}
+
+// BeforeCreate sets the creator of the meal to the actor of the change
+// (see [WithActor]) if there is one, so that meals can not be created
+// as created by other users, who could then purge them.
+func (m *Meal) BeforeCreate(tx *gorm.DB) error {
+	if actor := ActorFrom(tx.Statement.Context); actor != 0 {
+		m.CreatorID = actor
+	}
+	return nil
 }
 
 type Sources int64 //enums:bitflag
@@ -0,0 +1,132 @@
+package osusu
+
+import (
+	"math"
+	"slices"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// NormStrategies are the strategies that can be used to normalize raw
+// score values to scores from 0 to 100 in [ComputeNormScores].
+type NormStrategies int32 //enums:enum
+
+const (
+	// NormZScore uses z-scores based on the mean and standard deviation
+	// centered at 50, which are affected by outliers. It is the default,
+	// since scores were always normalized this way before the other strategies.
+	NormZScore NormStrategies = iota
+	// NormPercentile uses the percentile rank of each value, which only
+	// depends on the order of the values and is not affected by outliers.
+	NormPercentile
+	// NormRobust uses z-scores based on the median and the median absolute
+	// deviation, which are not affected by outliers, centered at 50.
+	NormRobust
+	// NormMinMax scales the values linearly from the minimum to the maximum
+	// after clipping them to the 1st and 99th percentiles.
+	NormMinMax
+)
+
+// normClipQuantile is the quantile at which values are clipped in [NormMinMax].
+const normClipQuantile = 0.01
+
+// Normalize returns the given values normalized to scores from 0 to 100 using the
+// strategy. Infinite values become 0 or 100, NaN values become 50, and neither
+// affects the normalization of the other values.
+func (ns NormStrategies) Normalize(values []float64) []float64 {
+	res := make([]float64, len(values))
+	finite := []float64{}
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			finite = append(finite, v)
+		}
+	}
+	slices.Sort(finite)
+
+	var norm func(v float64) float64
+	switch {
+	case len(finite) == 0:
+		norm = func(v float64) float64 { return 50 }
+	case ns == NormPercentile:
+		norm = func(v float64) float64 {
+			if len(finite) < 2 {
+				return 50
+			}
+			// ties get the average of their ranks
+			below, _ := slices.BinarySearch(finite, v)
+			above, _ := slices.BinarySearchFunc(finite, v, func(e, t float64) int {
+				if e <= t {
+					return -1
+				}
+				return 1
+			})
+			rank := float64(below+above-1) / 2
+			return 100 * rank / float64(len(finite)-1)
+		}
+	case ns == NormRobust:
+		median := sortedMedian(finite)
+		devs := make([]float64, len(finite))
+		for i, v := range finite {
+			devs[i] = math.Abs(v - median)
+		}
+		slices.Sort(devs)
+		// the scale factors make both estimates consistent with the standard deviation of normal data
+		scale := 1.4826 * sortedMedian(devs)
+		if scale == 0 {
+			// more than half of the values are the same, so we use the mean absolute deviation
+			scale = 1.2533 * stat.Mean(devs, nil)
+		}
+		norm = func(v float64) float64 {
+			if scale == 0 {
+				return 50
+			}
+			return 50*(v-median)/(3*scale) + 50
+		}
+	case ns == NormMinMax:
+		lo := stat.Quantile(normClipQuantile, stat.LinInterp, finite, nil)
+		hi := stat.Quantile(1-normClipQuantile, stat.LinInterp, finite, nil)
+		norm = func(v float64) float64 {
+			if hi <= lo {
+				return 50
+			}
+			return 100 * (min(max(v, lo), hi) - lo) / (hi - lo)
+		}
+	default:
+		mean, std := stat.MeanStdDev(finite, nil)
+		norm = func(v float64) float64 {
+			if std == 0 || math.IsNaN(std) {
+				return 50
+			}
+			return 50*(v-mean)/(3*std) + 50
+		}
+	}
+
+	for i, v := range values {
+		switch {
+		case math.IsNaN(v):
+			res[i] = 50
+		case math.IsInf(v, 1):
+			res[i] = 100
+		case math.IsInf(v, -1):
+			res[i] = 0
+		default:
+			// extreme values can overflow the normalization
+			if n := norm(v); math.IsNaN(n) {
+				res[i] = 50
+			} else {
+				res[i] = min(max(n, 0), 100)
+			}
+		}
+	}
+	return res
+}
+
+// sortedMedian returns the median of the given sorted values, which is
+// the mean of the two middle values if there is an even number of them.
+func sortedMedian(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return sorted[n/2-1]/2 + sorted[n/2]/2
+}
@@ -0,0 +1,145 @@
+package osusu
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+// checkNormalized checks that the given normalized values are valid scores for the
+// given values: one from 0 to 100 for each value, in the same order as the values.
+func checkNormalized(t *testing.T, ns NormStrategies, values, res []float64) {
+	t.Helper()
+	if len(res) != len(values) {
+		t.Fatalf("%v: expected %d values but got %d", ns, len(values), len(res))
+	}
+	for i, r := range res {
+		if math.IsNaN(r) || r < 0 || r > 100 {
+			t.Errorf("%v: value %v was normalized to %v, which is not from 0 to 100", ns, values[i], r)
+		}
+		for j, v := range values {
+			if values[i] < v && r > res[j]+tolerance {
+				t.Errorf("%v: %v was normalized to %v, which is more than %v for %v", ns, values[i], r, res[j], v)
+			}
+		}
+	}
+}
+
+func TestNormalizeAdversarial(t *testing.T) {
+	outlier := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 5000}
+	tests := map[string][]float64{
+		"empty":            {},
+		"single":           {42},
+		"all equal":        {7, 7, 7, 7, 7},
+		"mostly equal":     {3, 3, 3, 3, 3, 3, 4},
+		"outlier":          outlier,
+		"hours since 1970": {476000, 476024, 476100, 476500, 480000},
+		"negative":         {-5, -100, -1e6, 0},
+		"extreme":          {math.MaxFloat64, -math.MaxFloat64, 0, 1},
+		"tiny":             {math.SmallestNonzeroFloat64, 0, -math.SmallestNonzeroFloat64},
+		"NaN and Inf":      {math.NaN(), math.Inf(1), math.Inf(-1), 1, 2, 3},
+		"only NaN":         {math.NaN(), math.NaN()},
+		"only Inf":         {math.Inf(1), math.Inf(-1)},
+	}
+	for name, values := range tests {
+		for _, ns := range NormStrategiesValues() {
+			t.Run(name+"/"+ns.String(), func(t *testing.T) {
+				res := ns.Normalize(values)
+				checkNormalized(t, ns, values, res)
+				for i, v := range values {
+					want := -1.0
+					switch {
+					case math.IsNaN(v):
+						want = 50
+					case math.IsInf(v, 1):
+						want = 100
+					case math.IsInf(v, -1):
+						want = 0
+					case name == "single" || name == "all equal":
+						want = 50
+					}
+					if want >= 0 && res[i] != want {
+						t.Errorf("expected %v to be normalized to %v but got %v", v, want, res[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNormalizeNonFinite(t *testing.T) {
+	// non-finite values do not affect the normalization of the other values
+	finite := []float64{1, 2, 3, 10}
+	all := append([]float64{math.NaN(), math.Inf(1)}, append(slices.Clone(finite), math.Inf(-1))...)
+	for _, ns := range NormStrategiesValues() {
+		want := ns.Normalize(finite)
+		got := ns.Normalize(all)[2 : 2+len(finite)]
+		if !slices.Equal(got, want) {
+			t.Errorf("%v: expected %v but got %v", ns, want, got)
+		}
+	}
+}
+
+func TestNormalizeOutliers(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 5000}
+	spread := func(ns NormStrategies) float64 {
+		res := ns.Normalize(values)
+		return res[8] - res[0]
+	}
+	// the outlier squashes the other values together with z-scores
+	if s := spread(NormZScore); s > 1 {
+		t.Errorf("expected z-scores of 1 to 9 to be less than 1 apart but got %v", s)
+	}
+	// but not with the robust strategies
+	for _, ns := range []NormStrategies{NormPercentile, NormRobust} {
+		if s := spread(ns); s < 25 {
+			t.Errorf("%v: expected the scores of 1 to 9 to be at least 25 apart but got %v", ns, s)
+		}
+	}
+
+	res := NormPercentile.Normalize([]float64{10, 20, 20, 30})
+	if want := []float64{0, 50, 50, 100}; !slices.Equal(res, want) {
+		t.Errorf("expected percentiles %v with tied ranks averaged but got %v", want, res)
+	}
+	res = NormRobust.Normalize([]float64{1, 2, 3, 4, 5000})
+	if res[2] != 50 {
+		t.Errorf("expected the median to be normalized to 50 but got %v", res[2])
+	}
+}
+
+func TestNormalizeQuick(t *testing.T) {
+	f := func(values []float64, ns uint8) bool {
+		s := NormStrategiesValues()[int(ns)%len(NormStrategiesValues())]
+		res := s.Normalize(values)
+		if len(res) != len(values) {
+			return false
+		}
+		for i, r := range res {
+			if math.IsNaN(r) || r < 0 || r > 100 {
+				return false
+			}
+			for j, v := range values {
+				if values[i] < v && r > res[j]+tolerance {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNormStrategyDefault(t *testing.T) {
+	// scores were normalized with z-scores before there were other strategies,
+	// so it stays the default for existing options
+	var ns NormStrategies
+	if ns != NormZScore {
+		t.Errorf("expected the default strategy to be %v but got %v", NormZScore, ns)
+	}
+	if DefaultOptions().Normalization != NormZScore {
+		t.Errorf("expected the default options to use %v", NormZScore)
+	}
+}
@@ -0,0 +1,135 @@
+package osusu
+
+import (
+	"math"
+	"time"
+)
+
+// Nutrition represents the nutritional information of a recipe or meal
+type Nutrition struct {
+	Calories       int // unit: Calories (kcal)
+	Carbohydrate   int // g
+	Cholesterol    int // mg
+	Fiber          int // g
+	Protein        int // g
+	Fat            int // g
+	SaturatedFat   int // g
+	UnsaturatedFat int // g
+	Sodium         int // mg
+	Sugar          int // g
+}
+
+// Scale returns the nutrition multiplied by the given factor,
+// rounded to the nearest unit.
+func (n Nutrition) Scale(factor float64) Nutrition {
+	scale := func(v int) int {
+		return int(math.Round(float64(v) * factor))
+	}
+	return Nutrition{
+		Calories:       scale(n.Calories),
+		Carbohydrate:   scale(n.Carbohydrate),
+		Cholesterol:    scale(n.Cholesterol),
+		Fiber:          scale(n.Fiber),
+		Protein:        scale(n.Protein),
+		Fat:            scale(n.Fat),
+		SaturatedFat:   scale(n.SaturatedFat),
+		UnsaturatedFat: scale(n.UnsaturatedFat),
+		Sodium:         scale(n.Sodium),
+		Sugar:          scale(n.Sugar),
+	}
+}
+
+// Add adds the given nutrition to the nutrition.
+func (n *Nutrition) Add(o Nutrition) {
+	n.Calories += o.Calories
+	n.Carbohydrate += o.Carbohydrate
+	n.Cholesterol += o.Cholesterol
+	n.Fiber += o.Fiber
+	n.Protein += o.Protein
+	n.Fat += o.Fat
+	n.SaturatedFat += o.SaturatedFat
+	n.UnsaturatedFat += o.UnsaturatedFat
+	n.Sodium += o.Sodium
+	n.Sugar += o.Sugar
+}
+
+// IsZero returns whether no nutritional information is set.
+func (n Nutrition) IsZero() bool {
+	return n == Nutrition{}
+}
+
+// Nutrition returns the nutrition eaten in the entry, based on the
+// nutrition of one serving of its meal and the number of servings.
+// The meal of the entry must be loaded.
+func (e *Entry) Nutrition() Nutrition {
+	return e.Meal.Nutrition.Scale(e.Servings)
+}
+
+// NutritionTargets are the daily nutrition targets of a user.
+// A target of 0 means that there is no target.
+type NutritionTargets struct {
+	Calories int // unit: Calories (kcal)
+	Protein  int // g
+	Sodium   int // mg
+	Sugar    int // g
+}
+
+// NutritionSummary is the total nutrition eaten by a user
+// in the time period from Start (inclusive) to End (exclusive).
+type NutritionSummary struct {
+	Start     time.Time
+	End       time.Time
+	Nutrition Nutrition
+	// Days is the number of days in the time period, which is used to scale the targets
+	Days int
+}
+
+// Targets returns the given daily targets scaled to the
+// number of days in the summary.
+func (ns *NutritionSummary) Targets(targets NutritionTargets) NutritionTargets {
+	return NutritionTargets{
+		Calories: targets.Calories * ns.Days,
+		Protein:  targets.Protein * ns.Days,
+		Sodium:   targets.Sodium * ns.Days,
+		Sugar:    targets.Sugar * ns.Days,
+	}
+}
+
+// DailyNutrition returns the nutrition summaries for each of the
+// given number of days ending with the day containing the given time,
+// in order from newest to oldest. The meals of the entries must be loaded.
+func DailyNutrition(entries []Entry, now time.Time, days int) []NutritionSummary {
+	return summarizeNutrition(entries, now, days, 1)
+}
+
+// WeeklyNutrition returns the nutrition summaries for each of the
+// given number of weeks ending with the week containing the given time,
+// in order from newest to oldest. Weeks start on Sunday. The meals of
+// the entries must be loaded.
+func WeeklyNutrition(entries []Entry, now time.Time, weeks int) []NutritionSummary {
+	y, m, d := now.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	start = start.AddDate(0, 0, -int(start.Weekday()))
+	return summarizeNutrition(entries, start, weeks, 7)
+}
+
+// summarizeNutrition returns n nutrition summaries, each of the given number
+// of days, with the first one starting on the day containing the given time.
+func summarizeNutrition(entries []Entry, start time.Time, n int, days int) []NutritionSummary {
+	y, m, d := start.Date()
+	start = time.Date(y, m, d, 0, 0, 0, 0, start.Location())
+	res := make([]NutritionSummary, n)
+	for i := range res {
+		s := &res[i]
+		s.Start = start.AddDate(0, 0, -i*days)
+		s.End = s.Start.AddDate(0, 0, days)
+		s.Days = days
+		for _, entry := range entries {
+			if entry.Time.Before(s.Start) || !entry.Time.Before(s.End) {
+				continue
+			}
+			s.Nutrition.Add(entry.Nutrition())
+		}
+	}
+	return res
+}
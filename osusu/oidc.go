@@ -0,0 +1,79 @@
+package osusu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// Provider is an OpenID Connect provider whose ID tokens are accepted by a [Verifier].
+type Provider struct {
+	// Name is the name that clients use for the provider (eg: google)
+	Name string
+	// Issuer is the issuer URL of the provider (eg: https://accounts.google.com)
+	Issuer string
+	// ClientIDs are the client IDs of the apps that ID tokens can be issued to,
+	// one of which must be in the audience of each token
+	ClientIDs []string
+}
+
+// Verifier verifies ID tokens from OpenID Connect providers.
+type Verifier struct {
+	providers map[string]*providerVerifier
+}
+
+// providerVerifier is a [Provider] with its verifier.
+type providerVerifier struct {
+	*Provider
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewVerifier returns a new verifier for the given providers, getting
+// the configuration of each from its discovery document.
+func NewVerifier(ctx context.Context, providers []*Provider) (*Verifier, error) {
+	v := &Verifier{providers: map[string]*providerVerifier{}}
+	for _, p := range providers {
+		if len(p.ClientIDs) == 0 {
+			return nil, fmt.Errorf("provider %s has no client IDs", p.Name)
+		}
+		op, err := oidc.NewProvider(ctx, p.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("error getting provider %s: %w", p.Name, err)
+		}
+		// the audience is checked in Verify since there can be multiple client IDs
+		v.providers[p.Name] = &providerVerifier{p, op.Verifier(&oidc.Config{SkipClientIDCheck: true})}
+	}
+	return v, nil
+}
+
+// Verify verifies the signature, issuer, audience, and expiry of the given raw
+// ID token from the provider with the given name, and that it has the given nonce,
+// and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, provider, rawIDToken, nonce string) (*Claims, error) {
+	p, ok := v.providers[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", provider)
+	}
+	token, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(token.Audience, func(aud string) bool {
+		return slices.Contains(p.ClientIDs, aud)
+	}) {
+		return nil, fmt.Errorf("ID token was issued to another app: %v", token.Audience)
+	}
+	if nonce == "" || token.Nonce != nonce {
+		return nil, errors.New("ID token does not have the expected nonce")
+	}
+	c := &Claims{}
+	err = token.Claims(c)
+	if err != nil {
+		return nil, err
+	}
+	c.Issuer, c.Subject = token.Issuer, token.Subject
+	return c, nil
+}
@@ -0,0 +1,192 @@
+package osusu
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIssuer is a mock OpenID Connect provider that serves a discovery
+// document and the key that it signs ID tokens with.
+type testIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+}
+
+// newTestIssuer returns a new running [testIssuer].
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti := &testIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                ti.URL,
+			"jwks_uri":                              ti.URL + "/keys",
+			"authorization_endpoint":                ti.URL + "/auth",
+			"token_endpoint":                        ti.URL + "/token",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		enc := base64.RawURLEncoding
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   enc.EncodeToString(key.N.Bytes()),
+				"e":   enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	ti.Server = httptest.NewServer(mux)
+	t.Cleanup(ti.Close)
+	return ti
+}
+
+// token returns an ID token with the given claims signed with the given key,
+// or the key of the issuer if it is nil.
+func (ti *testIssuer) token(t *testing.T, claims map[string]any, key *rsa.PrivateKey) string {
+	t.Helper()
+	if key == nil {
+		key = ti.key
+	}
+	enc := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	payload := enc(map[string]any{"alg": "RS256", "typ": "JWT", "kid": "test"}) + "." + enc(claims)
+	hash := sha256.Sum256([]byte(payload))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	ti := newTestIssuer(t)
+	v, err := NewVerifier(ctx, []*Provider{{Name: "test", Issuer: ti.URL, ClientIDs: []string{"web", "desktop"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":            ti.URL,
+			"sub":            "123",
+			"aud":            "web",
+			"exp":            now.Add(time.Hour).Unix(),
+			"iat":            now.Unix(),
+			"nonce":          "abc",
+			"email":          "a@example.com",
+			"email_verified": true,
+			"name":           "A",
+		}
+	}
+	tests := []struct {
+		name     string
+		provider string
+		claims   func(c map[string]any)
+		key      *rsa.PrivateKey
+		nonce    string
+		err      string
+	}{
+		{name: "valid", nonce: "abc"},
+		{name: "other client ID", claims: func(c map[string]any) { c["aud"] = []string{"other", "desktop"} }, nonce: "abc"},
+		{name: "unknown provider", provider: "google", nonce: "abc", err: "unknown provider"},
+		{name: "wrong issuer", claims: func(c map[string]any) { c["iss"] = GoogleIssuer }, nonce: "abc", err: "different provider"},
+		{name: "wrong audience", claims: func(c map[string]any) { c["aud"] = "other" }, nonce: "abc", err: "another app"},
+		{name: "expired", claims: func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() }, nonce: "abc", err: "expired"},
+		{name: "wrong nonce", nonce: "xyz", err: "nonce"},
+		{name: "missing nonce", claims: func(c map[string]any) { delete(c, "nonce") }, nonce: "abc", err: "nonce"},
+		{name: "empty nonce", claims: func(c map[string]any) { c["nonce"] = "" }, nonce: "", err: "nonce"},
+		{name: "wrong key", key: otherKey, nonce: "abc", err: "signature"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := valid()
+			if test.claims != nil {
+				test.claims(claims)
+			}
+			provider := test.provider
+			if provider == "" {
+				provider = "test"
+			}
+			c, err := v.Verify(ctx, provider, ti.token(t, claims, test.key), test.nonce)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q but got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Claims{Issuer: ti.URL, Subject: "123", Email: "a@example.com", EmailVerified: true, Name: "A", Nonce: "abc"}
+			if *c != want {
+				t.Errorf("expected %+v but got %+v", want, *c)
+			}
+		})
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	ctx := context.Background()
+	ti := newTestIssuer(t)
+	_, err := NewVerifier(ctx, []*Provider{{Name: "test", Issuer: ti.URL}})
+	if err == nil {
+		t.Error("expected an error for a provider without client IDs")
+	}
+	// the issuer must match the one in the discovery document
+	_, err = NewVerifier(ctx, []*Provider{{Name: "test", Issuer: ti.URL + "/", ClientIDs: []string{"web"}}})
+	if err == nil {
+		t.Error("expected an error for a provider with another issuer")
+	}
+}
+
+// TestCheckEmailLink tests when a new identity is linked to an existing user
+// with the same email in [Login]; the rest of Login needs a database.
+func TestCheckEmailLink(t *testing.T) {
+	tests := []struct {
+		verified   bool
+		identities int64
+		want       error
+	}{
+		{true, 0, nil},
+		{false, 0, ErrEmailInUse},
+		{true, 1, ErrEmailInUse},
+		{false, 2, ErrEmailInUse},
+	}
+	for _, test := range tests {
+		got := checkEmailLink(&Claims{Email: "a@example.com", EmailVerified: test.verified}, test.identities)
+		if !errors.Is(got, test.want) {
+			t.Errorf("verified %v, %d identities: expected %v but got %v", test.verified, test.identities, test.want, got)
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package osusu
 
-import "cogentcore.org/core/enums"
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"cogentcore.org/core/enums"
+)
 
 type Options struct {
 	Categories            Categories
@@ -11,6 +18,22 @@ type Options struct {
 	CostImportance        int `display:"slider" min:"0" def:"50" max:"100"`
 	EffortImportance      int `display:"slider" min:"0" def:"50" max:"100"`
 	HealthinessImportance int `display:"slider" min:"0" def:"50" max:"100"`
+	// Importances are the importances of the custom dimensions, keyed by name
+	Importances map[string]int
+	// RecencyCurve is the curve used to compute the recency score of a meal from the time since it was last eaten
+	RecencyCurve RecencyCurves
+	// ComfortPeriods are the number of days after eating a meal in each category until it is fully recent again
+	ComfortPeriods ComfortPeriods
+	// RatingHalfLife is the number of days after which the ratings of an entry count half as much, or 0 for all ratings to count equally
+	RatingHalfLife int `min:"0" def:"365"`
+	// PriorStrength is the number of entries that the average ratings of all meals count as when scoring a meal, which keeps meals with few entries from having extreme scores
+	PriorStrength int `min:"0" def:"2"`
+	// Diversity is how much Discover favors recipes that are different from the ones above them over recipes with the highest scores
+	Diversity int `display:"slider" min:"0" def:"30" max:"100"`
+	// CollaborativeWeight is how much Discover uses the ratings of users in other groups that share their data relative to the content of the recipes, which is only used if your group also shares its data
+	CollaborativeWeight int `display:"slider" min:"0" def:"25" max:"100"`
+	// Normalization is how Discover turns the raw information about recipes, like their ratings and estimated costs, into scores from 0 to 100
+	Normalization NormStrategies
 }
 
 func DefaultOptions() *Options {
@@ -20,6 +43,11 @@ func DefaultOptions() *Options {
 		CostImportance:        50,
 		EffortImportance:      50,
 		HealthinessImportance: 50,
+		ComfortPeriods:        DefaultComfortPeriods(),
+		RatingHalfLife:        365,
+		PriorStrength:         2,
+		Diversity:             30,
+		CollaborativeWeight:   25,
 	}
 	for _, v := range opts.Categories.Values() {
 		opts.Categories.SetFlag(true, v.(enums.BitFlag))
@@ -32,3 +60,37 @@ func DefaultOptions() *Options {
 	}
 	return opts
 }
+
+// IncludesMeal returns whether the given meal matches the
+// category, source, and cuisine filters of the options.
+func (o *Options) IncludesMeal(m *Meal) bool {
+	return m.Category&o.Categories != 0 && m.Source&o.Sources != 0 && m.Cuisine&o.Cuisines != 0
+}
+
+// IncludesRecipe returns whether the given recipe matches
+// the category and cuisine filters of the options.
+func (o *Options) IncludesRecipe(r *Recipe) bool {
+	return r.CategoryFlag&o.Categories != 0 && r.CuisineFlag&o.Cuisines != 0
+}
+
+// SetQuery sets the options from the given URL query parameters, which are named
+// after the fields of the options in any case (eg: categories=Lunch,Dinner and
+// tasteImportance=80). Maps like Importances are given as JSON objects, which are
+// merged into the existing values. Parameters that are not options are ignored.
+func (o *Options) SetQuery(q url.Values) error {
+	v := reflect.ValueOf(o).Elem()
+	t := v.Type()
+	for key, values := range q {
+		f, ok := t.FieldByNameFunc(func(name string) bool {
+			return strings.EqualFold(name, key)
+		})
+		if !ok || len(values) == 0 {
+			continue
+		}
+		err := parseCSV(v.FieldByIndex(f.Index), values[len(values)-1])
+		if err != nil {
+			return fmt.Errorf("invalid value for option %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
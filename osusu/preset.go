@@ -0,0 +1,97 @@
+package osusu
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Preset is a named set of options saved by a user.
+type Preset struct {
+	gorm.Model `display:"-"`
+	UserID     uint `display:"-"`
+	Name       string
+	Options    Options `gorm:"serializer:json" display:"-"`
+}
+
+// DefaultPresets returns the presets that users start with.
+func DefaultPresets() []*Preset {
+	def := &Preset{Name: "Default", Options: *DefaultOptions()}
+
+	quick := &Preset{Name: "Weeknight quick", Options: *DefaultOptions()}
+	quick.Options.EffortImportance = 100
+	quick.Options.CostImportance = 70
+	quick.Options.Categories = 0
+	quick.Options.Categories.SetFlag(true, Lunch, Dinner)
+
+	date := &Preset{Name: "Date night", Options: *DefaultOptions()}
+	date.Options.TasteImportance = 100
+	date.Options.RecencyImportance = 80
+	date.Options.EffortImportance = 20
+	date.Options.CostImportance = 20
+	date.Options.Categories = 0
+	date.Options.Categories.SetFlag(true, Dinner, Dessert)
+
+	healthy := &Preset{Name: "Healthy week", Options: *DefaultOptions()}
+	healthy.Options.HealthinessImportance = 100
+	healthy.Options.TasteImportance = 40
+
+	return []*Preset{def, quick, date, healthy}
+}
+
+// UnmarshalJSON unmarshals the options on top of the default options
+// so that options saved before a field was added use its default value.
+func (o *Options) UnmarshalJSON(b []byte) error {
+	// options does not have the UnmarshalJSON method, which prevents recursion
+	type options Options
+	opts := (*options)(DefaultOptions())
+	err := json.Unmarshal(b, opts)
+	if err != nil {
+		return err
+	}
+	*o = Options(*opts)
+	return nil
+}
+
+// LoadPreset returns the current preset of the given user. If the user does not
+// have a current preset yet, it gives them the [DefaultPresets], along with a
+// copy of the default preset of their group if it has one, which becomes their
+// current preset.
+func LoadPreset(user *User) (*Preset, error) {
+	preset := &Preset{}
+	if user.PresetID != 0 {
+		err := DB.First(preset, user.PresetID).Error
+		if err == nil {
+			return preset, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	presets := DefaultPresets()
+	group := &Group{}
+	err := DB.First(group, user.GroupID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && group.DefaultPresetID != 0 {
+		gp := &Preset{}
+		err := DB.First(gp, group.DefaultPresetID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			presets = append([]*Preset{{Name: gp.Name, Options: gp.Options}}, presets...)
+		}
+	}
+	for _, p := range presets {
+		p.UserID = user.ID
+	}
+	err = DB.Create(&presets).Error
+	if err != nil {
+		return nil, err
+	}
+	user.PresetID = presets[0].ID
+	return presets[0], DB.Save(user).Error
+}
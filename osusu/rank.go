@@ -0,0 +1,72 @@
+package osusu
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// ScoredMeal is a meal with its score for a user.
+type ScoredMeal struct {
+	Meal  *Meal
+	Score *Score
+	// Entries are the entries of the user for the meal
+	Entries []Entry
+}
+
+// ScoreMeals returns the meals of the group of the given user that match the
+// filters of the given options with their scores for the user on the given
+// dimensions of the group, sorted from the highest total score to the lowest.
+func ScoreMeals(user *User, opts *Options, dims []*Dimension) ([]*ScoredMeal, error) {
+	var meals []*Meal
+	err := DB.Find(&meals, "group_id = ?", user.GroupID).Error
+	if err != nil {
+		return nil, err
+	}
+	var groupEntries []Entry
+	err = DB.Find(&groupEntries, "meal_id IN (?)", DB.Model(&Meal{}).Select("id").Where("group_id = ?", user.GroupID)).Error
+	if err != nil {
+		return nil, err
+	}
+	userEntries := []Entry{}
+	mealEntries := map[uint][]Entry{}
+	for _, entry := range groupEntries {
+		if entry.UserID == user.ID {
+			userEntries = append(userEntries, entry)
+			mealEntries[entry.MealID] = append(mealEntries[entry.MealID], entry)
+		}
+	}
+	now := time.Now()
+	prior := ComputePrior(userEntries, groupEntries, opts, dims, now)
+
+	res := []*ScoredMeal{}
+	for _, meal := range meals {
+		if !opts.IncludesMeal(meal) {
+			continue
+		}
+		score := meal.Score(mealEntries[meal.ID], prior, opts, dims, now)
+		score.ComputeTotal(opts, dims)
+		res = append(res, &ScoredMeal{Meal: meal, Score: score, Entries: mealEntries[meal.ID]})
+	}
+	slices.SortStableFunc(res, func(a, b *ScoredMeal) int {
+		return cmp.Compare(b.Score.Total, a.Score.Total)
+	})
+	return res, nil
+}
+
+// NewEntryChoice returns a new [EntryChoice] for the given user choosing the meal
+// with the given ID out of the given scored meals, or nil if it is not one of them.
+func NewEntryChoice(userID, mealID uint, scored []*ScoredMeal) *Choice {
+	all := make([]*Score, len(scored))
+	var chosen *Score
+	for i, sm := range scored {
+		all[i] = sm.Score
+		if sm.Meal.ID == mealID {
+			chosen = sm.Score
+		}
+	}
+	if chosen == nil {
+		return nil
+	}
+	return NewChoice(userID, EntryChoice, chosen, all)
+}
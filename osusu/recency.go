@@ -0,0 +1,112 @@
+package osusu
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultComfortPeriod is the comfort period in days used
+// for meals that do not have any categories.
+const DefaultComfortPeriod = 50
+
+// RecencyCurves are the curves that can be used to compute
+// the recency score of a meal from the time since it was last eaten.
+type RecencyCurves int32 //enums:enum
+
+const (
+	// RecencyLinear increases the recency score at a constant rate until the end of the comfort period.
+	RecencyLinear RecencyCurves = iota
+	// RecencyLogarithmic increases the recency score quickly at first and then more slowly
+	// until the end of the comfort period, so meals become acceptable again sooner.
+	RecencyLogarithmic
+)
+
+// Recency returns the recency score from 0 to 100 for the given number of days
+// since a meal was last eaten and the given comfort period in days, after
+// which the meal is fully recent again.
+func (rc RecencyCurves) Recency(days, period float64) float64 {
+	if period <= 0 || days >= period {
+		return 100
+	}
+	if days <= 0 {
+		return 0
+	}
+	switch rc {
+	case RecencyLogarithmic:
+		return 100 * math.Log1p(days) / math.Log1p(period)
+	}
+	return 100 * days / period
+}
+
+// ComfortPeriods are the number of days after eating a meal in each category
+// until it is fully recent again, so that, for example, breakfast can repeat
+// sooner than dinner.
+type ComfortPeriods struct {
+	Breakfast  int `min:"1"`
+	Brunch     int `min:"1"`
+	Lunch      int `min:"1"`
+	Dinner     int `min:"1"`
+	Dessert    int `min:"1"`
+	Snack      int `min:"1"`
+	Appetizer  int `min:"1"`
+	Side       int `min:"1"`
+	Drink      int `min:"1"`
+	Ingredient int `min:"1"`
+}
+
+// DefaultComfortPeriods returns the default comfort periods.
+func DefaultComfortPeriods() ComfortPeriods {
+	return ComfortPeriods{
+		Breakfast:  7,
+		Brunch:     14,
+		Lunch:      21,
+		Dinner:     50,
+		Dessert:    21,
+		Snack:      7,
+		Appetizer:  30,
+		Side:       21,
+		Drink:      3,
+		Ingredient: 14,
+	}
+}
+
+// Period returns the comfort period in days for the given categories,
+// which is the shortest comfort period of any of the categories.
+func (cp *ComfortPeriods) Period(categories Categories) int {
+	periods := map[Categories]int{
+		Breakfast:  cp.Breakfast,
+		Brunch:     cp.Brunch,
+		Lunch:      cp.Lunch,
+		Dinner:     cp.Dinner,
+		Dessert:    cp.Dessert,
+		Snack:      cp.Snack,
+		Appetizer:  cp.Appetizer,
+		Side:       cp.Side,
+		Drink:      cp.Drink,
+		Ingredient: cp.Ingredient,
+	}
+	res := 0
+	for _, v := range categories.Values() {
+		c := v.(Categories)
+		if !categories.HasFlag(c) || periods[c] <= 0 {
+			continue
+		}
+		if res == 0 || periods[c] < res {
+			res = periods[c]
+		}
+	}
+	if res == 0 {
+		return DefaultComfortPeriod
+	}
+	return res
+}
+
+// RatingWeight returns the weight of the ratings of an entry with the given age
+// based on [Options.RatingHalfLife].
+func (o *Options) RatingWeight(age time.Duration) float64 {
+	if o.RatingHalfLife <= 0 || age <= 0 {
+		return 1
+	}
+	days := age.Hours() / 24
+	return math.Pow(0.5, days/float64(o.RatingHalfLife))
+}
@@ -0,0 +1,136 @@
+package osusu
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// almostEqual returns whether the given values are equal up to rounding errors.
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRecency(t *testing.T) {
+	tests := []struct {
+		curve  RecencyCurves
+		days   float64
+		period float64
+		want   float64
+	}{
+		{RecencyLinear, 0, 10, 0},
+		{RecencyLinear, -1, 10, 0},
+		{RecencyLinear, 5, 10, 50},
+		{RecencyLinear, 2.5, 10, 25},
+		{RecencyLinear, 10, 10, 100},
+		{RecencyLinear, 20, 10, 100},
+		{RecencyLinear, 5, 0, 100},
+		{RecencyLinear, 5, -3, 100},
+		{RecencyLogarithmic, 0, 10, 0},
+		{RecencyLogarithmic, 10, 10, 100},
+		{RecencyLogarithmic, 1, 3, 50},
+		{RecencyLogarithmic, 3, 15, 50},
+		{RecencyLogarithmic, 5, 0, 100},
+	}
+	for _, test := range tests {
+		got := test.curve.Recency(test.days, test.period)
+		if !almostEqual(got, test.want) {
+			t.Errorf("%v.Recency(%v, %v): expected %v but got %v", test.curve, test.days, test.period, test.want, got)
+		}
+	}
+	// the logarithmic curve recovers sooner than the linear one
+	if lin, log := RecencyLinear.Recency(3, 30), RecencyLogarithmic.Recency(3, 30); log <= lin {
+		t.Errorf("expected logarithmic recency %v to be more than linear recency %v", log, lin)
+	}
+}
+
+func TestComfortPeriod(t *testing.T) {
+	cp := DefaultComfortPeriods()
+	zero := ComfortPeriods{Breakfast: 7}
+	tests := []struct {
+		name       string
+		periods    *ComfortPeriods
+		categories []Categories
+		want       int
+	}{
+		{"none", &cp, nil, DefaultComfortPeriod},
+		{"dinner", &cp, []Categories{Dinner}, 50},
+		{"breakfast", &cp, []Categories{Breakfast}, 7},
+		{"shortest", &cp, []Categories{Dinner, Lunch, Drink}, 3},
+		{"zero skipped", &zero, []Categories{Breakfast, Dinner}, 7},
+		{"all zero", &zero, []Categories{Dinner}, DefaultComfortPeriod},
+	}
+	for _, test := range tests {
+		var c Categories
+		for _, f := range test.categories {
+			c.SetFlag(true, f)
+		}
+		got := test.periods.Period(c)
+		if got != test.want {
+			t.Errorf("%s: expected %d but got %d", test.name, test.want, got)
+		}
+	}
+}
+
+func TestRatingWeight(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		halfLife int
+		age      time.Duration
+		want     float64
+	}{
+		{365, 0, 1},
+		{365, -day, 1},
+		{365, 365 * day, 0.5},
+		{365, 730 * day, 0.25},
+		{10, 5 * day, math.Sqrt(0.5)},
+		{0, 1000 * day, 1},
+		{-1, 1000 * day, 1},
+	}
+	for _, test := range tests {
+		o := &Options{RatingHalfLife: test.halfLife}
+		got := o.RatingWeight(test.age)
+		if !almostEqual(got, test.want) {
+			t.Errorf("half life %d, age %v: expected %v but got %v", test.halfLife, test.age, test.want, got)
+		}
+	}
+}
+
+func TestMealScoreTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	opts := &Options{
+		RecencyCurve:   RecencyLinear,
+		ComfortPeriods: DefaultComfortPeriods(),
+		RatingHalfLife: 10,
+	}
+	m := &Meal{}
+	m.Category.SetFlag(true, Breakfast)
+
+	s := m.Score(nil, nil, opts, BuiltinDimensions, now)
+	if s.Recency != 100 {
+		t.Errorf("expected a meal without entries to have a recency of 100 but got %v", s.Recency)
+	}
+
+	entries := []Entry{
+		{Time: now.Add(-10 * day), Taste: 0},
+		{Time: now.Add(-7 * day / 2), Taste: 100},
+	}
+	s = m.Score(entries, nil, opts, BuiltinDimensions, now)
+	// the newest entry is 3.5 days old and the breakfast comfort period is 7 days
+	if !almostEqual(s.Recency, 50) {
+		t.Errorf("expected a recency of 50 but got %v", s.Recency)
+	}
+	// the entries are weighted by their age with a half life of 10 days
+	newer, older := math.Pow(0.5, 0.35), 0.5
+	wantTaste := 100 * newer / (newer + older)
+	if !almostEqual(s.Taste, wantTaste) {
+		t.Errorf("expected a taste of %v but got %v", wantTaste, s.Taste)
+	}
+
+	// the same entries are fully recent at a later time
+	s = m.Score(entries, nil, opts, BuiltinDimensions, now.Add(30*day))
+	if s.Recency != 100 {
+		t.Errorf("expected a recency of 100 a month later but got %v", s.Recency)
+	}
+}
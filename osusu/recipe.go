@@ -1,12 +1,12 @@
 package osusu
 
 import (
+	"encoding/json"
 	"fmt"
 	"html"
+	"math"
 	"strings"
 	"time"
-
-	"gonum.org/v1/gonum/stat"
 )
 
 // A Recipe is an external recipe that can be used for new meal recommendations
@@ -23,6 +23,7 @@ type Recipe struct {
 	Cuisine           []string   `display:"-"`
 	CuisineFlag       Cuisines   `json:"-" label:"Cuisine"`
 	Ingredients       []string
+	Instructions      Instructions  `json:"recipeInstructions"`
 	TotalTime         string        `display:"-"`
 	PrepTime          string        `display:"-"`
 	CookTime          string        `display:"-"`
@@ -35,32 +36,64 @@ type Recipe struct {
 	RatingScore       int `display:"-" json:"-"`
 	RatingWeight      int `display:"-" json:"-"`
 	Nutrition         Nutrition
-	Source            string `json:"-"`
+	CostPerServing    float64 `json:"-" label:"Cost per serving"`
+	CostExplanation   string  `json:"-" label:"Cost estimate"`
+	EffortExplanation string  `json:"-" label:"Effort"`
+	Source            string  `json:"-"`
 	// index score values for base information about a recipe (using info like calories, time, ingredients, etc)
 	BaseScoreIndex Score `json:"-"`
-	// percentile values of BaseScoreIndex
+	// normalized values of BaseScoreIndex
 	BaseScore Score
 	// keyed by meal ID
 	TextEncodingScores map[uint]float32 `json:"-"`
 	// index score values for text encoding based scores
 	EncodingScoreIndex Score `json:"-"`
-	// percentile values of EncodingScoreIndex
+	// normalized values of EncodingScoreIndex
 	EncodingScore Score `json:"-"`
 	Score         Score `json:"-"`
 }
 
-// Nutrition represents the nutritional information of a recipe
-type Nutrition struct {
-	Calories       int // unit: Calories (kcal)
-	Carbohydrate   int // g
-	Cholesterol    int // mg
-	Fiber          int // g
-	Protein        int // g
-	Fat            int // g
-	SaturatedFat   int // g
-	UnsaturatedFat int // g
-	Sodium         int // mg
-	Sugar          int // g
+// Instructions are the instruction steps of a recipe. They can be decoded
+// from JSON as a single string, a list of strings, or a list of schema.org
+// HowToStep and HowToSection objects.
+type Instructions []string
+
+func (is *Instructions) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*is = nil
+		for _, line := range strings.Split(s, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				*is = append(*is, line)
+			}
+		}
+		return nil
+	}
+	// a step is either a string or an object, with sections containing more steps
+	type step struct {
+		Text            string
+		ItemListElement Instructions
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("error decoding recipe instructions: %w", err)
+	}
+	*is = nil
+	for _, r := range raw {
+		if json.Unmarshal(r, &s) == nil {
+			*is = append(*is, s)
+			continue
+		}
+		var st step
+		if err := json.Unmarshal(r, &st); err != nil {
+			return fmt.Errorf("error decoding recipe instruction step: %w", err)
+		}
+		if st.Text != "" {
+			*is = append(*is, st.Text)
+		}
+		*is = append(*is, st.ItemListElement...)
+	}
+	return nil
 }
 
 // Init initializes computed values in the recipe after it has been loaded.
@@ -102,9 +135,21 @@ func (r *Recipe) Init() error {
 		ingredient = strings.ReplaceAll(ingredient, "0.66666668653488", "1/6")
 		r.Ingredients[i] = ingredient
 	}
+	for i, instruction := range r.Instructions {
+		r.Instructions[i] = html.UnescapeString(instruction)
+	}
 	return nil
 }
 
+// ServingNutrition returns the nutritional information for one serving
+// of the recipe, based on the total nutrition of the recipe and its yield.
+func (r *Recipe) ServingNutrition() Nutrition {
+	if r.Yield <= 0 {
+		return r.Nutrition
+	}
+	return r.Nutrition.Scale(1 / float64(r.Yield))
+}
+
 // Text returns all of the text associated with the recipe as one string.
 // It is intended to be used as text encoding model data, so it should
 // not be presented to end-users.
@@ -112,66 +157,60 @@ func (r *Recipe) Text() string {
 	return strings.Join([]string{r.Name, r.Description, strings.Join(r.Ingredients, "\n")}, "\n")
 }
 
-// ComputeBaseScoreIndex computes and sets the base score index for the given recipe
+// ComputeBaseScoreIndex computes and sets the base score index for the given recipe.
+// [Recipe.EstimateCost] should be called first.
 func (r *Recipe) ComputeBaseScoreIndex() {
 	r.BaseScoreIndex = Score{}
-	// just length of ingredients, obviously can be improved to actually look at ingredients, but in general cost will increase with number of ingredients, higher = more expensive = worse
-	r.BaseScoreIndex.Cost = -len(r.Ingredients)
-	// use generic total time duration of one hour if it isn't defined
-	if r.TotalTimeDuration == 0 {
-		r.TotalTimeDuration = time.Hour
-	}
-	// use combination of number of ingredients and total time, higher = more effort = worse
-	r.BaseScoreIndex.Effort = -len(r.Ingredients) - int(r.TotalTimeDuration.Minutes())
-	// avoid div by 0
-	if r.Nutrition.Protein == 0 {
-		r.BaseScoreIndex.Healthiness = -r.Nutrition.Sugar * 10
-	} else {
-		// ratio of sugar to protein, higher = more sugar = worse
-		r.BaseScoreIndex.Healthiness = -100 * r.Nutrition.Sugar / r.Nutrition.Protein
+	// estimated cost per serving in cents, higher = more expensive = worse
+	r.BaseScoreIndex.Cost = -100 * r.CostPerServing
+	// active and passive time, steps, ingredients, techniques, and equipment, higher = more effort = worse
+	effort := r.EstimateEffort()
+	r.BaseScoreIndex.Effort = -float64(effort.Points)
+	r.EffortExplanation = effort.Explanation
+	// nutrient profile of one serving, higher = healthier = better;
+	// unknown healthiness is NaN, which is normalized to the middle
+	healthiness, ok := DefaultHealthinessModel.Healthiness(r.ServingNutrition())
+	r.BaseScoreIndex.Healthiness = float64(healthiness)
+	if !ok {
+		r.BaseScoreIndex.Healthiness = math.NaN()
 	}
 	// rating value combined with rating count, higher = better rated = better
-	r.BaseScoreIndex.Taste = int(100*r.RatingValue) + min(r.RatingCount, 500)
+	r.BaseScoreIndex.Taste = 100*r.RatingValue + float64(min(r.RatingCount, 500))
 	// hours since 1970 for date published and modified, higher = more recent = better
-	r.BaseScoreIndex.Recency = int(r.DatePublished.Unix()/3600 + r.DateModified.Unix()/3600)
+	r.BaseScoreIndex.Recency = float64(r.DatePublished.Unix()/3600 + r.DateModified.Unix()/3600)
 }
 
 // ComputeNormScores computes the normalized base and
-// text encoding scores for each recipe. The base and text
-// encoding score indices already need to be computed.
-// The normalized scores are the z-scores centered at 50
-// and bounded at roughly 0 and 100.
-func ComputeNormScores(recipes []*Recipe) {
-	len := len(recipes)
-	// we sort recipes by the base score indices on each metric and then loop over to find the percentile for each recipe on each metric and use that for the base score
-	doCompute := func(scoreValue func(s *Score) *int, indexScoreObject, percentileScoreObject func(r *Recipe) *Score) {
-		scores := make([]float64, len)
-		for i, recipe := range recipes {
-			scores[i] = float64(*scoreValue(indexScoreObject(recipe)))
-		}
-		mean, std := stat.MeanStdDev(scores, nil)
-		for i, recipe := range recipes {
-			z := (scores[i] - mean) / std
-			if std == 0 {
-				z = 0
+// text encoding scores for each recipe on the given
+// dimensions using the given normalization strategy. The base and text encoding
+// score indices already need to be computed.
+// The normalized scores are from 0 to 100.
+func ComputeNormScores(recipes []*Recipe, dims []*Dimension, strategy NormStrategies) {
+	doCompute := func(d *Dimension, indexScoreObject, normScoreObject func(r *Recipe) *Score) {
+		// only recipes with values on the dimension are included (custom dimensions do not have base scores)
+		scores := []float64{}
+		has := []*Recipe{}
+		for _, recipe := range recipes {
+			if v, ok := indexScoreObject(recipe).Value(d); ok {
+				scores = append(scores, v)
+				has = append(has, recipe)
 			}
-			sc := (50 * z / 3) + 50
-			*scoreValue(percentileScoreObject(recipe)) = int(sc)
+		}
+		if len(scores) == 0 {
+			return
+		}
+		for i, sc := range strategy.Normalize(scores) {
+			normScoreObject(has[i]).SetValue(d, sc)
 		}
 	}
-	compute := func(scoreValue func(s *Score) *int) {
-		doCompute(scoreValue,
+	for _, d := range dims {
+		doCompute(d,
 			func(r *Recipe) *Score { return &r.BaseScoreIndex },
 			func(r *Recipe) *Score { return &r.BaseScore },
 		)
-		doCompute(scoreValue,
+		doCompute(d,
 			func(r *Recipe) *Score { return &r.EncodingScoreIndex },
 			func(r *Recipe) *Score { return &r.EncodingScore },
 		)
 	}
-	compute(func(s *Score) *int { return &s.Taste })
-	compute(func(s *Score) *int { return &s.Recency })
-	compute(func(s *Score) *int { return &s.Cost })
-	compute(func(s *Score) *int { return &s.Effort })
-	compute(func(s *Score) *int { return &s.Healthiness })
 }
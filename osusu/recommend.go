@@ -0,0 +1,175 @@
+package osusu
+
+import (
+	"maps"
+	"time"
+)
+
+// RecommendConfig is the configuration of how [ScoreRecipes] combines the
+// different scores of recipes, which is separate from [Options] because it is
+// not set by users.
+type RecommendConfig struct {
+	// BaseWeight is the weight of the base score of a recipe, which is based
+	// on information about the recipe itself
+	BaseWeight int
+	// EncodingWeight is the weight of the encoding score of a recipe, which is
+	// based on how similar the recipe is to meals and how they are rated
+	EncodingWeight int
+}
+
+// DefaultRecommendConfig returns the default [RecommendConfig], in which the
+// encoding score is three times more important than the base score.
+func DefaultRecommendConfig() *RecommendConfig {
+	return &RecommendConfig{
+		BaseWeight:     1,
+		EncodingWeight: 3,
+	}
+}
+
+// ScoreRecipes computes the scores of the given recipes for recommending them to a user
+// based on the given meals, the entries of the user for each meal keyed by meal ID, and
+// the text encoding vectors of the meals keyed by meal ID and of the recipes keyed by
+// recipe URL. Meals without text encoding vectors do not count toward the encoding
+// scores. The meals are scored on the given dimensions at the given time. [Recipe.EstimateCost] should be
+// called on the recipes first.
+func ScoreRecipes(recipes []*Recipe, meals []*Meal, mealEntries map[uint][]Entry, mealVectors map[uint][]float32, recipeVectors map[string][]float32, prior *Score, opts *Options, dims []*Dimension, cfg *RecommendConfig, now time.Time) {
+	// the scores of the meals are the same for every recipe
+	mealScores := make([]*Score, len(meals))
+	for i, meal := range meals {
+		score := meal.Score(mealEntries[meal.ID], prior, opts, dims, now)
+		score.ComputeTotal(opts, dims)
+		mealScores[i] = score
+	}
+
+	for _, recipe := range recipes {
+		// first we get the base score index
+		// TODO(kai/osusu): cache this step
+		recipe.ComputeBaseScoreIndex()
+
+		// then we get the raw text encoding score
+		recipeVector := recipeVectors[recipe.URL]
+		recipe.TextEncodingScores = map[uint]float32{}
+		for _, meal := range meals {
+			if mealVector, ok := mealVectors[meal.ID]; ok {
+				recipe.TextEncodingScores[meal.ID] = float32(vectorDot(mealVector, recipeVector))
+			}
+		}
+
+		// then we get the weighted score
+		// this step can not be cached
+		weightedScores := []*Score{}
+		for i, meal := range meals {
+			textEncodingScore, ok := recipe.TextEncodingScores[meal.ID]
+			if !ok {
+				continue
+			}
+			score := *mealScores[i]
+			score.Custom = maps.Clone(score.Custom)
+			MulScore(&score, textEncodingScore)
+			weightedScores = append(weightedScores, &score)
+		}
+		recipe.EncodingScoreIndex = *AverageScore(weightedScores)
+	}
+
+	// now we can compute the normalized scores
+	ComputeNormScores(recipes, dims, opts.Normalization)
+
+	// and then the total scores
+	for _, recipe := range recipes {
+		recipe.BaseScore.ComputeTotal(opts, dims)
+		recipe.EncodingScore.ComputeTotal(opts, dims)
+		scores := []*Score{}
+		for range cfg.BaseWeight {
+			scores = append(scores, &recipe.BaseScore)
+		}
+		for range cfg.EncodingWeight {
+			scores = append(scores, &recipe.EncodingScore)
+		}
+		recipe.Score = *AverageScore(scores)
+	}
+}
+
+// SourceVectors returns the text encoding vectors of the given meals keyed by
+// meal ID, which are those of the recipes they were added from, keyed by recipe
+// URL. It can be used instead of the text encoding model, in which case meals
+// that were not added from recipes do not have vectors.
+func SourceVectors(meals []*Meal, recipeVectors map[string][]float32) map[uint][]float32 {
+	res := map[uint][]float32{}
+	for _, meal := range meals {
+		if v, ok := recipeVectors[meal.SourceURL]; ok {
+			res[meal.ID] = v
+		}
+	}
+	return res
+}
+
+// Discover returns the top n of the given recipes to recommend to the given user
+// based on the given meals and dimensions of their group. The recipes should have
+// their category and cuisine flags set. The text encoding vectors of the recipes are
+// keyed by recipe URL and those of the meals by meal ID. The recipes are scored with
+// the prices of the group, blended with collaborative filtering if the group shares
+// its data (see [Group.ShareData]), filtered to the ones that have not been added or
+// dismissed and that match the options, and then reranked for diversity.
+func Discover(recipes []*Recipe, recipeVectors map[string][]float32, meals []*Meal, mealVectors map[uint][]float32, user *User, opts *Options, dims []*Dimension, n int) ([]*Recipe, error) {
+	var groupEntries []Entry
+	err := DB.Find(&groupEntries, "meal_id IN (?)", DB.Model(&Meal{}).Select("id").Where("group_id = ?", user.GroupID)).Error
+	if err != nil {
+		return nil, err
+	}
+	// only the entries of the user in this group are used, not those in other groups
+	userEntries := []Entry{}
+	mealEntries := map[uint][]Entry{}
+	for _, entry := range groupEntries {
+		if entry.UserID == user.ID {
+			userEntries = append(userEntries, entry)
+			mealEntries[entry.MealID] = append(mealEntries[entry.MealID], entry)
+		}
+	}
+	now := time.Now()
+	prior := ComputePrior(userEntries, groupEntries, opts, dims, now)
+
+	var prices PriceTable
+	err = DB.Find(&prices, "group_id = ?", user.GroupID).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, recipe := range recipes {
+		// TODO(kai/osusu): cache this step
+		recipe.EstimateCost(prices)
+	}
+	ScoreRecipes(recipes, meals, mealEntries, mealVectors, recipeVectors, prior, opts, dims, DefaultRecommendConfig(), now)
+
+	// then we blend in what users in other groups like, which is only
+	// done for groups that opt in to sharing their own data
+	group := &Group{}
+	err = DB.Limit(1).Find(group, "id = ?", user.GroupID).Error
+	if err != nil {
+		return nil, err
+	}
+	if group.ShareData && opts.CollaborativeWeight > 0 {
+		cm, err := LoadCollaborativeModel()
+		if err != nil {
+			return nil, err
+		}
+		if cm != nil {
+			BlendCollaborative(recipes, cm, user.ID, opts)
+		}
+	}
+
+	dismissals, err := LoadDismissals(user.ID, user.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	filtered := []*Recipe{}
+	for _, recipe := range ExcludeRecipes(recipes, meals, dismissals) {
+		if opts.IncludesRecipe(recipe) {
+			filtered = append(filtered, recipe)
+		}
+	}
+
+	// dismissed recipes count against similar recipes
+	ApplyDismissals(filtered, recipeVectors, dismissals)
+
+	// then we rerank the top recipes to make them more diverse
+	return Rerank(filtered, recipeVectors, float64(opts.Diversity)/100, n), nil
+}
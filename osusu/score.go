@@ -1,76 +1,252 @@
 package osusu
 
 import (
+	"math"
 	"time"
 
 	"cogentcore.org/core/base/num"
 )
 
+// Score is a score on each metric and in total. Scores are kept at full
+// precision and should only be rounded for display, using [Score.Int].
 type Score struct {
+	Taste       float64
+	Recency     float64
+	Cost        float64
+	Effort      float64
+	Healthiness float64
+	Total       float64
+	// Confidence is how confident the score is from 0 to 100,
+	// based on the number of entries it is computed from
+	Confidence float64
+	// Custom are the values of the custom dimensions, keyed by name (see [LoadDimensions])
+	Custom map[string]float64
+}
+
+// IntScore is a [Score] with all of its values rounded to the nearest integer.
+type IntScore struct {
 	Taste       int
 	Recency     int
 	Cost        int
 	Effort      int
 	Healthiness int
 	Total       int
+	Confidence  int
+	Custom      map[string]int
 }
 
-func (s *Score) ComputeTotal(opts *Options) {
-	s.Total = (s.Taste * opts.TasteImportance) + (s.Recency * opts.RecencyImportance) +
-		(s.Cost * opts.CostImportance) + (s.Effort * opts.EffortImportance) +
-		(s.Healthiness * opts.HealthinessImportance)
-
-	totImp := opts.TasteImportance + opts.RecencyImportance + opts.CostImportance + opts.EffortImportance + opts.HealthinessImportance
-	if totImp != 0 {
-		s.Total /= totImp
+// Int returns the score with all of its values rounded to the nearest integer.
+func (s *Score) Int() IntScore {
+	is := IntScore{
+		Taste:       Round(s.Taste),
+		Recency:     Round(s.Recency),
+		Cost:        Round(s.Cost),
+		Effort:      Round(s.Effort),
+		Healthiness: Round(s.Healthiness),
+		Total:       Round(s.Total),
+		Confidence:  Round(s.Confidence),
+	}
+	if s.Custom != nil {
+		is.Custom = map[string]int{}
+		for k, v := range s.Custom {
+			is.Custom[k] = Round(v)
+		}
 	}
+	return is
 }
 
-func (e *Entry) Score() *Score {
+// Score returns the integer score as a [Score].
+func (is IntScore) Score() *Score {
 	s := &Score{
-		Taste:       e.Taste,
-		Cost:        100 - e.Cost,
-		Effort:      100 - e.Effort,
-		Healthiness: e.Healthiness,
+		Taste:       float64(is.Taste),
+		Recency:     float64(is.Recency),
+		Cost:        float64(is.Cost),
+		Effort:      float64(is.Effort),
+		Healthiness: float64(is.Healthiness),
+		Total:       float64(is.Total),
+		Confidence:  float64(is.Confidence),
+	}
+	if is.Custom != nil {
+		s.Custom = map[string]float64{}
+		for k, v := range is.Custom {
+			s.Custom[k] = float64(v)
+		}
 	}
 	return s
 }
 
-func (m *Meal) Score(entries []Entry) *Score {
-	n := len(entries)
-	if n == 0 {
-		return &Score{}
+// Round returns the given score value rounded to the nearest integer.
+func Round(v float64) int {
+	return int(math.Round(v))
+}
+
+// ComputeTotal computes the total score as the weighted average of the values
+// of the score on each of the given dimensions that it has values for, using the
+// importances in the given options as the weights.
+func (s *Score) ComputeTotal(opts *Options, dims []*Dimension) {
+	s.Total = 0
+	totImp := 0
+	for _, d := range dims {
+		v, ok := s.Value(d)
+		if !ok {
+			continue
+		}
+		imp := opts.Importance(d)
+		s.Total += v * float64(imp)
+		totImp += imp
 	}
+	if totImp != 0 {
+		s.Total /= float64(totImp)
+	}
+}
+
+// Rating returns the rating of the entry on the given dimension,
+// and whether the entry has a rating for it.
+func (e *Entry) Rating(d *Dimension) (int, bool) {
+	switch d.Name {
+	case Taste:
+		return e.Taste, true
+	case Recency:
+		return 0, false
+	case Cost:
+		return e.Cost, true
+	case Effort:
+		return e.Effort, true
+	case Healthiness:
+		return e.Healthiness, true
+	}
+	rating, ok := e.Ratings[d.Name]
+	return rating, ok
+}
 
+// Score returns the score of the entry on each of the given dimensions that
+// it has a rating for, using [Dimension.Normalize] so that higher scores are
+// always better. The dimensions should include the built-in dimensions.
+func (e *Entry) Score(dims []*Dimension) *Score {
 	s := &Score{}
-	newest := time.Time{}
+	for _, d := range dims {
+		if d.Computed {
+			continue
+		}
+		if rating, ok := e.Rating(d); ok {
+			s.SetValue(d, d.Normalize(rating))
+		}
+	}
+	return s
+}
+
+// ratings are the weighted sums of the ratings of entries.
+type ratings struct {
+	// sums are the weighted sums of the score values of the entries on each dimension
+	sums map[string]float64
+	// weights are the sums of the weights of the entries that have values on each dimension
+	weights map[string]float64
+	// weight is the sum of the weights of the entries, which is the
+	// effective number of entries
+	weight float64
+	newest time.Time
+}
+
+// add adds the given entries to the ratings on the given dimensions, weighting
+// them by age based on [Options.RatingHalfLife].
+func (r *ratings) add(entries []Entry, opts *Options, dims []*Dimension, now time.Time) {
+	if r.sums == nil {
+		r.sums = map[string]float64{}
+		r.weights = map[string]float64{}
+	}
 	for _, entry := range entries {
-		s.Taste += entry.Taste
-		s.Cost += 100 - entry.Cost
-		s.Effort += 100 - entry.Effort
-		s.Healthiness += entry.Healthiness
-		if entry.Time.After(newest) {
-			newest = entry.Time
+		w := opts.RatingWeight(now.Sub(entry.Time))
+		es := entry.Score(dims)
+		for _, d := range dims {
+			if d.Computed {
+				continue
+			}
+			if v, ok := es.Value(d); ok {
+				r.sums[d.Name] += w * v
+				r.weights[d.Name] += w
+			}
+		}
+		r.weight += w
+		if entry.Time.After(r.newest) {
+			r.newest = entry.Time
+		}
+	}
+}
+
+// shrink returns the score for the ratings on the given dimensions with the
+// averages shrunk toward the given prior score based on [Options.PriorStrength].
+// If the prior is nil, the averages are not shrunk.
+func (r *ratings) shrink(prior *Score, opts *Options, dims []*Dimension) *Score {
+	s := &Score{}
+	if prior == nil {
+		prior = &Score{}
+	}
+	for _, d := range dims {
+		if d.Computed {
+			continue
 		}
+		k := float64(opts.PriorStrength)
+		pv, ok := prior.Value(d)
+		if !ok {
+			k = 0
+		}
+		weight := r.weights[d.Name] + k
+		if weight == 0 {
+			continue
+		}
+		s.SetValue(d, (r.sums[d.Name]+k*pv)/weight)
+	}
+	if weight := r.weight + float64(opts.PriorStrength); weight != 0 {
+		s.Confidence = 100 * r.weight / weight
 	}
-	s.Taste /= n
-	s.Cost /= n
-	s.Effort /= n
-	s.Healthiness /= n
+	return s
+}
 
-	days := time.Since(newest) / (24 * time.Hour)
-	recency := 2 * days
-	s.Recency = min(int(recency), 100)
+// ComputePrior returns the prior score on the given dimensions for the ratings of a user, which is
+// the average of the ratings of all of the entries of the user shrunk toward
+// the average of the ratings of all of the entries of their group, with the
+// ratings weighted by their age at the given time. Meal scores are shrunk
+// toward the prior in [Meal.Score] so that meals with few entries do not have
+// extreme scores.
+func ComputePrior(userEntries, groupEntries []Entry, opts *Options, dims []*Dimension, now time.Time) *Score {
+	group := &ratings{}
+	group.add(groupEntries, opts, dims, now)
+	user := &ratings{}
+	user.add(userEntries, opts, dims, now)
+	return user.shrink(group.shrink(nil, opts, dims), opts, dims)
+}
+
+// Score returns the score of the meal on the given dimensions at the given time
+// based on the given entries for it. The ratings of older entries are weighted less based on [Options.RatingHalfLife],
+// and the averages are shrunk toward the given prior score (see [ComputePrior]),
+// which can be nil, based on the number of entries. The recency is computed from
+// the newest entry using [Options.RecencyCurve] and the comfort period of the
+// categories of the meal; meals without entries are fully recent.
+func (m *Meal) Score(entries []Entry, prior *Score, opts *Options, dims []*Dimension, now time.Time) *Score {
+	r := &ratings{}
+	r.add(entries, opts, dims, now)
+	s := r.shrink(prior, opts, dims)
+	if len(entries) == 0 {
+		s.Recency = 100
+		return s
+	}
+	days := now.Sub(r.newest).Hours() / 24
+	period := opts.ComfortPeriods.Period(m.Category)
+	s.Recency = opts.RecencyCurve.Recency(days, float64(period))
 	return s
 }
 
 func MulScore[T num.Number](s *Score, scalar T) {
-	s.Taste = int(T(s.Taste) * scalar)
-	s.Recency = int(T(s.Recency) * scalar)
-	s.Cost = int(T(s.Cost) * scalar)
-	s.Effort = int(T(s.Effort) * scalar)
-	s.Healthiness = int(T(s.Healthiness) * scalar)
-	s.Total = int(T(s.Total) * scalar)
+	f := float64(scalar)
+	s.Taste *= f
+	s.Recency *= f
+	s.Cost *= f
+	s.Effort *= f
+	s.Healthiness *= f
+	s.Total *= f
+	for k, v := range s.Custom {
+		s.Custom[k] = v * f
+	}
 }
 
 func AverageScore(scores []*Score) *Score {
@@ -79,6 +255,8 @@ func AverageScore(scores []*Score) *Score {
 		return &Score{}
 	}
 	res := &Score{}
+	// custom values are averaged over the scores that have them
+	var counts map[string]int
 	for _, score := range scores {
 		res.Cost += score.Cost
 		res.Effort += score.Effort
@@ -86,12 +264,26 @@ func AverageScore(scores []*Score) *Score {
 		res.Taste += score.Taste
 		res.Recency += score.Recency
 		res.Total += score.Total
+		res.Confidence += score.Confidence
+		for k, v := range score.Custom {
+			if res.Custom == nil {
+				res.Custom = map[string]float64{}
+				counts = map[string]int{}
+			}
+			res.Custom[k] += v
+			counts[k]++
+		}
+	}
+	n := float64(ls)
+	res.Cost /= n
+	res.Effort /= n
+	res.Healthiness /= n
+	res.Taste /= n
+	res.Recency /= n
+	res.Total /= n
+	res.Confidence /= n
+	for k, c := range counts {
+		res.Custom[k] /= float64(c)
 	}
-	res.Cost /= ls
-	res.Effort /= ls
-	res.Healthiness /= ls
-	res.Taste /= ls
-	res.Recency /= ls
-	res.Total /= ls
 	return res
 }
@@ -0,0 +1,194 @@
+package osusu
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// testScore returns a score with the given values divided by 100
+// on each of the built-in dimensions.
+func testScore(v [5]int16) *Score {
+	return &Score{
+		Taste:       float64(v[0]) / 100,
+		Recency:     float64(v[1]) / 100,
+		Cost:        float64(v[2]) / 100,
+		Effort:      float64(v[3]) / 100,
+		Healthiness: float64(v[4]) / 100,
+	}
+}
+
+// testDominating returns a score that is higher than or equal to
+// the given score on every dimension by the given amounts divided by 100.
+func testDominating(s *Score, d [5]uint16) *Score {
+	return &Score{
+		Taste:       s.Taste + float64(d[0])/100,
+		Recency:     s.Recency + float64(d[1])/100,
+		Cost:        s.Cost + float64(d[2])/100,
+		Effort:      s.Effort + float64(d[3])/100,
+		Healthiness: s.Healthiness + float64(d[4])/100,
+	}
+}
+
+// testOptions returns options with the given importances.
+func testOptions(imp [5]uint8) *Options {
+	return &Options{
+		TasteImportance:       int(imp[0]),
+		RecencyImportance:     int(imp[1]),
+		CostImportance:        int(imp[2]),
+		EffortImportance:      int(imp[3]),
+		HealthinessImportance: int(imp[4]),
+	}
+}
+
+// tolerance is the allowed rounding error when comparing score values.
+const tolerance = 1e-9
+
+func TestRoundOrder(t *testing.T) {
+	f := func(a, b float64) bool {
+		a, b = math.Mod(a, 1e6), math.Mod(b, 1e6)
+		if a > b {
+			a, b = b, a
+		}
+		return Round(a) <= Round(b) && math.Abs(float64(Round(a))-a) <= 0.5
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestComputeTotalOrder(t *testing.T) {
+	f := func(v [5]int16, d [5]uint16, imp [5]uint8) bool {
+		opts := testOptions(imp)
+		a := testScore(v)
+		b := testDominating(a, d)
+		a.ComputeTotal(opts, BuiltinDimensions)
+		b.ComputeTotal(opts, BuiltinDimensions)
+		if a.Total > b.Total+tolerance {
+			return false
+		}
+		// the total is a weighted average, so it is between the lowest and highest values
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for i, d := range BuiltinDimensions {
+			if imp[i] == 0 {
+				continue
+			}
+			v, _ := a.Value(d)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if math.IsInf(lo, 1) {
+			return a.Total == 0
+		}
+		return a.Total >= lo-tolerance && a.Total <= hi+tolerance
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMulScoreOrder(t *testing.T) {
+	f := func(va, vb [5]int16, imp [5]uint8, scalar uint8) bool {
+		k := float64(scalar)/10 + 0.1
+		opts := testOptions(imp)
+		a, b := testScore(va), testScore(vb)
+		a.ComputeTotal(opts, BuiltinDimensions)
+		b.ComputeTotal(opts, BuiltinDimensions)
+		diff := a.Total - b.Total
+		MulScore(a, k)
+		MulScore(b, k)
+		mulDiff := a.Total - b.Total
+		// the totals of the scaled scores are also the totals of the scaled values
+		ta, tb := a.Total, b.Total
+		a.ComputeTotal(opts, BuiltinDimensions)
+		b.ComputeTotal(opts, BuiltinDimensions)
+		return math.Abs(mulDiff-k*diff) <= tolerance &&
+			math.Abs(a.Total-ta) <= tolerance && math.Abs(b.Total-tb) <= tolerance
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAverageScoreOrder(t *testing.T) {
+	f := func(va, vc [5]int16, d [5]uint16, imp [5]uint8) bool {
+		opts := testOptions(imp)
+		a, c := testScore(va), testScore(vc)
+		b := testDominating(a, d)
+		a.ComputeTotal(opts, BuiltinDimensions)
+		b.ComputeTotal(opts, BuiltinDimensions)
+		c.ComputeTotal(opts, BuiltinDimensions)
+		ac := AverageScore([]*Score{a, c})
+		bc := AverageScore([]*Score{b, c})
+		for _, d := range BuiltinDimensions {
+			va, _ := ac.Value(d)
+			vb, _ := bc.Value(d)
+			if va > vb+tolerance {
+				return false
+			}
+		}
+		if ac.Total > bc.Total+tolerance {
+			return false
+		}
+		// the average of a single score is the score
+		return reflect.DeepEqual(AverageScore([]*Score{a}), a)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIntScoreRoundTrip(t *testing.T) {
+	f := func(v [7]int32, custom map[string]int32) bool {
+		is := IntScore{
+			Taste:       int(v[0]),
+			Recency:     int(v[1]),
+			Cost:        int(v[2]),
+			Effort:      int(v[3]),
+			Healthiness: int(v[4]),
+			Total:       int(v[5]),
+			Confidence:  int(v[6]),
+		}
+		for k, v := range custom {
+			if is.Custom == nil {
+				is.Custom = map[string]int{}
+			}
+			is.Custom[k] = int(v)
+		}
+		return reflect.DeepEqual(is.Score().Int(), is)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+	g := func(v [5]int16, custom map[string]int16) bool {
+		s := testScore(v)
+		for k, v := range custom {
+			s.SetValue(&Dimension{Name: "custom " + k}, float64(v)/100)
+		}
+		is := s.Int()
+		return reflect.DeepEqual(is.Score().Int(), is)
+	}
+	if err := quick.Check(g, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEntryScore(t *testing.T) {
+	spicy := &Dimension{Name: "Spiciness", HigherIsBetter: false, Min: 1, Max: 5}
+	dims := append(append([]*Dimension{}, BuiltinDimensions...), spicy)
+	e := &Entry{Taste: 80, Cost: 30, Effort: 60, Healthiness: 40, Ratings: map[string]int{"Spiciness": 2}}
+	s := e.Score(dims)
+	want := &Score{Taste: 80, Cost: 70, Effort: 40, Healthiness: 40, Custom: map[string]float64{"Spiciness": 75}}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("expected %+v but got %+v", want, s)
+	}
+
+	// the built-in dimensions are normalized with their settings like custom ones
+	taste := *BuiltinDimensions[0]
+	taste.HigherIsBetter = false
+	taste.Min, taste.Max = 0, 200
+	s = e.Score([]*Dimension{&taste})
+	if s.Taste != 60 {
+		t.Errorf("expected a taste of 60 but got %v", s.Taste)
+	}
+}
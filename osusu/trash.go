@@ -0,0 +1,181 @@
+package osusu
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPurgeNotAllowed is returned by [Meal.Purge] when the actor is not allowed
+// to permanently delete the entries of other users for the meal.
+var ErrPurgeNotAllowed = errors.New("only the creator of the meal and the owner of the group can permanently delete the entries of other members for it")
+
+// Delete moves the meal and its entries to the trash, from which they can
+// be restored with [Meal.Restore]. The context is used for the database,
+// so it should have the actor of the change (see [WithActor]); the same is
+// true for the other methods for the trash.
+//
+// The database does not support transactions, so the meal is moved first:
+// if moving its entries fails, they are still hidden with the meal, and
+// calling Delete again moves them, while calling Restore restores the meal.
+func (m *Meal) Delete(ctx context.Context) error {
+	// the entries are given the deletion ID of the meal so that only
+	// they are restored with it, and not entries deleted before; a meal
+	// that is already in the trash keeps its ID so that its entries are
+	// moved with the same one
+	id, now := m.DeletionID, m.DeletedAt.Time
+	if !m.DeletedAt.Valid || id == "" {
+		b := make([]byte, 8)
+		_, err := rand.Read(b)
+		if err != nil {
+			return err
+		}
+		id, now = hex.EncodeToString(b), time.Now()
+	}
+	db := DB.WithContext(ctx)
+	err := db.Unscoped().Model(m).Updates(map[string]any{"deleted_at": now, "deletion_id": id}).Error
+	if err != nil {
+		return err
+	}
+	m.DeletedAt, m.DeletionID = gorm.DeletedAt{Time: now, Valid: true}, id
+	return db.Model(&Entry{}).Where("meal_id = ?", m.ID).Updates(map[string]any{"deleted_at": now, "deletion_id": id}).Error
+}
+
+// Restore restores the meal and the entries deleted with it from the trash.
+// The entries are restored first, so if restoring the meal fails, they are
+// still hidden with it until Restore is called again.
+func (m *Meal) Restore(ctx context.Context) error {
+	if !m.DeletedAt.Valid {
+		return nil
+	}
+	db := DB.WithContext(ctx)
+	entries := db.Unscoped().Model(&Entry{}).Where("meal_id = ?", m.ID)
+	if m.DeletionID != "" {
+		entries = entries.Where("deletion_id = ?", m.DeletionID)
+	} else {
+		// meals deleted before deletion IDs were deleted at the same time as their entries
+		entries = entries.Where("deleted_at >= ?", m.DeletedAt.Time)
+	}
+	err := entries.Updates(map[string]any{"deleted_at": nil, "deletion_id": ""}).Error
+	if err != nil {
+		return err
+	}
+	err = db.Unscoped().Model(m).Updates(map[string]any{"deleted_at": nil, "deletion_id": ""}).Error
+	if err != nil {
+		return err
+	}
+	m.DeletedAt, m.DeletionID = gorm.DeletedAt{}, ""
+	return nil
+}
+
+// checkPurge returns [ErrPurgeNotAllowed] if the meal has entries of users
+// other than the actor in the context of the given database and the actor
+// is neither the creator of the meal nor the owner of its group.
+func (m *Meal) checkPurge(tx *gorm.DB) error {
+	actor := ActorFrom(tx.Statement.Context)
+	if actor != 0 && actor == m.CreatorID {
+		return nil
+	}
+	var n int64
+	err := tx.Unscoped().Model(&Entry{}).Where("meal_id = ? AND user_id <> ?", m.ID, actor).Count(&n).Error
+	if err != nil || n == 0 {
+		return err
+	}
+	err = tx.Model(&Group{}).Where("id = ? AND owner_id = ?", m.GroupID, actor).Count(&n).Error
+	if err != nil {
+		return err
+	}
+	if actor == 0 || n == 0 {
+		return ErrPurgeNotAllowed
+	}
+	return nil
+}
+
+// Purge permanently deletes the meal and all of its entries. It returns
+// [ErrPurgeNotAllowed] if that would delete the entries of other users
+// and the actor is neither the creator of the meal nor the owner of its group.
+// The entries are deleted first, so if deleting the meal fails, it stays
+// in the trash without them and can be purged again.
+func (m *Meal) Purge(ctx context.Context) error {
+	db := DB.WithContext(ctx)
+	err := m.checkPurge(db)
+	if err != nil {
+		return err
+	}
+	err = db.Unscoped().Delete(&Entry{}, "meal_id = ?", m.ID).Error
+	if err != nil {
+		return err
+	}
+	return db.Unscoped().Delete(m).Error
+}
+
+// Delete moves the entry to the trash, from which it can be restored with [Entry.Restore].
+func (e *Entry) Delete(ctx context.Context) error {
+	return DB.WithContext(ctx).Delete(e).Error
+}
+
+// Restore restores the entry from the trash.
+func (e *Entry) Restore(ctx context.Context) error {
+	err := DB.WithContext(ctx).Unscoped().Model(e).Updates(map[string]any{"deleted_at": nil, "deletion_id": ""}).Error
+	if err != nil {
+		return err
+	}
+	e.DeletedAt, e.DeletionID = gorm.DeletedAt{}, ""
+	return nil
+}
+
+// Purge permanently deletes the entry.
+func (e *Entry) Purge(ctx context.Context) error {
+	return DB.WithContext(ctx).Unscoped().Delete(e).Error
+}
+
+// Trash is the deleted meals of a group and the deleted entries of a user.
+type Trash struct {
+	Meals []*Meal
+	// Entries are the deleted entries of the user for meals that are
+	// not deleted; entries deleted with their meal are in the trash
+	// as part of the meal
+	Entries []*Entry
+}
+
+// LoadTrash returns the trash of the given user in the given group.
+func LoadTrash(userID, groupID uint) (*Trash, error) {
+	t := &Trash{}
+	err := DB.Unscoped().Where("group_id = ? AND deleted_at IS NOT NULL", groupID).Order("deleted_at DESC").Find(&t.Meals).Error
+	if err != nil {
+		return nil, err
+	}
+	err = DB.Unscoped().Preload("Meal").Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Where("meal_id IN (?)", DB.Model(&Meal{}).Select("id").Where("group_id = ?", groupID)).
+		Order("deleted_at DESC").Find(&t.Entries).Error
+	return t, err
+}
+
+// Empty permanently deletes everything in the trash, except for meals
+// that the actor is not allowed to purge (see [Meal.Purge]), which are
+// left in the trash.
+func (t *Trash) Empty(ctx context.Context) error {
+	var kept []*Meal
+	for _, m := range t.Meals {
+		err := m.Purge(ctx)
+		if errors.Is(err, ErrPurgeNotAllowed) {
+			kept = append(kept, m)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range t.Entries {
+		err := e.Purge(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	t.Meals, t.Entries = kept, nil
+	return nil
+}
@@ -0,0 +1,204 @@
+package osusu
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// testLiveEntries returns the number of entries for the given meal that are not deleted.
+func testLiveEntries(t *testing.T, mealID uint) int64 {
+	t.Helper()
+	var n int64
+	err := DB.Model(&Entry{}).Where("meal_id = ?", mealID).Count(&n).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMealDeleteRestore(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	ctx := WithActor(context.Background(), td.a.ID)
+	// an entry deleted before the meal stays deleted when the meal is restored
+	entry := &Entry{}
+	err := DB.First(entry, "meal_id = ? AND user_id = ?", td.tacos.ID, td.b.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = entry.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = td.tacos.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testLiveEntries(t, td.tacos.ID); n != 0 {
+		t.Errorf("expected the entries to be deleted with the meal but %d are not", n)
+	}
+	trash, err := LoadTrash(td.a.ID, td.family.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash.Meals) != 1 || trash.Meals[0].ID != td.tacos.ID || trash.Meals[0].DeletionID != td.tacos.DeletionID {
+		t.Fatalf("expected the meal to be in the trash but got %v", trash.Meals)
+	}
+
+	// deleting the meal again, like after moving its entries failed, keeps its deletion
+	id := td.tacos.DeletionID
+	err = td.tacos.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if td.tacos.DeletionID != id {
+		t.Errorf("expected the deletion ID %q to be kept but got %q", id, td.tacos.DeletionID)
+	}
+
+	err = trash.Meals[0].Restore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testLiveEntries(t, td.tacos.ID); n != 1 {
+		t.Errorf("expected only the entry deleted with the meal to be restored but %d are", n)
+	}
+	meal := &Meal{}
+	err = DB.First(meal, td.tacos.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meal.DeletionID != "" {
+		t.Errorf("expected the restored meal to not have a deletion ID but got %q", meal.DeletionID)
+	}
+}
+
+func TestMealRestorePartialDelete(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	ctx := WithActor(context.Background(), td.a.ID)
+	// the meal is moved to the trash first, so if moving its entries fails,
+	// they are hidden with it and restoring it restores everything
+	err := DB.WithContext(ctx).Unscoped().Model(td.tacos).Updates(map[string]any{"deleted_at": td.tacos.CreatedAt, "deletion_id": "partial"}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []*Entry
+	err = DB.Where("meal_id IN (?)", DB.Model(&Meal{}).Select("id").Where("group_id = ?", td.family.ID)).Find(&entries).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the entry for the other meal to be in the group but got %d entries", len(entries))
+	}
+	meal := &Meal{}
+	err = DB.Unscoped().First(meal, td.tacos.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = meal.Restore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testLiveEntries(t, td.tacos.ID); n != 2 {
+		t.Errorf("expected the 2 entries of the meal to be live but %d are", n)
+	}
+	err = DB.First(&Meal{}, td.tacos.ID).Error
+	if err != nil {
+		t.Errorf("expected the meal to be restored: %v", err)
+	}
+}
+
+func TestMealPurge(t *testing.T) {
+	tests := []struct {
+		name string
+		// actor returns the actor of the purge
+		actor func(td *testData) uint
+		err   error
+	}{
+		{"creator", func(td *testData) uint { return td.b.ID }, nil},
+		{"owner", func(td *testData) uint { return td.a.ID }, nil},
+		{"other member", func(td *testData) uint { return td.c.ID }, ErrPurgeNotAllowed},
+		{"unknown", func(td *testData) uint { return 0 }, ErrPurgeNotAllowed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testDB(t)
+			td := testSeed(t)
+			err := DB.Model(td.family).Update("owner_id", td.a.ID).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			// c is not in the group, but the entries of both a and b are for the meal
+			meal := &Meal{GroupID: td.family.ID, Name: "Curry"}
+			err = DB.WithContext(WithActor(context.Background(), td.b.ID)).Create(meal).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			testCreate(t, &Entry{MealID: meal.ID, UserID: td.a.ID}, &Entry{MealID: meal.ID, UserID: td.b.ID})
+
+			ctx := WithActor(context.Background(), test.actor(td))
+			err = meal.Delete(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = meal.Purge(ctx)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected the error %v but got %v", test.err, err)
+			}
+			var n int64
+			err = DB.Unscoped().Model(&Entry{}).Where("meal_id = ?", meal.ID).Count(&n).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := map[bool]int64{true: 0, false: 2}[test.err == nil]; n != want {
+				t.Errorf("expected %d entries to be left but got %d", want, n)
+			}
+		})
+	}
+}
+
+func TestMealCreator(t *testing.T) {
+	testDB(t)
+	td := testSeed(t)
+	// the creator is always the actor, so meals can not be
+	// created as created by other users
+	meal := &Meal{GroupID: td.family.ID, Name: "Curry", CreatorID: td.b.ID}
+	err := DB.WithContext(WithActor(context.Background(), td.a.ID)).Create(meal).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meal.CreatorID != td.a.ID {
+		t.Errorf("expected the creator to be the actor %d but got %d", td.a.ID, meal.CreatorID)
+	}
+
+	// but imported meals keep their creators
+	de, err := ExportData(td.family.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range de.Meals {
+		m.CreatorID = td.b.ID
+	}
+	err = DB.Model(&Meal{}).Where("group_id = ?", td.family.ID).Update("creator_id", td.b.ID).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	DB = DB.WithContext(WithActor(context.Background(), td.a.ID))
+	_, err = de.Import(&ImportOptions{Conflicts: ImportNew, GroupID: td.family.ID, UserID: td.a.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var creators []uint
+	err = DB.Model(&Meal{}).Where("group_id = ?", td.family.ID).Pluck("creator_id", &creators).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range creators {
+		if c != td.b.ID {
+			t.Errorf("expected all of the meals to be created by %d but got %v", td.b.ID, creators)
+			break
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package osusu
 
-import "gorm.io/gorm"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model `display:"-"`
@@ -9,22 +16,96 @@ type User struct {
 	Name       string
 	Locale     string
 	Picture    string
+	// Targets are the daily nutrition targets of the user
+	Targets NutritionTargets `gorm:"embedded;embeddedPrefix:target_"`
+	// PresetID is the ID of the current options preset of the user
+	PresetID uint `display:"-"`
 }
 
-/*
+// Session is a logged in session of a user, which is authenticated with its token.
 type Session struct {
 	gorm.Model
 	UserID uint
-	User   User
-	Token  string
+	User   User   `json:"-"`
+	Token  string `gorm:"uniqueIndex"`
+}
+
+// SessionDuration is how long sessions last before they expire.
+const SessionDuration = 2 * 7 * 24 * time.Hour
+
+// ErrInvalidSession is returned by [LoadSession] for sessions that do not exist or have expired.
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// NewSession creates a new session for the user with the given ID with a random token.
+func NewSession(userID uint) (*Session, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{UserID: userID, Token: hex.EncodeToString(b)}
+	return session, DB.Create(session).Error
+}
+
+// LoadSession returns the session with the given token with its user loaded.
+// It returns [ErrInvalidSession] if there is no such session or it has expired,
+// in which case it deletes it.
+func LoadSession(token string) (*Session, error) {
+	session := &Session{}
+	err := DB.Preload("User").First(session, "token = ?", token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(session.CreatedAt) > SessionDuration {
+		return nil, errors.Join(ErrInvalidSession, DB.Delete(session).Error)
+	}
+	return session, nil
 }
-*/
 
 type Group struct {
 	gorm.Model `display:"-"`
 	Name       string
 	Code       string `display:"-"`
 	OwnerID    uint   `display:"-"`
-	Owner      User   `display:"-"`
-	Members    []User
+	Owner      User   `display:"-" json:"-"`
+	Members    []User `json:"-"`
+	// DefaultPresetID is the ID of the options preset that new members start with
+	DefaultPresetID uint `display:"-"`
+	// ShareData is whether the anonymized ratings of the group are used to
+	// recommend recipes to other groups through collaborative filtering
+	ShareData bool `label:"Share anonymized ratings"`
+}
+
+// BeforeCreate gives the group a random join code if it does not have one.
+func (g *Group) BeforeCreate(tx *gorm.DB) error {
+	if g.Code != "" {
+		return nil
+	}
+	b := make([]byte, 6)
+	_, err := rand.Read(b)
+	if err != nil {
+		return err
+	}
+	g.Code = hex.EncodeToString(b)
+	return nil
+}
+
+// ErrInvalidGroupCode is returned by [User.JoinGroup] when there is no group with the code.
+var ErrInvalidGroupCode = errors.New("there is no group with that code")
+
+// JoinGroup makes the user a member of the group with the given join code and returns it.
+func (u *User) JoinGroup(code string) (*Group, error) {
+	group := &Group{}
+	err := DB.First(group, "code = ?", code).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidGroupCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.GroupID = group.ID
+	return group, DB.Save(u).Error
 }